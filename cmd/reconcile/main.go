@@ -0,0 +1,105 @@
+// reconcile 命令行对账工具，与 GET/POST /api/v1/admin/reconcile 相同
+//
+//	go run ./cmd/reconcile                          # 预览，输出 JSON 报告
+//	go run ./cmd/reconcile -confirm <fingerprint>   # 按预览结果修复
+//
+// 需要在服务根目录下运行，读取 ./configs/config.yaml 中的数据库和存储配置
+package main
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/spf13/viper"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"log"
+	"os"
+)
+
+func main() {
+	viper.SetDefault("reconcile.min_age", "1h")
+	configDir := flag.String("config", "./configs", "配置文件 config.yaml 所在目录")
+	confirm := flag.String("confirm", "", "预览结果中的 fingerprint，提供时执行修复")
+	minAge := flag.Duration("min-age", 0, "忽略在此时长内修改过的文件，默认使用配置 reconcile.min_age")
+	flag.Parse()
+
+	// 1. 加载配置
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(*configDir)
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %s", err)
+	}
+	opts := service.ReconcileOptions{MinAge: viper.GetDuration("reconcile.min_age")}
+	if *minAge > 0 {
+		opts.MinAge = *minAge
+	}
+
+	// 2. 连接数据库，不做迁移
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+		viper.GetString("mysql.user"),
+		viper.GetString("mysql.password"),
+		viper.GetString("mysql.host"),
+		viper.GetInt("mysql.port"),
+		viper.GetString("mysql.dbname"),
+		viper.GetString("mysql.charset"),
+	)
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		log.Fatalf("连接数据库失败: %s", err)
+	}
+
+	// 3. 初始化存储后端
+	backend, err := storage.New(storage.Config{
+		Driver: viper.GetString("storage.driver"),
+		Local: storage.LocalConfig{
+			Root: viper.GetString("storage.local.root"),
+		},
+		S3: storage.S3Config{
+			Endpoint:      viper.GetString("storage.s3.endpoint"),
+			Region:        viper.GetString("storage.s3.region"),
+			Bucket:        viper.GetString("storage.s3.bucket"),
+			AccessKey:     viper.GetString("storage.s3.access_key"),
+			SecretKey:     viper.GetString("storage.s3.secret_key"),
+			UseSSL:        viper.GetBool("storage.s3.use_ssl"),
+			ServeMode:     viper.GetString("storage.s3.serve_mode"),
+			PresignExpiry: viper.GetDuration("storage.s3.presign_expiry"),
+		},
+	})
+	if err != nil {
+		log.Fatalf("初始化存储后端失败: %s", err)
+	}
+
+	// 4. 预览或修复
+	reconciler := service.NewReconciler(store.NewIslandStore(db), store.NewDataFileStore(db), store.NewHistoryTrailStore(db), store.NewBlobStore(db), backend)
+	var report *service.ReconcileReport
+	if *confirm == "" {
+		report, err = reconciler.Scan(opts)
+	} else {
+		report, err = reconciler.Repair(opts, *confirm)
+	}
+	if err != nil {
+		log.Fatalf("对账失败: %s", err)
+	}
+
+	// 5. 输出报告，修复中有失败的项目时以非零状态退出
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("输出报告失败: %s", err)
+	}
+	t := report.Totals
+	log.Printf("孤立文件 %d 个 (%d 字节)，失效记录 %d 条，无引用内容 %d 个 (%d 字节)，引用数需修正 %d 个",
+		t.OrphanFiles, t.OrphanBytes, t.DanglingRows, t.StaleBlobs, t.StaleBlobBytes, t.RefCountFixes)
+	if report.DryRun {
+		log.Printf("以上为预览，确认后执行: reconcile -confirm %s", report.Fingerprint)
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"Go_for_unity/internal/handler"
 	"Go_for_unity/internal/model"
 	"Go_for_unity/internal/router"
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
 	"Go_for_unity/internal/store"
 	"Go_for_unity/internal/ws"
 	"fmt"
@@ -12,6 +14,8 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"log"
+	"os"
+	"path/filepath"
 )
 
 func main() {
@@ -38,32 +42,162 @@ func main() {
 	}
 
 	// 3. 自动迁移 (创建/更新表结构)
-	err = db.AutoMigrate(&model.Island{}, &model.DataFile{}, &model.HistoryTrail{})
+	err = db.AutoMigrate(&model.Island{}, &model.DataFile{}, &model.HistoryTrail{}, &model.UploadSession{}, &model.Job{}, &model.Blob{}, &model.WeatherStep{}, &model.MappingJoin{})
 	if err != nil {
 		log.Fatalf("数据库迁移失败: %s", err)
 	}
 	log.Println("数据库迁移成功！")
 
-	// 4. 依赖注入：创建 store 和 handler
+	// 4. 初始化存储后端 (local 或 s3)
+	viper.SetDefault("storage.driver", "local")
+	backend, err := storage.New(storage.Config{
+		Driver: viper.GetString("storage.driver"),
+		Local: storage.LocalConfig{
+			Root: viper.GetString("storage.local.root"),
+		},
+		S3: storage.S3Config{
+			Endpoint:      viper.GetString("storage.s3.endpoint"),
+			Region:        viper.GetString("storage.s3.region"),
+			Bucket:        viper.GetString("storage.s3.bucket"),
+			AccessKey:     viper.GetString("storage.s3.access_key"),
+			SecretKey:     viper.GetString("storage.s3.secret_key"),
+			UseSSL:        viper.GetBool("storage.s3.use_ssl"),
+			ServeMode:     viper.GetString("storage.s3.serve_mode"),
+			PresignExpiry: viper.GetDuration("storage.s3.presign_expiry"),
+		},
+	})
+	if err != nil {
+		log.Fatalf("初始化存储后端失败: %s", err)
+	}
+	if s3Backend, ok := backend.(*storage.S3); ok {
+		if err := s3Backend.EnsureBucket(); err != nil {
+			log.Fatalf("初始化 S3 存储桶失败: %s", err)
+		}
+	}
+	log.Printf("存储后端: %s", viper.GetString("storage.driver"))
+
+	// 上传临时文件和分片的目录：未配置 upload.staging_dir 时，本地存储放在 <storage.local.root>/uploads 下，
+	// S3 存储放在系统临时目录下，不写入工作目录
+	stagingDir := viper.GetString("upload.staging_dir")
+	if stagingDir == "" {
+		if viper.GetString("storage.driver") == "s3" {
+			stagingDir = filepath.Join(os.TempDir(), "go_for_unity")
+		} else {
+			stagingDir = filepath.Join(viper.GetString("storage.local.root"), "uploads")
+		}
+	}
+	service.SetStagingDir(stagingDir)
+	log.Printf("上传临时目录: %s", stagingDir)
+
+	// 缩略图：岛屿图片和 jpg 数据文件上传后生成
+	viper.SetDefault("thumbnails.sizes", service.DefaultThumbnailSizes)
+	thumbnails := service.NewThumbnailService(backend, viper.GetIntSlice("thumbnails.sizes"))
+	viper.SetDefault("thumbnails.max_pixels", service.DefaultMaxImagePixels)
+	service.SetMaxImagePixels(viper.GetInt64("thumbnails.max_pixels"))
+
+	// 配置文件中声明的额外数据类型，注册在内置类型之后
+	var dataTypes []service.ProcessorConfig
+	if err := viper.UnmarshalKey("data_types", &dataTypes); err != nil {
+		log.Fatalf("读取 data_types 配置失败: %s", err)
+	}
+	for _, cfg := range dataTypes {
+		p, err := service.NewConfigProcessor(cfg)
+		if err == nil {
+			err = service.RegisterProcessor(p)
+		}
+		if err != nil {
+			log.Fatalf("注册数据类型失败: %s", err)
+		}
+	}
+
+	// 上传内容按文件头和结构检查，upload.mime_types 按数据类型覆盖默认接受的 MIME 类型
+	viper.SetDefault("upload.island_mime_types", service.DefaultIslandPictureMIMETypes)
+	service.SetIslandPictureMIMETypes(viper.GetStringSlice("upload.island_mime_types"))
+	for dataType, types := range viper.GetStringMapStringSlice("upload.mime_types") {
+		if err := service.SetProcessorMIMETypes(dataType, types); err != nil {
+			log.Fatalf("配置 upload.mime_types 失败: %s", err)
+		}
+	}
+
+	// 5. 依赖注入：创建 store 和 handler
 	islandStore := store.NewIslandStore(db)
-	islandHandler := handler.NewIslandHandler(islandStore)
+	// 空间查询：MySQL 8 上使用空间索引，不支持时岛屿按网格编号、数据范围按 B-tree 联合索引查询
+	if err := islandStore.BackfillGridCells(); err != nil {
+		log.Fatalf("补齐岛屿网格编号失败: %s", err)
+	}
+	if err := islandStore.EnableSpatialIndex(); err != nil {
+		log.Printf("岛屿中心点无法使用空间索引，改用网格编号: %s", err)
+	}
+	islandHandler := handler.NewIslandHandler(islandStore, backend, thumbnails)
 	dataFileStore := store.NewDataFileStore(db)
+	if err := dataFileStore.EnableSpatialIndex(); err != nil {
+		log.Printf("数据范围无法使用空间索引，改用 B-tree 索引: %s", err)
+	}
+	// 引入版本链之前创建的文件记录各自成为一个版本链
+	if err := dataFileStore.BackfillLineage(); err != nil {
+		log.Fatalf("补齐文件版本链失败: %s", err)
+	}
+	// 按文件 ID 保存的旧 CSV 关联改为指向版本链
+	mappingJoinStore := store.NewMappingJoinStore(db)
+	if err := mappingJoinStore.BackfillLineage(); err != nil {
+		log.Fatalf("补齐 CSV 关联的版本链失败: %s", err)
+	}
 	historyTrailStore := store.NewHistoryTrailStore(db)
-	wsManager := ws.NewManager()                                              // 创建 WebSocket 管理器
-	dataFileHandler := handler.NewDataFileHandler(dataFileStore, islandStore) // 注意这里需要传入两个 store
+	blobStore := store.NewBlobStore(db)
+	wsManager := ws.NewManager()                                                                   // 创建 WebSocket 管理器
+	contentStore := service.NewContentStore(blobStore, backend)                                    // 按 SHA-256 去重存储上传内容
+	ingestor := service.NewIngestor(dataFileStore, islandStore, contentStore, backend, thumbnails) // 负责按类型处理上传文件
+
+	// 后台任务：上传文件的解压和校验在 worker 池中进行，进度通过 WebSocket 推送
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.queue_size", 100)
+	jobStore := store.NewJobStore(db)
+	jobRunner := service.NewJobRunner(jobStore, wsManager, viper.GetInt("jobs.workers"), viper.GetInt("jobs.queue_size"))
+	jobRunner.Start()
+	jobHandler := handler.NewJobHandler(jobStore)
+
+	geoJSONService := service.NewGeoJSONService(backend)
+	mappingService := service.NewMappingService(backend)
+	dataFileEditor := service.NewDataFileEditor(dataFileStore, islandStore, contentStore, backend, thumbnails, ingestor)
+	dataFileHandler := handler.NewDataFileHandler(dataFileStore, islandStore, ingestor, jobRunner, contentStore, backend, geoJSONService, thumbnails, dataFileEditor) // 注意这里需要传入两个 store
 	exportHandler := handler.NewExportHandler(islandStore, dataFileStore, wsManager)
 	wsHandler := handler.NewWebsocketHandler(wsManager) // 创建 WebSocket 处理器
-	historyTrailHandler := handler.NewHistoryTrailHandler(historyTrailStore)
+	historyTrailHandler := handler.NewHistoryTrailHandler(historyTrailStore, backend)
+	storageHandler := handler.NewStorageHandler(backend)
+	spatialHandler := handler.NewSpatialHandler(service.NewSpatialService(islandStore, dataFileStore))
+	weatherHandler := handler.NewWeatherHandler(dataFileStore, service.NewWeatherService(store.NewWeatherStepStore(db)))
+	mappingHandler := handler.NewMappingHandler(dataFileStore, mappingService)
+	joinService := service.NewJoinService(mappingJoinStore, dataFileStore, backend, mappingService, geoJSONService)
+	joinHandler := handler.NewJoinHandler(joinService, backend)
+	versionHandler := handler.NewVersionHandler(service.NewVersionService(dataFileStore))
+	importHandler := handler.NewImportHandler(islandStore, service.NewBatchImporter(ingestor, dataFileStore), jobRunner)
+	downloadHandler := handler.NewDownloadHandler(dataFileStore, service.NewDownloadService(dataFileStore, blobStore, islandStore, backend), backend)
 	logHandler := handler.NewLogHandler(islandStore, dataFileStore, historyTrailStore)
-	// 5. 初始化 Gin 引擎
+
+	// 存储对账：预览后按 fingerprint 确认修复，修改时间在 reconcile.min_age 之内的文件不处理
+	viper.SetDefault("reconcile.min_age", "1h")
+	reconciler := service.NewReconciler(islandStore, dataFileStore, historyTrailStore, blobStore, backend)
+	adminHandler := handler.NewAdminHandler(reconciler, viper.GetDuration("reconcile.min_age"))
+
+	// 分片上传：会话在最后一次活动后保留 upload.session_ttl，过期后由后台协程清理
+	viper.SetDefault("upload.session_ttl", "24h")
+	viper.SetDefault("upload.cleanup_interval", "10m")
+	uploadSessionStore := store.NewUploadSessionStore(db)
+	uploadSessionService := service.NewUploadSessionService(uploadSessionStore, islandStore, ingestor, jobRunner, viper.GetDuration("upload.session_ttl"))
+	uploadSessionService.RecoverInterrupted()
+	uploadSessionService.StartJanitor(viper.GetDuration("upload.cleanup_interval"))
+	uploadSessionHandler := handler.NewUploadSessionHandler(uploadSessionService)
+
+	// 6. 初始化 Gin 引擎
 	r := gin.Default()
-	// 增加 Body 大小限制，防止上传大文件时出错
-	r.MaxMultipartMemory = 2 << 30 // 2 GB
+	// 表单上传时最多在内存中缓存 32 MB，超出部分由 Gin 写入临时文件
+	// 大文件请使用 /api/v1/uploads 分片上传接口，避免占用大量内存且支持断点续传
+	r.MaxMultipartMemory = 32 << 20 // 32 MB
 
-	// 6. 设置路由
-	router.Setup(r, islandHandler, dataFileHandler, exportHandler, wsHandler, historyTrailHandler, logHandler)
+	// 7. 设置路由
+	router.Setup(r, islandHandler, dataFileHandler, exportHandler, wsHandler, historyTrailHandler, logHandler, uploadSessionHandler, jobHandler, storageHandler, spatialHandler, handler.NewCRSHandler(), weatherHandler, mappingHandler, joinHandler, versionHandler, importHandler, downloadHandler, adminHandler)
 
-	// 7. 启动服务器
+	// 8. 启动服务器
 	// All the Go project developed by LaputaMao will listen on port 9090 , just because 9090 like 'gogo' hhh.
 	log.Println("服务器启动，监听端口 :9090")
 	if err := r.Run(":9090"); err != nil {
@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultWorkers 默认的并发解压 worker 数量
+const DefaultWorkers = 4
+
+// ProgressFunc 解压进度回调，done/total 均为未压缩字节数
+// 回调会被串行调用，实现方无需自己加锁
+type ProgressFunc func(done, total int64)
+
+// ExtractOptions 控制解压行为
+type ExtractOptions struct {
+	Workers  int          // 并发写盘的 worker 数，<=0 时使用 DefaultWorkers
+	Progress ProgressFunc // 进度回调，可为 nil
+}
+
+// Extract 将 src 指向的 zip 包解压到 dest 目录
+// 与旧的 unzip 不同：每个条目写完立即关闭文件句柄，不会因为条目过多而耗尽 ulimit；
+// 同时使用有限数量的 worker 并发解压，并通过 Progress 回调汇报进度。
+func Extract(src, dest string, opts ExtractOptions) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	// 1. 先串行创建目录并校验路径，同时统计总字节数
+	var files []*zip.File
+	var total int64
+	for _, f := range r.File {
+		path, err := entryPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		files = append(files, f)
+		total += int64(f.UncompressedSize64)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	// 2. 使用有限的 worker 并发解压文件
+	reporter := &progressReporter{total: total, fn: opts.Progress}
+	jobs := make(chan *zip.File)
+	done := make(chan struct{})
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				if err := extractFile(f, dest, reporter); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, f := range files {
+		select {
+		case jobs <- f:
+		case <-done:
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	reporter.finish()
+	return nil
+}
+
+// extractFile 解压单个条目，函数返回前就关闭读写两端的句柄
+func extractFile(f *zip.File, dest string, reporter *progressReporter) error {
+	path, err := entryPath(dest, f.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, &countingReader{r: rc, reporter: reporter})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// entryPath 计算条目的落盘路径，并防止 "../" 之类的路径穿越 (zip slip)
+func entryPath(dest, name string) (string, error) {
+	path := filepath.Join(dest, name)
+	cleanDest := filepath.Clean(dest) + string(os.PathSeparator)
+	if !strings.HasPrefix(path+string(os.PathSeparator), cleanDest) {
+		return "", fmt.Errorf("压缩包中包含非法路径: %s", name)
+	}
+	return path, nil
+}
+
+// progressReporter 汇总各个 worker 的进度，并串行地调用回调
+type progressReporter struct {
+	mu       sync.Mutex
+	done     int64
+	total    int64
+	lastSent int64
+	fn       ProgressFunc
+}
+
+// add 累加已解压的字节数，每增长 1% 才回调一次，避免回调过于频繁
+func (p *progressReporter) add(n int64) {
+	if p.fn == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	if p.total > 0 && (p.done-p.lastSent)*100 < p.total {
+		return
+	}
+	p.lastSent = p.done
+	p.fn(p.done, p.total)
+}
+
+// finish 保证最后一次回调一定是 100%
+func (p *progressReporter) finish() {
+	if p.fn == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lastSent != p.total || p.total == 0 {
+		p.fn(p.total, p.total)
+	}
+}
+
+// countingReader 在读取时统计字节数
+type countingReader struct {
+	r        io.Reader
+	reporter *progressReporter
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if n > 0 {
+		c.reporter.add(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,50 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ZipDir 把 srcDir 目录下的所有文件打包为 dst，条目路径相对于 srcDir
+// 条目按路径顺序写入且不记录修改时间，相同内容的目录总是得到相同的压缩包 (内容哈希可用于去重)
+func ZipDir(srcDir, dst string) (err error) {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	zw := zip.NewWriter(out)
+	err = filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
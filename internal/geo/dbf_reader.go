@@ -0,0 +1,127 @@
+package geo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DBFReader 顺序读取 .dbf 中的属性记录
+type DBFReader struct {
+	r      *bufio.Reader
+	Header DBFHeader
+	decode Decoder
+	record []byte
+	read   int // 已读取的记录数
+}
+
+// NewDBFReader 读取文件头并定位到第一条记录，decode 为 nil 时根据语言驱动 ID 推断编码
+func NewDBFReader(r io.Reader, decode Decoder) (*DBFReader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	header, err := ReadDBFHeader(br, decode)
+	if err != nil {
+		return nil, err
+	}
+	// 字段描述之后可能还有填充字节，跳到 HeaderLength 处
+	if skip := header.HeaderLength - header.consumed; skip > 0 {
+		if _, err := io.CopyN(io.Discard, br, int64(skip)); err != nil {
+			return nil, fmt.Errorf("dbf 文件头不完整: %w", err)
+		}
+	}
+	// 记录至少要容纳删除标记和所有字段，否则无法按字段切分 (记录长度为 0 时也无法读取删除标记)
+	need := 1
+	for _, f := range header.Fields {
+		need += f.Length
+	}
+	if header.RecordLength < need {
+		return nil, fmt.Errorf("dbf 记录长度 %d 小于字段总长度 %d", header.RecordLength, need)
+	}
+	if decode == nil {
+		decode = DecoderFor("", header.LanguageID)
+	}
+	return &DBFReader{r: br, Header: header, decode: decode, record: make([]byte, header.RecordLength)}, nil
+}
+
+// Next 读取下一条记录，返回字段值 (与 Header.Fields 顺序一致) 和是否已被标记删除
+// 读完文件头中声明的记录数或遇到文件结束标记 (0x1A) 时返回 io.EOF
+func (d *DBFReader) Next() ([]interface{}, bool, error) {
+	if d.read >= d.Header.RecordCount {
+		return nil, false, io.EOF
+	}
+	// 先读删除标记：文件结束标记只有一个字节，不能按整条记录读取
+	// 记录数不足时按文件被截断处理
+	if _, err := io.ReadFull(d.r, d.record[:1]); err != nil {
+		return nil, false, truncatedDBF(err)
+	}
+	if d.record[0] == 0x1A {
+		return nil, false, io.EOF
+	}
+	if _, err := io.ReadFull(d.r, d.record[1:]); err != nil {
+		return nil, false, truncatedDBF(err)
+	}
+	d.read++
+	deleted := d.record[0] == '*'
+
+	values := make([]interface{}, len(d.Header.Fields))
+	offset := 1
+	for i, f := range d.Header.Fields {
+		end := offset + f.Length
+		if end > len(d.record) {
+			return nil, false, fmt.Errorf("dbf 记录长度与字段定义不符")
+		}
+		values[i] = d.parseValue(f, d.record[offset:end])
+		offset = end
+	}
+	return values, deleted, nil
+}
+
+// truncatedDBF 读取记录时遇到文件结尾，返回不会被当作正常结束的错误
+func truncatedDBF(err error) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("dbf 记录不完整: %w", err)
+}
+
+// parseValue 按字段类型转换为 JSON 友好的值，空值返回 nil
+func (d *DBFReader) parseValue(f DBFField, raw []byte) interface{} {
+	switch f.Type {
+	case "number", "float":
+		s := strings.TrimSpace(string(raw))
+		if s == "" || strings.Trim(s, "*") == "" {
+			return nil
+		}
+		if f.Decimals == 0 {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		}
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+		return nil
+	case "bool":
+		switch strings.ToUpper(strings.TrimSpace(string(raw))) {
+		case "T", "Y":
+			return true
+		case "F", "N":
+			return false
+		}
+		return nil
+	case "date":
+		s := strings.TrimSpace(string(raw))
+		if len(s) != 8 {
+			return nil
+		}
+		return s[0:4] + "-" + s[4:6] + "-" + s[6:8]
+	default:
+		s := strings.TrimSpace(d.decode(raw))
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+}
@@ -0,0 +1,140 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// dbfField 构造测试文件时的字段定义
+type dbfField struct {
+	name     string
+	typ      byte
+	length   int
+	decimals int
+}
+
+// buildDBF 拼出完整的 .dbf，recordLength 为 0 时按字段长度计算
+func buildDBF(fields []dbfField, recordLength int, records ...string) []byte {
+	if recordLength == 0 {
+		recordLength = 1
+		for _, f := range fields {
+			recordLength += f.length
+		}
+	}
+	headerLength := 32 + 32*len(fields) + 1
+
+	var buf bytes.Buffer
+	header := make([]byte, 32)
+	header[0] = 0x03
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(records)))
+	binary.LittleEndian.PutUint16(header[8:10], uint16(headerLength))
+	binary.LittleEndian.PutUint16(header[10:12], uint16(recordLength))
+	buf.Write(header)
+	for _, f := range fields {
+		desc := make([]byte, 32)
+		copy(desc[:11], f.name)
+		desc[11] = f.typ
+		desc[16] = byte(f.length)
+		desc[17] = byte(f.decimals)
+		buf.Write(desc)
+	}
+	buf.WriteByte(0x0D)
+	for _, rec := range records {
+		buf.WriteString(rec)
+	}
+	buf.WriteByte(0x1A)
+	return buf.Bytes()
+}
+
+var testDBFFields = []dbfField{
+	{"NAME", 'C', 6, 0},
+	{"CODE", 'N', 4, 0},
+	{"AREA", 'N', 6, 2},
+	{"OK", 'L', 1, 0},
+	{"DAY", 'D', 8, 0},
+}
+
+func readAllRecords(data []byte) ([][]interface{}, []bool, error) {
+	r, err := NewDBFReader(bytes.NewReader(data), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var rows [][]interface{}
+	var deleted []bool
+	for {
+		values, del, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return rows, deleted, nil
+		}
+		if err != nil {
+			return rows, deleted, err
+		}
+		rows = append(rows, values)
+		deleted = append(deleted, del)
+	}
+}
+
+func TestDBFReaderValid(t *testing.T) {
+	data := buildDBF(testDBFFields, 0,
+		" "+"road  "+"  12"+"  3.50"+"T"+"20240131",
+		"*"+"river "+"    "+"******"+"?"+"        ",
+	)
+	rows, deleted, err := readAllRecords(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{
+		{"road", int64(12), 3.5, true, "2024-01-31"},
+		{"river", nil, nil, nil, nil}, // 空值和溢出的数值 (*) 为 nil
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("记录解析结果为 %#v, 期望 %#v", rows, want)
+	}
+	if !reflect.DeepEqual(deleted, []bool{false, true}) {
+		t.Fatalf("删除标记为 %v", deleted)
+	}
+
+	// 没有文件结束标记时按记录数结束
+	rows, _, err = readAllRecords(data[:len(data)-1])
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("没有结束标记的文件读取到 %d 条记录: %v", len(rows), err)
+	}
+}
+
+func TestDBFReaderMalformed(t *testing.T) {
+	valid := buildDBF(testDBFFields, 0, " road    12  3.50T20240131")
+	badHeaderLength := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint16(badHeaderLength[8:10], 20)
+	// 没有字段且记录长度为 0：不能在读取删除标记时越界
+	zeroRecord := buildDBF(nil, 0)
+	binary.LittleEndian.PutUint16(zeroRecord[10:12], 0)
+
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"文件头不完整", valid[:20], "读取 dbf 文件头失败"},
+		{"文件头长度无效", badHeaderLength, "文件头长度无效"},
+		{"字段描述不完整", valid[:32+40], "读取 dbf 字段描述失败"},
+		{"记录长度小于字段总长度", buildDBF(testDBFFields, 10, " road    12"), "小于字段总长度"},
+		{"记录长度为 0", zeroRecord, "小于字段总长度"},
+		{"记录不完整", valid[:len(valid)-10], "dbf 记录不完整"},
+		{"记录数不足", valid[:len(valid)-27], "dbf 记录不完整"},
+	}
+	for _, c := range cases {
+		_, _, err := readAllRecords(c.data)
+		if err == nil {
+			t.Errorf("%s: 应返回错误", c.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: 错误为 %q, 期望包含 %q", c.name, err, c.want)
+		}
+	}
+}
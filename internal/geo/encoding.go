@@ -0,0 +1,49 @@
+package geo
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Decoder 把文件中的原始字节解码为字符串
+type Decoder func([]byte) string
+
+// DBFLanguageGBK dbf 文件头中表示 GBK (代码页 936) 的语言驱动 ID
+const DBFLanguageGBK = 0x4D
+
+// DecoderFor 根据 .cpg 内容和 dbf 语言驱动 ID 选择解码方式
+// 国内数据常见 GBK 编码；无法判断时，合法的 UTF-8 按原样使用，否则按 GBK 解码
+func DecoderFor(cpg string, languageID byte) Decoder {
+	switch strings.ToUpper(strings.TrimSpace(cpg)) {
+	case "UTF-8", "UTF8", "65001":
+		return decodeUTF8
+	case "GBK", "GB2312", "GB18030", "936", "CP936":
+		return DecodeGBK
+	}
+	if languageID == DBFLanguageGBK {
+		return DecodeGBK
+	}
+	return decodeAuto
+}
+
+// DecodeGBK 按 GBK 解码，失败时原样返回
+func DecodeGBK(b []byte) string {
+	out, err := simplifiedchinese.GBK.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(out)
+}
+
+func decodeUTF8(b []byte) string {
+	return string(b)
+}
+
+func decodeAuto(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return DecodeGBK(b)
+}
@@ -0,0 +1,161 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// gaussKruger CGCS2000 高斯-克吕格投影 (横轴墨卡托，比例因子 1，东偏 500km)
+// 带号前缀的坐标系 X 坐标前面加上带号 (例如 39500000)
+type gaussKruger struct {
+	centralMeridian float64 // 中央经线 (度)
+	zone            int     // 带号，X 坐标不带带号前缀时为 0
+}
+
+// gaussKrugerByEPSG 按 EPSG 代码查找 CGCS2000 高斯-克吕格投影
+//
+//	4491-4501: 6 度带第 13-23 带，X 带带号
+//	4502-4512: 6 度带中央经线 75E-135E，X 不带带号
+//	4513-4533: 3 度带第 25-45 带，X 带带号
+//	4534-4554: 3 度带中央经线 75E-135E，X 不带带号
+func gaussKrugerByEPSG(code int) (gaussKruger, bool) {
+	switch {
+	case code >= 4491 && code <= 4501:
+		zone := code - 4491 + 13
+		return gaussKruger{centralMeridian: float64(zone*6 - 3), zone: zone}, true
+	case code >= 4502 && code <= 4512:
+		return gaussKruger{centralMeridian: float64(75 + (code-4502)*6)}, true
+	case code >= 4513 && code <= 4533:
+		zone := code - 4513 + 25
+		return gaussKruger{centralMeridian: float64(zone * 3), zone: zone}, true
+	case code >= 4534 && code <= 4554:
+		return gaussKruger{centralMeridian: float64(75 + (code-4534)*3)}, true
+	}
+	return gaussKruger{}, false
+}
+
+// GaussKrugerEPSG 根据分带宽度 (3 或 6) 和中央经线返回不带带号的 CGCS2000 高斯-克吕格 EPSG 代码，不存在时返回 0
+func GaussKrugerEPSG(width int, centralMeridian int) int {
+	if centralMeridian < 75 || centralMeridian > 135 || (centralMeridian-75)%width != 0 {
+		return 0
+	}
+	switch width {
+	case 3:
+		return 4534 + (centralMeridian-75)/3
+	case 6:
+		return 4502 + (centralMeridian-75)/6
+	}
+	return 0
+}
+
+// 横轴墨卡托的 Krüger 级数系数 (6 阶)，见 Karney (2011) "Transverse Mercator with an accuracy of a few nanometers"
+var tmSeries = newTMSeries(cgcs2000A, cgcs2000F)
+
+type tmCoefficients struct {
+	e     float64    // 第一偏心率
+	a     float64    // 子午线弧长的归一化系数 A
+	alpha [6]float64 // 正算系数
+	beta  [6]float64 // 反算系数
+}
+
+func newTMSeries(a, f float64) tmCoefficients {
+	n := f / (2 - f)
+	n2, n3 := n*n, n*n*n
+	n4, n5, n6 := n2*n2, n2*n3, n3*n3
+	return tmCoefficients{
+		e: math.Sqrt(f * (2 - f)),
+		a: a / (1 + n) * (1 + n2/4 + n4/64 + n6/256),
+		alpha: [6]float64{
+			n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288 + 7891*n6/37800,
+			13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630 - 1983433*n6/1935360,
+			61*n3/240 - 103*n4/140 + 15061*n5/26880 + 167603*n6/181440,
+			49561*n4/161280 - 179*n5/168 + 6601661*n6/7257600,
+			34729*n5/80640 - 3418889*n6/1995840,
+			212378941 * n6 / 319334400,
+		},
+		beta: [6]float64{
+			n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512 + 96199*n6/604800,
+			n2/48 + n3/15 - 437*n4/1440 + 46*n5/105 - 1118711*n6/3870720,
+			17*n3/480 - 37*n4/840 - 209*n5/4480 + 5569*n6/90720,
+			4397*n4/161280 - 11*n5/504 - 830251*n6/7257600,
+			4583*n5/161280 - 108847*n6/3991680,
+			20648693 * n6 / 638668800,
+		},
+	}
+}
+
+func (g gaussKruger) falseEasting() float64 {
+	return 500000 + float64(g.zone)*1e6
+}
+
+func (g gaussKruger) fromLonLat(lon, lat float64) (float64, float64, error) {
+	if err := checkLonLat(lon, lat); err != nil {
+		return 0, 0, err
+	}
+	s := tmSeries
+	phi := lat * math.Pi / 180
+	lambda := (lon - g.centralMeridian) * math.Pi / 180
+
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - s.e*math.Atanh(s.e*math.Sin(phi)))
+	xi0 := math.Atan2(t, math.Cos(lambda))
+	eta0 := math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+
+	xi, eta := xi0, eta0
+	for j, alpha := range s.alpha {
+		k := 2 * float64(j+1)
+		xi += alpha * math.Sin(k*xi0) * math.Cosh(k*eta0)
+		eta += alpha * math.Cos(k*xi0) * math.Sinh(k*eta0)
+	}
+	return g.falseEasting() + s.a*eta, s.a * xi, nil
+}
+
+func (g gaussKruger) toLonLat(x, y float64) (float64, float64, error) {
+	if g.zone > 0 && int(x/1e6) != g.zone {
+		return 0, 0, fmt.Errorf("X 坐标 %v 与第 %d 带不符，应带有带号前缀", x, g.zone)
+	}
+	s := tmSeries
+	xi := y / s.a
+	eta := (x - g.falseEasting()) / s.a
+
+	xi0, eta0 := xi, eta
+	for j, beta := range s.beta {
+		k := 2 * float64(j+1)
+		xi0 -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta0 -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	sinhEta := math.Sinh(eta0)
+	cosXi := math.Cos(xi0)
+	tau0 := math.Sin(xi0) / math.Sqrt(sinhEta*sinhEta+cosXi*cosXi)
+	lambda := math.Atan2(sinhEta, cosXi)
+
+	// 牛顿迭代由等角纬度的正切求大地纬度的正切
+	e2 := s.e * s.e
+	tau := tau0
+	for i := 0; i < 10; i++ {
+		sigma := math.Sinh(s.e * math.Atanh(s.e*tau/math.Sqrt(1+tau*tau)))
+		tauI := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+		d := (tau0 - tauI) / math.Sqrt(1+tauI*tauI) * (1 + (1-e2)*tau*tau) / ((1 - e2) * math.Sqrt(1+tau*tau))
+		tau += d
+		if math.Abs(d) < 1e-12 {
+			break
+		}
+	}
+
+	lon := g.centralMeridian + lambda*180/math.Pi
+	lat := math.Atan(tau) * 180 / math.Pi
+	return lon, lat, checkLonLat(lon, lat)
+}
+
+// gaussKrugerName 生成与 EPSG 一致的坐标系名称
+func gaussKrugerName(code int) string {
+	g, _ := gaussKrugerByEPSG(code)
+	width := 3
+	if code <= 4512 {
+		width = 6
+	}
+	if g.zone > 0 {
+		return fmt.Sprintf("CGCS2000 / %d-degree Gauss-Kruger zone %d", width, g.zone)
+	}
+	return fmt.Sprintf("CGCS2000 / %d-degree Gauss-Kruger CM %dE", width, int(g.centralMeridian))
+}
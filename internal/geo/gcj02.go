@@ -0,0 +1,67 @@
+package geo
+
+import "math"
+
+// gcj02 国测局 GCJ-02 坐标，在 WGS84 经纬度上叠加非线性偏移，中国境外不偏移
+type gcj02 struct{}
+
+// Krasovsky 1940 椭球参数，GCJ-02 偏移算法使用
+const (
+	krasovskyA  = 6378245.0
+	krasovskyE2 = 0.00669342162296594323
+)
+
+func (gcj02) fromLonLat(lon, lat float64) (float64, float64, error) {
+	if err := checkLonLat(lon, lat); err != nil {
+		return 0, 0, err
+	}
+	dLon, dLat := gcjOffset(lon, lat)
+	return lon + dLon, lat + dLat, nil
+}
+
+// toLonLat 偏移没有解析逆变换，用迭代逼近，精度优于 1e-9 度
+func (gcj02) toLonLat(x, y float64) (float64, float64, error) {
+	if err := checkLonLat(x, y); err != nil {
+		return 0, 0, err
+	}
+	lon, lat := x, y
+	for i := 0; i < 30; i++ {
+		dLon, dLat := gcjOffset(lon, lat)
+		errLon, errLat := lon+dLon-x, lat+dLat-y
+		lon -= errLon
+		lat -= errLat
+		if math.Abs(errLon) < 1e-9 && math.Abs(errLat) < 1e-9 {
+			break
+		}
+	}
+	return lon, lat, nil
+}
+
+// gcjOffset 计算 WGS84 坐标对应的 GCJ-02 偏移量 (度)
+func gcjOffset(lon, lat float64) (float64, float64) {
+	if outOfChina(lon, lat) {
+		return 0, 0
+	}
+	x, y := lon-105, lat-35
+	dLat := -100 + 2*x + 3*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	dLat += (20*math.Sin(6*x*math.Pi) + 20*math.Sin(2*x*math.Pi)) * 2 / 3
+	dLat += (20*math.Sin(y*math.Pi) + 40*math.Sin(y/3*math.Pi)) * 2 / 3
+	dLat += (160*math.Sin(y/12*math.Pi) + 320*math.Sin(y*math.Pi/30)) * 2 / 3
+
+	dLon := 300 + x + 2*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	dLon += (20*math.Sin(6*x*math.Pi) + 20*math.Sin(2*x*math.Pi)) * 2 / 3
+	dLon += (20*math.Sin(x*math.Pi) + 40*math.Sin(x/3*math.Pi)) * 2 / 3
+	dLon += (150*math.Sin(x/12*math.Pi) + 300*math.Sin(x/30*math.Pi)) * 2 / 3
+
+	radLat := lat / 180 * math.Pi
+	magic := 1 - krasovskyE2*math.Sin(radLat)*math.Sin(radLat)
+	sqrtMagic := math.Sqrt(magic)
+	dLat = dLat * 180 / ((krasovskyA * (1 - krasovskyE2)) / (magic * sqrtMagic) * math.Pi)
+	dLon = dLon * 180 / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return dLon, dLat
+}
+
+// outOfChina 粗略判断是否在中国境外
+func outOfChina(lon, lat float64) bool {
+	return lon < 72.004 || lon > 137.8347 || lat < 0.8293 || lat > 55.8271
+}
@@ -0,0 +1,281 @@
+package geo
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrUnknownField 请求的属性字段在 dbf 中不存在
+var ErrUnknownField = errors.New("属性字段不存在")
+
+// GeoJSONOptions 控制 shp 转 GeoJSON 的输出
+type GeoJSONOptions struct {
+	BBox      *[4]float64 // 仅输出外包矩形与该范围相交的要素，nil 表示不过滤
+	Fields    []string    // 仅输出这些属性字段，nil 表示全部
+	Precision int         // 坐标保留的小数位数，小于 0 表示不处理
+	CRS       string      // 非 WGS84 时写入 crs 成员，例如 EPSG:4490
+
+	// Extra 根据完整的 dbf 记录 (与 Header.Fields 顺序一致) 返回要追加到 properties 的属性，可为 nil
+	// 例如按关键字段关联 CSV 中的属性；与已有属性同名时不会覆盖
+	Extra func(values []interface{}) map[string]interface{}
+}
+
+// WriteGeoJSON 把 shp (以及可选的 dbf) 转换为 GeoJSON FeatureCollection 写入 w
+// 记录按顺序流式处理，返回输出的要素数量
+func WriteGeoJSON(w io.Writer, shp *ShpReader, dbf *DBFReader, opts GeoJSONOptions) (int, error) {
+	fieldIndex, err := selectFields(dbf, opts.Fields)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriterSize(w, 64<<10)
+	bw.WriteString(`{"type":"FeatureCollection"`)
+	if opts.CRS != "" && opts.CRS != "EPSG:4326" {
+		crs, _ := json.Marshal(map[string]interface{}{
+			"type":       "name",
+			"properties": map[string]string{"name": opts.CRS},
+		})
+		bw.WriteString(`,"crs":`)
+		bw.Write(crs)
+	}
+	bw.WriteString(`,"features":[`)
+
+	count := 0
+	for index := 0; ; index++ {
+		shape, err := shp.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("读取第 %d 条几何记录失败: %w", index+1, err)
+		}
+
+		var values []interface{}
+		deleted := false
+		if dbf != nil {
+			values, deleted, err = dbf.Next()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return count, fmt.Errorf("读取第 %d 条属性记录失败: %w", index+1, err)
+			}
+		}
+		if deleted || !intersects(shape, opts.BBox) {
+			continue
+		}
+
+		properties := make(map[string]interface{}, len(fieldIndex))
+		for _, fi := range fieldIndex {
+			if fi.index < len(values) {
+				properties[fi.name] = values[fi.index]
+			} else {
+				properties[fi.name] = nil
+			}
+		}
+		if opts.Extra != nil {
+			for name, v := range opts.Extra(values) {
+				if _, exists := properties[name]; !exists {
+					properties[name] = v
+				}
+			}
+		}
+		feature := map[string]interface{}{
+			"type":       "Feature",
+			"id":         index,
+			"geometry":   toGeometry(shape, opts.Precision),
+			"properties": properties,
+		}
+		b, err := json.Marshal(feature)
+		if err != nil {
+			return count, err
+		}
+		if count > 0 {
+			bw.WriteByte(',')
+		}
+		bw.Write(b)
+		count++
+	}
+
+	bw.WriteString("]}")
+	return count, bw.Flush()
+}
+
+type fieldRef struct {
+	name  string
+	index int
+}
+
+// selectFields 根据请求的字段名找到它们在 dbf 中的位置，未知字段返回错误
+func selectFields(dbf *DBFReader, names []string) ([]fieldRef, error) {
+	if dbf == nil {
+		if len(names) > 0 {
+			return nil, fmt.Errorf("%w: 该图层没有属性表", ErrUnknownField)
+		}
+		return nil, nil
+	}
+	all := make(map[string]int, len(dbf.Header.Fields))
+	var refs []fieldRef
+	for i, f := range dbf.Header.Fields {
+		all[f.Name] = i
+		if names == nil {
+			refs = append(refs, fieldRef{name: f.Name, index: i})
+		}
+	}
+	for _, name := range names {
+		i, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+		refs = append(refs, fieldRef{name: name, index: i})
+	}
+	return refs, nil
+}
+
+// intersects 判断几何外包矩形是否与过滤范围相交
+func intersects(shape *Shape, bbox *[4]float64) bool {
+	if bbox == nil {
+		return true
+	}
+	if shape.IsNull() {
+		return false
+	}
+	return shape.BBox[0] <= bbox[2] && shape.BBox[2] >= bbox[0] &&
+		shape.BBox[1] <= bbox[3] && shape.BBox[3] >= bbox[1]
+}
+
+// toGeometry 把 shp 几何转换为 GeoJSON geometry 对象
+func toGeometry(shape *Shape, precision int) interface{} {
+	if shape.IsNull() {
+		return nil
+	}
+	switch shape.Type {
+	case 1, 11, 21:
+		return geometry("Point", coord(shape.Parts[0][0], precision))
+
+	case 8, 18, 28:
+		points := make([]interface{}, 0, len(shape.Parts))
+		for _, part := range shape.Parts {
+			points = append(points, coord(part[0], precision))
+		}
+		return geometry("MultiPoint", points)
+
+	case 3, 13, 23:
+		lines := make([]interface{}, 0, len(shape.Parts))
+		for _, part := range shape.Parts {
+			lines = append(lines, coords(part, precision))
+		}
+		if len(lines) == 1 {
+			return geometry("LineString", lines[0])
+		}
+		return geometry("MultiLineString", lines)
+
+	default: // 5, 15, 25 面
+		polygons := groupRings(shape.Parts)
+		out := make([]interface{}, 0, len(polygons))
+		for _, rings := range polygons {
+			polygon := make([]interface{}, 0, len(rings))
+			for _, ring := range rings {
+				polygon = append(polygon, coords(ring, precision))
+			}
+			out = append(out, polygon)
+		}
+		if len(out) == 1 {
+			return geometry("Polygon", out[0])
+		}
+		return geometry("MultiPolygon", out)
+	}
+}
+
+// groupRings 把 shp 的环分组为多边形：shp 中外环为顺时针、内环 (洞) 为逆时针；
+// GeoJSON (RFC 7946) 要求外环逆时针、内环顺时针，这里同时反转方向
+func groupRings(rings [][]Point) [][][]Point {
+	var polygons [][][]Point
+	var holes [][]Point
+	for _, ring := range rings {
+		if len(ring) < 4 {
+			continue
+		}
+		if signedArea(ring) <= 0 { // 顺时针 => 外环
+			polygons = append(polygons, [][]Point{reversed(ring)})
+		} else {
+			holes = append(holes, reversed(ring))
+		}
+	}
+	for _, hole := range holes {
+		target := -1
+		for i, polygon := range polygons {
+			if containsPoint(polygon[0], hole[0]) {
+				target = i
+				break
+			}
+		}
+		if target < 0 {
+			if len(polygons) == 0 {
+				// 只有逆时针环的数据 (方向写反了)，把它们当作外环
+				polygons = append(polygons, [][]Point{reversed(hole)})
+				continue
+			}
+			target = len(polygons) - 1
+		}
+		polygons[target] = append(polygons[target], hole)
+	}
+	return polygons
+}
+
+// signedArea 鞋带公式计算有向面积，逆时针为正
+func signedArea(ring []Point) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i].X*ring[i+1].Y - ring[i+1].X*ring[i].Y
+	}
+	return area / 2
+}
+
+// containsPoint 射线法判断点是否在环内
+func containsPoint(ring []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func reversed(ring []Point) []Point {
+	out := make([]Point, len(ring))
+	for i, p := range ring {
+		out[len(ring)-1-i] = p
+	}
+	return out
+}
+
+func geometry(kind string, coordinates interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "coordinates": coordinates}
+}
+
+func coords(points []Point, precision int) [][]float64 {
+	out := make([][]float64, len(points))
+	for i, p := range points {
+		out[i] = coord(p, precision)
+	}
+	return out
+}
+
+func coord(p Point, precision int) []float64 {
+	if p.HasZ {
+		return []float64{round(p.X, precision), round(p.Y, precision), round(p.Z, precision)}
+	}
+	return []float64{round(p.X, precision), round(p.Y, precision)}
+}
+
+func round(v float64, precision int) float64 {
+	if precision < 0 {
+		return v
+	}
+	scale := math.Pow(10, float64(precision))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,82 @@
+package geo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CRSInfo 从 .prj (WKT) 中解析出的坐标系信息
+type CRSInfo struct {
+	Name       string // 坐标系名称，例如 CGCS2000_3_Degree_GK_CM_117E 或 GCS_WGS_1984
+	EPSG       int    // 能识别时的 EPSG 代码，否则为 0
+	Geographic bool   // 是否为地理坐标系 (经纬度)，投影坐标系为 false
+	WKT        string // 原始 WKT
+}
+
+var (
+	wktNamePattern      = regexp.MustCompile(`^\s*(PROJCS|GEOGCS|PROJCRS|GEOGCRS|GEODCRS)\s*\[\s*"([^"]*)"`)
+	wktAuthorityPattern = regexp.MustCompile(`AUTHORITY\s*\[\s*"EPSG"\s*,\s*"?(\d+)"?\s*\]\s*\]\s*$`)
+	wktIDPattern        = regexp.MustCompile(`ID\s*\[\s*"EPSG"\s*,\s*(\d+)\s*\]\s*\]\s*$`)
+	gkNamePattern       = regexp.MustCompile(`(?i)^CGCS2000_(3|6)_Degree_GK_(?:CM_(\d+)E|Zone_(\d+))$`)
+)
+
+// wellKnownCRS ESRI 风格 .prj 通常不带 AUTHORITY，这里按名称识别常用坐标系
+var wellKnownCRS = map[string]int{
+	"GCS_WGS_1984": 4326,
+	"WGS 84":       4326,
+	"WGS_1984":     4326,
+	"GCS_China_Geodetic_Coordinate_System_2000": 4490,
+	"China Geodetic Coordinate System 2000":     4490,
+	"CGCS2000":                                  4490,
+	"WGS_1984_Web_Mercator_Auxiliary_Sphere":    3857,
+	"WGS 84 / Pseudo-Mercator":                  3857,
+}
+
+// ParsePRJ 解析 .prj 中的 WKT，识别坐标系名称和 EPSG 代码
+func ParsePRJ(wkt string) CRSInfo {
+	wkt = strings.TrimSpace(strings.TrimPrefix(wkt, "\uFEFF"))
+	info := CRSInfo{WKT: wkt}
+	if m := wktNamePattern.FindStringSubmatch(wkt); m != nil {
+		info.Name = m[2]
+		info.Geographic = strings.HasPrefix(m[1], "GEOG") || m[1] == "GEODCRS"
+	}
+	if m := wktAuthorityPattern.FindStringSubmatch(wkt); m != nil {
+		info.EPSG = atoi(m[1])
+	} else if m := wktIDPattern.FindStringSubmatch(wkt); m != nil {
+		info.EPSG = atoi(m[1])
+	} else if code, ok := wellKnownCRS[info.Name]; ok {
+		info.EPSG = code
+	} else {
+		info.EPSG = gaussKrugerNameEPSG(info.Name)
+	}
+	return info
+}
+
+// gaussKrugerNameEPSG 识别 ESRI 风格的 CGCS2000 高斯-克吕格坐标系名称，例如 CGCS2000_3_Degree_GK_CM_117E
+func gaussKrugerNameEPSG(name string) int {
+	m := gkNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return 0
+	}
+	width := atoi(m[1])
+	if m[2] != "" {
+		return GaussKrugerEPSG(width, atoi(m[2]))
+	}
+	// 带号形式，X 坐标带带号前缀
+	zone := atoi(m[3])
+	switch {
+	case width == 3 && zone >= 25 && zone <= 45:
+		return 4513 + zone - 25
+	case width == 6 && zone >= 13 && zone <= 23:
+		return 4491 + zone - 13
+	}
+	return 0
+}
+
+func atoi(s string) int {
+	n := 0
+	for _, c := range s {
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
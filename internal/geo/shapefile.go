@@ -0,0 +1,201 @@
+package geo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// shpFileCode .shp/.shx 文件头中的固定标识
+const shpFileCode = 9994
+
+// shapeTypeNames shp 几何类型编号到名称的映射 (ESRI Shapefile Technical Description)
+var shapeTypeNames = map[int32]string{
+	0:  "Null",
+	1:  "Point",
+	3:  "PolyLine",
+	5:  "Polygon",
+	8:  "MultiPoint",
+	11: "PointZ",
+	13: "PolyLineZ",
+	15: "PolygonZ",
+	18: "MultiPointZ",
+	21: "PointM",
+	23: "PolyLineM",
+	25: "PolygonM",
+	28: "MultiPointM",
+	31: "MultiPatch",
+}
+
+// ShapeTypeName 返回几何类型的名称
+func ShapeTypeName(t int32) string {
+	if name, ok := shapeTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", t)
+}
+
+// ShpHeader .shp 文件头 (前 100 字节) 中的信息
+type ShpHeader struct {
+	FileLength int64 // 文件总字节数
+	ShapeType  int32 // 几何类型编号
+	MinX       float64
+	MinY       float64
+	MaxX       float64
+	MaxY       float64
+}
+
+// ReadShpHeader 解析 .shp 或 .shx 的 100 字节文件头
+func ReadShpHeader(r io.Reader) (ShpHeader, error) {
+	var buf [100]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return ShpHeader{}, fmt.Errorf("读取 shp 文件头失败: %w", err)
+	}
+	// 文件标识和长度为大端序，其余字段为小端序
+	if code := int32(binary.BigEndian.Uint32(buf[0:4])); code != shpFileCode {
+		return ShpHeader{}, fmt.Errorf("不是有效的 shp 文件 (文件标识为 %d)", code)
+	}
+	h := ShpHeader{
+		FileLength: int64(binary.BigEndian.Uint32(buf[24:28])) * 2, // 以 16 位字为单位
+		ShapeType:  int32(binary.LittleEndian.Uint32(buf[32:36])),
+		MinX:       readFloat64(buf[36:44]),
+		MinY:       readFloat64(buf[44:52]),
+		MaxX:       readFloat64(buf[52:60]),
+		MaxY:       readFloat64(buf[60:68]),
+	}
+	if _, ok := shapeTypeNames[h.ShapeType]; !ok {
+		return ShpHeader{}, fmt.Errorf("不支持的 shp 几何类型: %d", h.ShapeType)
+	}
+	return h, nil
+}
+
+// ShxRecordCount 根据 .shx 文件头计算要素数量，每条索引记录固定 8 字节
+func ShxRecordCount(h ShpHeader) int {
+	if h.FileLength < 100 {
+		return 0
+	}
+	return int((h.FileLength - 100) / 8)
+}
+
+// CountShpRecords 在没有 .shx 时逐条跳过 .shp 记录来统计要素数量，r 需位于文件头之后
+func CountShpRecords(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	count := 0
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return count, nil
+			}
+			return count, fmt.Errorf("读取第 %d 条 shp 记录失败: %w", count+1, err)
+		}
+		contentLength := int64(binary.BigEndian.Uint32(header[4:8])) * 2
+		if _, err := io.CopyN(io.Discard, br, contentLength); err != nil {
+			return count, fmt.Errorf("第 %d 条 shp 记录不完整: %w", count+1, err)
+		}
+		count++
+	}
+}
+
+// DBFField .dbf 中的一个属性字段
+type DBFField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`     // string / number / float / bool / date / memo / 其他原始类型字符
+	Length   int    `json:"length"`   // 字段宽度
+	Decimals int    `json:"decimals"` // 小数位数
+}
+
+// DBFHeader .dbf 文件头信息
+type DBFHeader struct {
+	RecordCount  int
+	HeaderLength int
+	RecordLength int
+	LanguageID   byte // 语言驱动 ID，用于推断编码 (0x4D 表示 GBK)
+	Fields       []DBFField
+	consumed     int // 解析文件头时已读取的字节数
+}
+
+// ReadDBFHeader 解析 .dbf 文件头和字段描述，字段名按 decode 解码
+func ReadDBFHeader(r io.Reader, decode Decoder) (DBFHeader, error) {
+	var buf [32]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return DBFHeader{}, fmt.Errorf("读取 dbf 文件头失败: %w", err)
+	}
+	h := DBFHeader{
+		RecordCount:  int(binary.LittleEndian.Uint32(buf[4:8])),
+		HeaderLength: int(binary.LittleEndian.Uint16(buf[8:10])),
+		RecordLength: int(binary.LittleEndian.Uint16(buf[10:12])),
+		LanguageID:   buf[29],
+	}
+	if h.HeaderLength < 33 {
+		return DBFHeader{}, fmt.Errorf("dbf 文件头长度无效: %d", h.HeaderLength)
+	}
+	if decode == nil {
+		decode = DecoderFor("", h.LanguageID)
+	}
+
+	// 字段描述从第 32 字节开始，每个 32 字节，以 0x0D 结束
+	h.consumed = 32
+	for h.consumed < h.HeaderLength {
+		var desc [32]byte
+		if _, err := io.ReadFull(r, desc[:1]); err != nil {
+			return DBFHeader{}, fmt.Errorf("读取 dbf 字段描述失败: %w", err)
+		}
+		h.consumed++
+		if desc[0] == 0x0D {
+			break
+		}
+		if _, err := io.ReadFull(r, desc[1:]); err != nil {
+			return DBFHeader{}, fmt.Errorf("读取 dbf 字段描述失败: %w", err)
+		}
+		h.consumed += 31
+		name := desc[:11]
+		if i := indexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		h.Fields = append(h.Fields, DBFField{
+			Name:     strings.TrimSpace(decode(name)),
+			Type:     dbfTypeName(desc[11]),
+			Length:   int(desc[16]),
+			Decimals: int(desc[17]),
+		})
+	}
+	return h, nil
+}
+
+// dbfTypeName 把 dbf 字段类型字符转换为易读的名称
+func dbfTypeName(t byte) string {
+	switch t {
+	case 'C':
+		return "string"
+	case 'N':
+		return "number"
+	case 'F':
+		return "float"
+	case 'L':
+		return "bool"
+	case 'D':
+		return "date"
+	case 'M':
+		return "memo"
+	default:
+		return string(t)
+	}
+}
+
+func readFloat64(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func indexByte(b []byte, c byte) int {
+	for i, v := range b {
+		if v == c {
+			return i
+		}
+	}
+	return -1
+}
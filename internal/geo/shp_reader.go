@@ -0,0 +1,203 @@
+package geo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Point 一个坐标点，HasZ 表示是否带高程
+type Point struct {
+	X, Y, Z float64
+	HasZ    bool
+}
+
+// Shape .shp 中的一条几何记录
+type Shape struct {
+	Type  int32
+	BBox  [4]float64 // minX, minY, maxX, maxY
+	Parts [][]Point  // 点类型为单个部分；线、面按 part 拆分
+}
+
+// IsNull 判断是否为空几何
+func (s *Shape) IsNull() bool {
+	return s == nil || s.Type == 0 || len(s.Parts) == 0
+}
+
+// maxShpRecordLength 单条记录内容的长度上限，记录头中的长度超过它时视为文件损坏，避免按伪造的长度分配内存
+const maxShpRecordLength = 64 << 20
+
+// ShpReader 顺序读取 .shp 中的几何记录，不会把整个文件读入内存
+type ShpReader struct {
+	r      *bufio.Reader
+	offset int64 // 已读取的字节数，用于按文件头中的文件长度检查记录长度
+	Header ShpHeader
+}
+
+// NewShpReader 读取文件头并返回记录读取器
+func NewShpReader(r io.Reader) (*ShpReader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	header, err := ReadShpHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	return &ShpReader{r: br, offset: 100, Header: header}, nil
+}
+
+// Next 读取下一条记录，读完时返回 io.EOF
+func (s *ShpReader) Next() (*Shape, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("shp 记录头不完整: %w", err)
+		}
+		return nil, err
+	}
+	s.offset += 8
+	contentLength := int64(binary.BigEndian.Uint32(header[4:8])) * 2
+	if contentLength > maxShpRecordLength || contentLength > s.Header.FileLength-s.offset {
+		return nil, fmt.Errorf("shp 记录长度无效: %d 字节", contentLength)
+	}
+	content := make([]byte, contentLength)
+	if _, err := io.ReadFull(s.r, content); err != nil {
+		return nil, fmt.Errorf("shp 记录内容不完整: %w", err)
+	}
+	s.offset += contentLength
+	return parseShape(content)
+}
+
+// parseShape 解析一条记录的内容
+func parseShape(b []byte) (*Shape, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("shp 记录过短")
+	}
+	shape := &Shape{Type: int32(binary.LittleEndian.Uint32(b[0:4]))}
+	rd := &byteReader{b: b, off: 4}
+
+	switch shape.Type {
+	case 0: // Null
+		return shape, nil
+
+	case 1, 11, 21: // Point / PointZ / PointM
+		p := Point{X: rd.float(), Y: rd.float()}
+		if shape.Type == 11 {
+			p.Z, p.HasZ = rd.float(), true
+		}
+		shape.BBox = [4]float64{p.X, p.Y, p.X, p.Y}
+		shape.Parts = [][]Point{{p}}
+
+	case 8, 18, 28: // MultiPoint / MultiPointZ / MultiPointM
+		shape.BBox = rd.bbox()
+		n := rd.int()
+		if n < 0 || n > len(b) {
+			return nil, fmt.Errorf("shp 记录的点数量无效")
+		}
+		points := rd.points(n)
+		if shape.Type == 18 {
+			rd.skip(16) // Z 范围
+			for i := range points {
+				points[i].Z, points[i].HasZ = rd.float(), true
+			}
+		}
+		for _, p := range points {
+			shape.Parts = append(shape.Parts, []Point{p})
+		}
+
+	case 3, 5, 13, 15, 23, 25: // PolyLine / Polygon 及其 Z、M 变体
+		shape.BBox = rd.bbox()
+		numParts, numPoints := rd.int(), rd.int()
+		if numParts < 0 || numPoints < 0 || numParts > len(b) || numPoints > len(b) {
+			return nil, fmt.Errorf("shp 记录的部件或点数量无效")
+		}
+		starts := make([]int, numParts)
+		for i := range starts {
+			starts[i] = rd.int()
+		}
+		points := rd.points(numPoints)
+		if shape.Type == 13 || shape.Type == 15 {
+			rd.skip(16)
+			for i := range points {
+				points[i].Z, points[i].HasZ = rd.float(), true
+			}
+		}
+		for i, start := range starts {
+			end := numPoints
+			if i+1 < numParts {
+				end = starts[i+1]
+			}
+			if start < 0 || start > end || end > numPoints {
+				return nil, fmt.Errorf("shp 记录的部件索引无效")
+			}
+			shape.Parts = append(shape.Parts, points[start:end])
+		}
+
+	default:
+		// MultiPatch 等类型暂不转换几何，按空几何处理
+		shape.Type = 0
+		return shape, nil
+	}
+
+	if rd.err != nil {
+		return nil, rd.err
+	}
+	return shape, nil
+}
+
+// byteReader 按小端序从记录内容中读取数值，越界时记录错误而不是 panic
+type byteReader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (r *byteReader) need(n int) bool {
+	if r.err != nil {
+		return false
+	}
+	if r.off+n > len(r.b) {
+		r.err = fmt.Errorf("shp 记录长度与内容不符")
+		return false
+	}
+	return true
+}
+
+func (r *byteReader) float() float64 {
+	if !r.need(8) {
+		return 0
+	}
+	v := readFloat64(r.b[r.off:])
+	r.off += 8
+	return v
+}
+
+func (r *byteReader) int() int {
+	if !r.need(4) {
+		return 0
+	}
+	v := int(int32(binary.LittleEndian.Uint32(r.b[r.off:])))
+	r.off += 4
+	return v
+}
+
+func (r *byteReader) skip(n int) {
+	if r.need(n) {
+		r.off += n
+	}
+}
+
+func (r *byteReader) bbox() [4]float64 {
+	return [4]float64{r.float(), r.float(), r.float(), r.float()}
+}
+
+func (r *byteReader) points(n int) []Point {
+	if n < 0 || !r.need(n*16) {
+		return nil
+	}
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{X: r.float(), Y: r.float()}
+	}
+	return points
+}
@@ -0,0 +1,130 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// shpRecord 一条记录的内容 (不含 8 字节记录头)
+type shpRecord []byte
+
+// buildShp 拼出完整的 .shp：100 字节文件头加上各条记录，fileLength 为 0 时按实际长度填写
+func buildShp(shapeType int32, fileLength int, records ...shpRecord) []byte {
+	var body bytes.Buffer
+	for i, rec := range records {
+		binary.Write(&body, binary.BigEndian, int32(i+1))
+		binary.Write(&body, binary.BigEndian, int32(len(rec)/2))
+		body.Write(rec)
+	}
+	if fileLength == 0 {
+		fileLength = 100 + body.Len()
+	}
+	header := make([]byte, 100)
+	binary.BigEndian.PutUint32(header[0:4], shpFileCode)
+	binary.BigEndian.PutUint32(header[24:28], uint32(fileLength/2))
+	binary.LittleEndian.PutUint32(header[28:32], 1000)
+	binary.LittleEndian.PutUint32(header[32:36], uint32(shapeType))
+	return append(header, body.Bytes()...)
+}
+
+// le 按小端序依次写入 int32 和 float64
+func le(values ...interface{}) shpRecord {
+	var buf bytes.Buffer
+	for _, v := range values {
+		switch x := v.(type) {
+		case int:
+			binary.Write(&buf, binary.LittleEndian, int32(x))
+		case float64:
+			binary.Write(&buf, binary.LittleEndian, math.Float64bits(x))
+		}
+	}
+	return buf.Bytes()
+}
+
+func readAllShapes(data []byte) ([]*Shape, error) {
+	r, err := NewShpReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var shapes []*Shape
+	for {
+		shape, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return shapes, nil
+		}
+		if err != nil {
+			return shapes, err
+		}
+		shapes = append(shapes, shape)
+	}
+}
+
+func TestShpReaderValid(t *testing.T) {
+	points := buildShp(1, 0,
+		le(1, 116.1, 39.9),
+		le(0), // 空几何
+		le(1, 116.2, 40.0),
+	)
+	shapes, err := readAllShapes(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 3 || shapes[0].Parts[0][0] != (Point{X: 116.1, Y: 39.9}) || !shapes[1].IsNull() {
+		t.Fatalf("点记录解析结果不正确: %+v", shapes)
+	}
+
+	// 两个部件的折线：第 0 个部件 2 个点，第 1 个部件 1 个点
+	line := buildShp(3, 0, le(3, 0.0, 0.0, 3.0, 3.0, 2, 3, 0, 2, 0.0, 0.0, 1.0, 1.0, 3.0, 3.0))
+	shapes, err = readAllShapes(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 1 || len(shapes[0].Parts) != 2 || len(shapes[0].Parts[0]) != 2 || len(shapes[0].Parts[1]) != 1 {
+		t.Fatalf("折线记录解析结果不正确: %+v", shapes)
+	}
+}
+
+func TestShpReaderMalformed(t *testing.T) {
+	valid := buildShp(1, 0, le(1, 116.1, 39.9))
+	hugeLength := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(hugeLength[104:108], math.MaxUint32) // 记录头声称约 8 GiB
+
+	cases := []struct {
+		name string
+		data []byte
+		want string // 错误信息中应包含的内容
+	}{
+		{"文件头不完整", valid[:60], "文件头"},
+		{"文件标识错误", append([]byte{0, 0, 0, 1}, valid[4:]...), "不是有效的 shp"},
+		{"记录头不完整", valid[:104], "记录头不完整"},
+		{"记录内容不完整", valid[:120], "记录内容不完整"},
+		{"记录长度超过上限", hugeLength, "记录长度无效"},
+		{"记录长度超过文件长度", buildShp(1, 120, le(1, 116.1, 39.9)), "记录长度无效"},
+		{"记录过短", buildShp(1, 0, le()), "记录过短"},
+		{"点记录缺少坐标", buildShp(1, 0, le(1, 116.1)), "长度与内容不符"},
+		{"多点数量为负", buildShp(8, 0, le(8, 0.0, 0.0, 1.0, 1.0, -1)), "点数量无效"},
+		{"多点数量超过记录长度", buildShp(8, 0, le(8, 0.0, 0.0, 1.0, 1.0, 1<<30)), "点数量无效"},
+		{"多点缺少坐标", buildShp(8, 0, le(8, 0.0, 0.0, 1.0, 1.0, 2, 0.0, 0.0)), "长度与内容不符"},
+		{"折线部件数为负", buildShp(3, 0, le(3, 0.0, 0.0, 1.0, 1.0, -1, 1)), "部件或点数量无效"},
+		{"折线部件索引越界", buildShp(3, 0, le(3, 0.0, 0.0, 1.0, 1.0, 1, 1, 5, 0.0, 0.0)), "部件索引无效"},
+		{"折线部件索引倒序", buildShp(3, 0, le(3, 0.0, 0.0, 1.0, 1.0, 2, 2, 1, 0, 0.0, 0.0, 1.0, 1.0)), "部件索引无效"},
+	}
+	for _, c := range cases {
+		_, err := readAllShapes(c.data)
+		if err == nil {
+			t.Errorf("%s: 应返回错误", c.name)
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			t.Errorf("%s: 损坏的文件不应按正常结束处理: %v", c.name, err)
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: 错误为 %q, 期望包含 %q", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package geo
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RasterIndexXML 影像瓦片的 XML 索引，支持 gdal2tiles 生成的 TMS TileMap
+// 以及 ContextCapture 导出的 ModelMetadata，其余格式只校验 XML 是否合法
+type RasterIndexXML struct {
+	Root    string         // 根元素名称
+	TileMap *TileMap       // 根元素为 TileMap 时有值
+	Model   *ModelMetadata // 根元素为 ModelMetadata 时有值
+}
+
+// TileMap TMS 规范中的 tilemapresource.xml
+type TileMap struct {
+	Version     string `xml:"version,attr"`
+	Title       string `xml:"Title"`
+	SRS         string `xml:"SRS"`
+	BoundingBox struct {
+		MinX float64 `xml:"minx,attr"`
+		MinY float64 `xml:"miny,attr"`
+		MaxX float64 `xml:"maxx,attr"`
+		MaxY float64 `xml:"maxy,attr"`
+	} `xml:"BoundingBox"`
+	TileFormat struct {
+		Width     int    `xml:"width,attr"`
+		Height    int    `xml:"height,attr"`
+		MimeType  string `xml:"mime-type,attr"`
+		Extension string `xml:"extension,attr"`
+	} `xml:"TileFormat"`
+	TileSets struct {
+		Profile  string    `xml:"profile,attr"`
+		TileSets []TileSet `xml:"TileSet"`
+	} `xml:"TileSets"`
+}
+
+// TileSet TileMap 中的一个级别，瓦片位于 <href>/<x>/<y>.<extension>
+type TileSet struct {
+	Href          string  `xml:"href,attr"`
+	UnitsPerPixel float64 `xml:"units-per-pixel,attr"`
+	Order         int     `xml:"order,attr"`
+}
+
+// ModelMetadata ContextCapture 导出的 metadata.xml
+type ModelMetadata struct {
+	Version   string `xml:"version,attr"`
+	SRS       string `xml:"SRS"`
+	SRSOrigin string `xml:"SRSOrigin"`
+}
+
+// ParseRasterIndexXML 解析影像索引 XML，并对能识别的格式做结构校验
+func ParseRasterIndexXML(r io.Reader) (*RasterIndexXML, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("索引文件不是有效的 XML: %w", err)
+	}
+	index := &RasterIndexXML{Root: root.XMLName.Local}
+
+	switch root.XMLName.Local {
+	case "TileMap":
+		var tm TileMap
+		if err := xml.Unmarshal(data, &tm); err != nil {
+			return nil, fmt.Errorf("TileMap 解析失败: %w", err)
+		}
+		if err := tm.validate(); err != nil {
+			return nil, err
+		}
+		index.TileMap = &tm
+	case "ModelMetadata":
+		var mm ModelMetadata
+		if err := xml.Unmarshal(data, &mm); err != nil {
+			return nil, fmt.Errorf("ModelMetadata 解析失败: %w", err)
+		}
+		mm.SRS = strings.TrimSpace(mm.SRS)
+		mm.SRSOrigin = strings.TrimSpace(mm.SRSOrigin)
+		index.Model = &mm
+	}
+	return index, nil
+}
+
+func (tm *TileMap) validate() error {
+	if len(tm.TileSets.TileSets) == 0 {
+		return errors.New("TileMap 中没有 TileSet")
+	}
+	if tm.TileFormat.Extension == "" {
+		return errors.New("TileMap 缺少 TileFormat 的 extension")
+	}
+	for _, ts := range tm.TileSets.TileSets {
+		if ts.Href == "" {
+			return fmt.Errorf("第 %d 级 TileSet 缺少 href", ts.Order)
+		}
+	}
+	bb := tm.BoundingBox
+	if bb.MinX > bb.MaxX || bb.MinY > bb.MaxY {
+		return errors.New("TileMap 的 BoundingBox 最小值大于最大值")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Tileset 3D Tiles 的 tileset.json，只保留校验和统计需要的字段
+type Tileset struct {
+	Asset struct {
+		Version        string `json:"version"`
+		TilesetVersion string `json:"tilesetVersion"`
+	} `json:"asset"`
+	GeometricError *float64 `json:"geometricError"`
+	Root           *Tile    `json:"root"`
+}
+
+// Tile tileset 中的一个瓦片节点
+type Tile struct {
+	BoundingVolume BoundingVolume `json:"boundingVolume"`
+	GeometricError *float64       `json:"geometricError"`
+	Content        *TileContent   `json:"content"`  // 1.0
+	Contents       []TileContent  `json:"contents"` // 1.1 允许一个瓦片有多个内容
+	Children       []*Tile        `json:"children"`
+}
+
+// TileContent 瓦片内容的引用，早期版本使用 url 字段
+type TileContent struct {
+	URI string `json:"uri"`
+	URL string `json:"url"`
+}
+
+// Ref 返回内容引用的地址
+func (c TileContent) Ref() string {
+	if c.URI != "" {
+		return c.URI
+	}
+	return c.URL
+}
+
+// ContentRefs 返回瓦片引用的所有内容地址
+func (t *Tile) ContentRefs() []string {
+	var refs []string
+	if t.Content != nil && t.Content.Ref() != "" {
+		refs = append(refs, t.Content.Ref())
+	}
+	for _, c := range t.Contents {
+		if c.Ref() != "" {
+			refs = append(refs, c.Ref())
+		}
+	}
+	return refs
+}
+
+// BoundingVolume 包围体，region 为弧度制的 [west, south, east, north, minHeight, maxHeight]
+type BoundingVolume struct {
+	Region []float64 `json:"region"`
+	Box    []float64 `json:"box"`
+	Sphere []float64 `json:"sphere"`
+}
+
+func (b BoundingVolume) validate() error {
+	switch {
+	case b.Region != nil:
+		if len(b.Region) != 6 {
+			return errors.New("region 应包含 6 个数值")
+		}
+	case b.Box != nil:
+		if len(b.Box) != 12 {
+			return errors.New("box 应包含 12 个数值")
+		}
+	case b.Sphere != nil:
+		if len(b.Sphere) != 4 {
+			return errors.New("sphere 应包含 4 个数值")
+		}
+	default:
+		return errors.New("缺少 region、box 或 sphere")
+	}
+	return nil
+}
+
+// RegionDegrees 把 region 换算为经纬度范围，不是 region 类型时 ok 为 false
+func (b BoundingVolume) RegionDegrees() (minLon, minLat, maxLon, maxLat float64, ok bool) {
+	if len(b.Region) != 6 {
+		return 0, 0, 0, 0, false
+	}
+	deg := 180 / math.Pi
+	return b.Region[0] * deg, b.Region[1] * deg, b.Region[2] * deg, b.Region[3] * deg, true
+}
+
+// ParseTileset 解析并校验 tileset.json 的结构：asset.version、geometricError 和每个瓦片的包围体
+func ParseTileset(r io.Reader) (*Tileset, error) {
+	var ts Tileset
+	if err := json.NewDecoder(r).Decode(&ts); err != nil {
+		return nil, fmt.Errorf("tileset 不是有效的 JSON: %w", err)
+	}
+	if ts.Asset.Version == "" {
+		return nil, errors.New("tileset 缺少 asset.version")
+	}
+	if ts.GeometricError == nil {
+		return nil, errors.New("tileset 缺少 geometricError")
+	}
+	if ts.Root == nil {
+		return nil, errors.New("tileset 缺少 root")
+	}
+	err := ts.Walk(func(tile *Tile, path string, depth int) error {
+		if err := tile.BoundingVolume.validate(); err != nil {
+			return fmt.Errorf("%s.boundingVolume %v", path, err)
+		}
+		if tile.GeometricError == nil {
+			return fmt.Errorf("%s 缺少 geometricError", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ts, nil
+}
+
+// Walk 深度优先遍历所有瓦片，path 形如 root.children[0]，depth 从 0 开始
+func (ts *Tileset) Walk(fn func(tile *Tile, path string, depth int) error) error {
+	return walkTile(ts.Root, "root", 0, fn)
+}
+
+func walkTile(tile *Tile, path string, depth int, fn func(*Tile, string, int) error) error {
+	if tile == nil {
+		return fmt.Errorf("%s 为空", path)
+	}
+	if err := fn(tile, path, depth); err != nil {
+		return err
+	}
+	for i, child := range tile.Children {
+		if err := walkTile(child, fmt.Sprintf("%s.children[%d]", path, i), depth+1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
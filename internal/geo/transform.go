@@ -0,0 +1,163 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 坐标转换以 WGS84 经纬度为中转：源坐标系 -> WGS84 -> 目标坐标系
+// CGCS2000 与 WGS84 的椭球和基准差异在厘米级，这里不做区分
+
+const (
+	CRSWGS84       = "EPSG:4326"
+	CRSCGCS2000    = "EPSG:4490"
+	CRSWebMercator = "EPSG:3857"
+	CRSGCJ02       = "GCJ-02" // 国测局加密坐标 (火星坐标)，没有 EPSG 代码
+)
+
+// CGCS2000 椭球参数
+const (
+	cgcs2000A = 6378137.0
+	cgcs2000F = 1 / 298.257222101
+)
+
+// projection 一个可以与 WGS84 经纬度互相转换的坐标系
+type projection interface {
+	toLonLat(x, y float64) (lon, lat float64, err error)
+	fromLonLat(lon, lat float64) (x, y float64, err error)
+}
+
+// crsAliases 常见的坐标系写法
+var crsAliases = map[string]string{
+	"WGS84":        CRSWGS84,
+	"WGS 84":       CRSWGS84,
+	"EPSG:4979":    CRSWGS84,
+	"CGCS2000":     CRSCGCS2000,
+	"EPSG:900913":  CRSWebMercator,
+	"EPSG:3785":    CRSWebMercator,
+	"WEBMERCATOR":  CRSWebMercator,
+	"WEB MERCATOR": CRSWebMercator,
+	"GCJ02":        CRSGCJ02,
+	"GCJ-02":       CRSGCJ02,
+}
+
+// NormalizeCRS 把坐标系写法统一为 EPSG:xxxx 或 GCJ-02，不支持的坐标系返回错误
+// 支持 WGS84、CGCS2000 经纬度、Web Mercator、GCJ-02 以及 CGCS2000 高斯-克吕格投影 (EPSG:4491-4554)
+func NormalizeCRS(code string) (string, error) {
+	_, canonical, err := lookupProjection(code)
+	return canonical, err
+}
+
+// Transform 把坐标 (x, y) 从 from 坐标系转换到 to 坐标系，经纬度坐标 x 为经度、y 为纬度
+func Transform(from, to string, x, y float64) (float64, float64, error) {
+	src, srcCode, err := lookupProjection(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	dst, dstCode, err := lookupProjection(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if srcCode == dstCode {
+		return x, y, nil
+	}
+	lon, lat, err := src.toLonLat(x, y)
+	if err != nil {
+		return 0, 0, err
+	}
+	return dst.fromLonLat(lon, lat)
+}
+
+func lookupProjection(code string) (projection, string, error) {
+	key := strings.ToUpper(strings.TrimSpace(code))
+	if _, err := strconv.Atoi(key); err == nil {
+		key = "EPSG:" + key
+	}
+	if alias, ok := crsAliases[key]; ok {
+		key = alias
+	}
+
+	switch key {
+	case CRSWGS84, CRSCGCS2000:
+		return lonLat{}, key, nil
+	case CRSWebMercator:
+		return webMercator{}, key, nil
+	case CRSGCJ02:
+		return gcj02{}, key, nil
+	}
+	if strings.HasPrefix(key, "EPSG:") {
+		if p, ok := gaussKrugerByEPSG(atoi(strings.TrimPrefix(key, "EPSG:"))); ok {
+			return p, key, nil
+		}
+	}
+	return nil, "", fmt.Errorf("不支持的坐标系: %s", code)
+}
+
+// --- 经纬度 ---
+
+type lonLat struct{}
+
+func (lonLat) toLonLat(x, y float64) (float64, float64, error) {
+	if err := checkLonLat(x, y); err != nil {
+		return 0, 0, err
+	}
+	return x, y, nil
+}
+
+func (lonLat) fromLonLat(lon, lat float64) (float64, float64, error) {
+	return lon, lat, nil
+}
+
+func checkLonLat(lon, lat float64) error {
+	if math.IsNaN(lon) || math.IsNaN(lat) || lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return fmt.Errorf("经纬度超出范围: lon=%v, lat=%v", lon, lat)
+	}
+	return nil
+}
+
+// --- Web Mercator (EPSG:3857) ---
+
+const (
+	mercatorRadius = 6378137.0
+	mercatorMaxLat = 85.05112877980659
+)
+
+type webMercator struct{}
+
+func (webMercator) toLonLat(x, y float64) (float64, float64, error) {
+	lon := x / mercatorRadius * 180 / math.Pi
+	lat := (2*math.Atan(math.Exp(y/mercatorRadius)) - math.Pi/2) * 180 / math.Pi
+	return lon, lat, checkLonLat(lon, lat)
+}
+
+func (webMercator) fromLonLat(lon, lat float64) (float64, float64, error) {
+	if err := checkLonLat(lon, lat); err != nil {
+		return 0, 0, err
+	}
+	lat = math.Max(-mercatorMaxLat, math.Min(mercatorMaxLat, lat))
+	x := mercatorRadius * lon * math.Pi / 180
+	y := mercatorRadius * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+	return x, y, nil
+}
+
+// CRSDefinition 可以互相转换的坐标系
+type CRSDefinition struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// SupportedCRS 列出 Transform 支持的所有坐标系
+func SupportedCRS() []CRSDefinition {
+	list := []CRSDefinition{
+		{Code: CRSWGS84, Name: "WGS 84"},
+		{Code: CRSCGCS2000, Name: "China Geodetic Coordinate System 2000"},
+		{Code: CRSWebMercator, Name: "WGS 84 / Pseudo-Mercator"},
+		{Code: CRSGCJ02, Name: "GCJ-02"},
+	}
+	for code := 4491; code <= 4554; code++ {
+		list = append(list, CRSDefinition{Code: fmt.Sprintf("EPSG:%d", code), Name: gaussKrugerName(code)})
+	}
+	return list
+}
@@ -0,0 +1,136 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// 高斯-克吕格的参考值按 Snyder《Map Projections: A Working Manual》的级数公式独立计算 (CGCS2000 椭球，中央经线 117E)，
+// 中央经线 ±1.5° 内与 Krüger 级数的差异在毫米级；(117, 40) 的北坐标与 UTM 40N 的子午线弧长 4429529.03m 一致
+var gaussKrugerCases = []struct {
+	lon, lat float64
+	x, y     float64 // 不带带号 (EPSG:4548) 的坐标
+}{
+	{117, 40, 500000.0000, 4429529.0304},
+	{118.2, 39.5, 603217.5603, 4374701.7008},
+	{116.1, 31.2, 414224.4317, 3453497.5626},
+	{117.9, 24.5, 591221.7838, 2710966.7032},
+	{115.6, 45.3, 390192.0853, 5019238.4635},
+}
+
+func TestTransformGaussKruger(t *testing.T) {
+	for _, c := range gaussKrugerCases {
+		// EPSG:4548 中央经线 117E，X 不带带号
+		x, y, err := Transform(CRSCGCS2000, "EPSG:4548", c.lon, c.lat)
+		if err != nil {
+			t.Fatalf("(%v, %v) -> EPSG:4548: %v", c.lon, c.lat, err)
+		}
+		if math.Abs(x-c.x) > 0.01 || math.Abs(y-c.y) > 0.01 {
+			t.Errorf("(%v, %v) -> EPSG:4548 = (%.4f, %.4f), 期望 (%.4f, %.4f)", c.lon, c.lat, x, y, c.x, c.y)
+		}
+
+		// EPSG:4527 同为 3 度带第 39 带，X 带有带号前缀
+		x, y, err = Transform(CRSCGCS2000, "EPSG:4527", c.lon, c.lat)
+		if err != nil {
+			t.Fatalf("(%v, %v) -> EPSG:4527: %v", c.lon, c.lat, err)
+		}
+		if math.Abs(x-(c.x+39e6)) > 0.01 || math.Abs(y-c.y) > 0.01 {
+			t.Errorf("(%v, %v) -> EPSG:4527 = (%.4f, %.4f), 期望 (%.4f, %.4f)", c.lon, c.lat, x, y, c.x+39e6, c.y)
+		}
+
+		// 反算回经纬度
+		lon, lat, err := Transform("EPSG:4527", CRSCGCS2000, c.x+39e6, c.y)
+		if err != nil {
+			t.Fatalf("EPSG:4527 -> (%v, %v): %v", c.lon, c.lat, err)
+		}
+		if math.Abs(lon-c.lon) > 1e-7 || math.Abs(lat-c.lat) > 1e-7 {
+			t.Errorf("EPSG:4527 (%.4f, %.4f) 反算为 (%.9f, %.9f), 期望 (%v, %v)", c.x+39e6, c.y, lon, lat, c.lon, c.lat)
+		}
+	}
+}
+
+func TestTransformGaussKrugerWrongZone(t *testing.T) {
+	// 带号前缀与坐标系不符 (第 38 带的坐标按第 39 带解析)
+	if _, _, err := Transform("EPSG:4527", CRSCGCS2000, 38500000, 4429529); err == nil {
+		t.Error("带号不符的坐标应返回错误")
+	}
+}
+
+func TestTransformWebMercator(t *testing.T) {
+	cases := []struct {
+		lon, lat float64
+		x, y     float64
+	}{
+		{0, 0, 0, 0},
+		{-180, 85.0511287798066, -20037508.342789, 20037508.342789},
+		{116.404, 39.915, 12958034.006300, 4853597.988300},
+	}
+	for _, c := range cases {
+		x, y, err := Transform(CRSWGS84, CRSWebMercator, c.lon, c.lat)
+		if err != nil {
+			t.Fatalf("(%v, %v) -> EPSG:3857: %v", c.lon, c.lat, err)
+		}
+		if math.Abs(x-c.x) > 1e-3 || math.Abs(y-c.y) > 1e-3 {
+			t.Errorf("(%v, %v) -> EPSG:3857 = (%.6f, %.6f), 期望 (%.6f, %.6f)", c.lon, c.lat, x, y, c.x, c.y)
+		}
+		lon, lat, err := Transform(CRSWebMercator, CRSWGS84, c.x, c.y)
+		if err != nil {
+			t.Fatalf("EPSG:3857 -> (%v, %v): %v", c.lon, c.lat, err)
+		}
+		if math.Abs(lon-c.lon) > 1e-9 || math.Abs(lat-c.lat) > 1e-9 {
+			t.Errorf("EPSG:3857 (%v, %v) 反算为 (%v, %v), 期望 (%v, %v)", c.x, c.y, lon, lat, c.lon, c.lat)
+		}
+	}
+}
+
+func TestTransformGCJ02(t *testing.T) {
+	cases := []struct {
+		lon, lat float64 // WGS84
+		gLon     float64 // GCJ-02
+		gLat     float64
+	}{
+		// 与常用的 coordtransform 实现 wgs84togcj02(116.404, 39.915) 的结果一致
+		{116.404, 39.915, 116.41024449916938, 39.91640428150164},
+		// 中国境外不偏移
+		{-122.4194, 37.7749, -122.4194, 37.7749},
+	}
+	for _, c := range cases {
+		lon, lat, err := Transform(CRSWGS84, CRSGCJ02, c.lon, c.lat)
+		if err != nil {
+			t.Fatalf("(%v, %v) -> GCJ-02: %v", c.lon, c.lat, err)
+		}
+		if math.Abs(lon-c.gLon) > 1e-9 || math.Abs(lat-c.gLat) > 1e-9 {
+			t.Errorf("(%v, %v) -> GCJ-02 = (%v, %v), 期望 (%v, %v)", c.lon, c.lat, lon, lat, c.gLon, c.gLat)
+		}
+		// 逆变换迭代求解，应回到原坐标
+		lon, lat, err = Transform(CRSGCJ02, CRSWGS84, c.gLon, c.gLat)
+		if err != nil {
+			t.Fatalf("GCJ-02 -> (%v, %v): %v", c.lon, c.lat, err)
+		}
+		if math.Abs(lon-c.lon) > 1e-8 || math.Abs(lat-c.lat) > 1e-8 {
+			t.Errorf("GCJ-02 (%v, %v) 反算为 (%v, %v), 期望 (%v, %v)", c.gLon, c.gLat, lon, lat, c.lon, c.lat)
+		}
+	}
+}
+
+func TestNormalizeCRS(t *testing.T) {
+	cases := map[string]string{
+		"4326":        CRSWGS84,
+		"wgs84":       CRSWGS84,
+		"CGCS2000":    CRSCGCS2000,
+		"EPSG:900913": CRSWebMercator,
+		"gcj02":       CRSGCJ02,
+		" epsg:4548 ": "EPSG:4548",
+	}
+	for in, want := range cases {
+		got, err := NormalizeCRS(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeCRS(%q) = %q, %v, 期望 %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"EPSG:4490x", "EPSG:4555", "EPSG:2000", ""} {
+		if _, err := NormalizeCRS(in); err == nil {
+			t.Errorf("NormalizeCRS(%q) 应返回错误", in)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package gltf
+
+import (
+	"fmt"
+	"math"
+)
+
+// mat4 列主序的 4x4 矩阵，与 glTF node.matrix 的存储方式相同
+type mat4 [16]float64
+
+var identity = mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+func (a mat4) mul(b mat4) mat4 {
+	var m mat4
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+r] * b[c*4+k]
+			}
+			m[c*4+r] = sum
+		}
+	}
+	return m
+}
+
+func (a mat4) apply(p [3]float64) [3]float64 {
+	return [3]float64{
+		a[0]*p[0] + a[4]*p[1] + a[8]*p[2] + a[12],
+		a[1]*p[0] + a[5]*p[1] + a[9]*p[2] + a[13],
+		a[2]*p[0] + a[6]*p[1] + a[10]*p[2] + a[14],
+	}
+}
+
+// localMatrix 节点的局部变换，matrix 优先，否则由 TRS 组合
+func localMatrix(n Node) mat4 {
+	if len(n.Matrix) == 16 {
+		var m mat4
+		copy(m[:], n.Matrix)
+		return m
+	}
+	t := [3]float64{0, 0, 0}
+	q := [4]float64{0, 0, 0, 1}
+	s := [3]float64{1, 1, 1}
+	if len(n.Translation) == 3 {
+		copy(t[:], n.Translation)
+	}
+	if len(n.Rotation) == 4 {
+		copy(q[:], n.Rotation)
+	}
+	if len(n.Scale) == 3 {
+		copy(s[:], n.Scale)
+	}
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return mat4{
+		(1 - 2*(y*y+z*z)) * s[0], 2 * (x*y + z*w) * s[0], 2 * (x*z - y*w) * s[0], 0,
+		2 * (x*y - z*w) * s[1], (1 - 2*(x*x+z*z)) * s[1], 2 * (y*z + x*w) * s[1], 0,
+		2 * (x*z + y*w) * s[2], 2 * (y*z - x*w) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+// sceneRoots 返回默认场景的根节点，没有场景时返回 nil
+func sceneRoots(doc *Document) []int {
+	if len(doc.Scenes) == 0 {
+		return nil
+	}
+	i := 0
+	if doc.Scene != nil && validIndex(*doc.Scene, len(doc.Scenes)) {
+		i = *doc.Scene
+	}
+	var roots []int
+	for _, n := range doc.Scenes[i].Nodes {
+		if validIndex(n, len(doc.Nodes)) {
+			roots = append(roots, n)
+		}
+	}
+	return roots
+}
+
+// computeBounds 遍历默认场景，用 POSITION 的 min/max 和节点变换计算包围盒
+// 没有场景时直接合并所有网格的局部包围盒；同时检查节点索引越界和循环引用
+func computeBounds(doc *Document, stats *Stats) error {
+	for i, scene := range doc.Scenes {
+		for _, n := range scene.Nodes {
+			if !validIndex(n, len(doc.Nodes)) {
+				return fmt.Errorf("scenes[%d].nodes 越界", i)
+			}
+		}
+	}
+	for i, n := range doc.Nodes {
+		if n.Mesh != nil && !validIndex(*n.Mesh, len(doc.Meshes)) {
+			return fmt.Errorf("nodes[%d].mesh 越界", i)
+		}
+		for _, c := range n.Children {
+			if !validIndex(c, len(doc.Nodes)) {
+				return fmt.Errorf("nodes[%d].children 越界", i)
+			}
+		}
+	}
+
+	b := &boundsBuilder{doc: doc, stats: stats, visiting: make([]bool, len(doc.Nodes))}
+	roots := sceneRoots(doc)
+	if roots == nil {
+		for i := range doc.Meshes {
+			b.addMesh(i, identity)
+		}
+		return nil
+	}
+	for _, n := range roots {
+		if err := b.visit(n, identity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type boundsBuilder struct {
+	doc      *Document
+	stats    *Stats
+	visiting []bool
+}
+
+func (b *boundsBuilder) visit(node int, parent mat4) error {
+	if b.visiting[node] {
+		return fmt.Errorf("nodes[%d] 存在循环引用", node)
+	}
+	b.visiting[node] = true
+	defer func() { b.visiting[node] = false }()
+
+	n := b.doc.Nodes[node]
+	world := parent.mul(localMatrix(n))
+	if n.Mesh != nil {
+		b.addMesh(*n.Mesh, world)
+	}
+	for _, c := range n.Children {
+		if err := b.visit(c, world); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *boundsBuilder) addMesh(mesh int, m mat4) {
+	for _, p := range b.doc.Meshes[mesh].Primitives {
+		idx, ok := p.Attributes["POSITION"]
+		if !ok {
+			continue
+		}
+		a := b.doc.Accessors[idx]
+		if len(a.Min) != 3 || len(a.Max) != 3 {
+			continue
+		}
+		// 变换局部包围盒的 8 个角点
+		for i := 0; i < 8; i++ {
+			corner := [3]float64{a.Min[0], a.Min[1], a.Min[2]}
+			if i&1 != 0 {
+				corner[0] = a.Max[0]
+			}
+			if i&2 != 0 {
+				corner[1] = a.Max[1]
+			}
+			if i&4 != 0 {
+				corner[2] = a.Max[2]
+			}
+			b.extend(m.apply(corner))
+		}
+	}
+}
+
+func (b *boundsBuilder) extend(p [3]float64) {
+	s := b.stats
+	if !s.HasBounds {
+		s.BoundsMin, s.BoundsMax, s.HasBounds = p, p, true
+		return
+	}
+	for i := 0; i < 3; i++ {
+		s.BoundsMin[i] = math.Min(s.BoundsMin[i], p[i])
+		s.BoundsMax[i] = math.Max(s.BoundsMax[i], p[i])
+	}
+}
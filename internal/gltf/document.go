@@ -0,0 +1,186 @@
+// Package gltf 解析 glTF 2.0 (.gltf / .glb) 模型，校验数据引用并统计网格信息
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// GLB 文件头和块类型
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+)
+
+// maxJSONSize JSON 部分的大小上限，超过时视为损坏的文件
+const maxJSONSize = 64 << 20
+
+// Document glTF JSON 中校验和统计需要的部分
+type Document struct {
+	Asset struct {
+		Version    string                 `json:"version"`
+		MinVersion string                 `json:"minVersion"`
+		Generator  string                 `json:"generator"`
+		Extras     map[string]interface{} `json:"extras"`
+	} `json:"asset"`
+	Scene              *int         `json:"scene"`
+	Scenes             []Scene      `json:"scenes"`
+	Nodes              []Node       `json:"nodes"`
+	Meshes             []Mesh       `json:"meshes"`
+	Accessors          []Accessor   `json:"accessors"`
+	BufferViews        []BufferView `json:"bufferViews"`
+	Buffers            []Buffer     `json:"buffers"`
+	Materials          []struct{}   `json:"materials"`
+	Textures           []struct{}   `json:"textures"`
+	Images             []Image      `json:"images"`
+	ExtensionsUsed     []string     `json:"extensionsUsed"`
+	ExtensionsRequired []string     `json:"extensionsRequired"`
+}
+
+type Scene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type Node struct {
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Matrix      []float64 `json:"matrix"`
+	Translation []float64 `json:"translation"`
+	Rotation    []float64 `json:"rotation"`
+	Scale       []float64 `json:"scale"`
+}
+
+type Mesh struct {
+	Primitives []Primitive `json:"primitives"`
+}
+
+type Primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Mode       *int           `json:"mode"`
+}
+
+type Accessor struct {
+	BufferView    *int      `json:"bufferView"`
+	ByteOffset    int64     `json:"byteOffset"`
+	ComponentType int       `json:"componentType"`
+	Count         int64     `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min"`
+	Max           []float64 `json:"max"`
+}
+
+type BufferView struct {
+	Buffer     int   `json:"buffer"`
+	ByteOffset int64 `json:"byteOffset"`
+	ByteLength int64 `json:"byteLength"`
+	ByteStride int64 `json:"byteStride"`
+}
+
+type Buffer struct {
+	URI        string `json:"uri"`
+	ByteLength int64  `json:"byteLength"`
+}
+
+type Image struct {
+	URI        string `json:"uri"`
+	BufferView *int   `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+// File 解析后的模型文件
+type File struct {
+	Binary    bool  // 是否为 GLB
+	BINLength int64 // GLB 内嵌 BIN 块的长度，没有 BIN 块时为 -1
+	Doc       Document
+}
+
+// Read 读取 .gltf 或 .glb，按文件头自动识别格式
+// GLB 只读取 JSON 块和 BIN 块的长度，不会把二进制数据读入内存
+func Read(r io.Reader) (*File, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, fmt.Errorf("模型文件为空或已损坏: %w", err)
+	}
+	if binary.LittleEndian.Uint32(head[:]) == glbMagic {
+		return readGLB(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(io.MultiReader(bytes.NewReader(head[:]), r), maxJSONSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxJSONSize {
+		return nil, errors.New("glTF JSON 过大")
+	}
+	f := &File{BINLength: -1}
+	if err := decodeJSON(data, &f.Doc); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func readGLB(r io.Reader) (*File, error) {
+	var header struct {
+		Version uint32
+		Length  uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("GLB 文件头不完整: %w", err)
+	}
+	if header.Version != 2 {
+		return nil, fmt.Errorf("不支持的 GLB 版本: %d", header.Version)
+	}
+
+	// 第一个块必须是 JSON
+	var chunk struct {
+		Length uint32
+		Type   uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+		return nil, fmt.Errorf("GLB 缺少 JSON 块: %w", err)
+	}
+	if chunk.Type != glbChunkJSON {
+		return nil, errors.New("GLB 的第一个块不是 JSON")
+	}
+	if chunk.Length > maxJSONSize || int64(chunk.Length)+20 > int64(header.Length) {
+		return nil, errors.New("GLB 的 JSON 块长度无效")
+	}
+	data := make([]byte, chunk.Length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("GLB 的 JSON 块不完整: %w", err)
+	}
+
+	f := &File{Binary: true, BINLength: -1}
+	if err := decodeJSON(bytes.TrimRight(data, " \x00"), &f.Doc); err != nil {
+		return nil, err
+	}
+
+	// 可选的 BIN 块，只确认长度与文件实际大小一致
+	if err := binary.Read(r, binary.LittleEndian, &chunk); err == nil && chunk.Type == glbChunkBIN {
+		n, err := io.CopyN(io.Discard, r, int64(chunk.Length))
+		if err != nil || n != int64(chunk.Length) {
+			return nil, errors.New("GLB 的 BIN 块不完整")
+		}
+		f.BINLength = int64(chunk.Length)
+	}
+	return f, nil
+}
+
+func decodeJSON(data []byte, doc *Document) error {
+	if err := json.Unmarshal(data, doc); err != nil {
+		return fmt.Errorf("glTF JSON 解析失败: %w", err)
+	}
+	if doc.Asset.Version == "" {
+		return errors.New("glTF 缺少 asset.version")
+	}
+	if doc.Asset.Version[0] != '2' {
+		return fmt.Errorf("只支持 glTF 2.0，文件版本为 %s", doc.Asset.Version)
+	}
+	return nil
+}
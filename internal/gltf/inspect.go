@@ -0,0 +1,270 @@
+package gltf
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Resolver 查找模型引用的外部文件，返回其大小；文件不存在时返回错误
+type Resolver func(uri string) (size int64, err error)
+
+// Stats 模型的统计信息
+type Stats struct {
+	Format         string     // gltf / glb
+	Version        string     // asset.version
+	Generator      string     // 导出工具
+	MeshCount      int        // 网格数量
+	PrimitiveCount int        // 图元 (子网格) 数量
+	VertexCount    int64      // 顶点数，按网格定义统计，不含实例化
+	TriangleCount  int64      // 三角形数，按网格定义统计
+	MaterialCount  int        // 材质数量
+	TextureCount   int        // 纹理数量
+	ImageCount     int        // 图片数量
+	NodeCount      int        // 节点数量
+	HasBounds      bool       // 是否算出了包围盒
+	BoundsMin      [3]float64 // 场景坐标系下的包围盒
+	BoundsMax      [3]float64
+	UpAxis         string   // 向上的轴，glTF 规定为 Y；根节点绕 X 轴旋转 90 度时说明原始模型为 Z 轴向上
+	ExtensionsUsed []string // 使用的扩展
+	ExternalFiles  []string // 引用的外部文件 (buffers 和 images 的相对 uri)
+	Images         []string // 其中的外部贴图
+}
+
+// componentSizes accessor.componentType 对应的字节数
+var componentSizes = map[int]int64{5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4}
+
+// typeComponents accessor.type 对应的分量个数
+var typeComponents = map[string]int64{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16}
+
+// Inspect 校验模型中的索引和数据引用，并统计网格信息
+// 外部文件通过 resolve 检查是否存在以及长度是否足够，resolve 为 nil 时外部引用视为错误
+func Inspect(f *File, resolve Resolver) (*Stats, error) {
+	doc := &f.Doc
+	stats := &Stats{
+		Format:         "gltf",
+		Version:        doc.Asset.Version,
+		Generator:      doc.Asset.Generator,
+		MeshCount:      len(doc.Meshes),
+		MaterialCount:  len(doc.Materials),
+		TextureCount:   len(doc.Textures),
+		ImageCount:     len(doc.Images),
+		NodeCount:      len(doc.Nodes),
+		UpAxis:         "Y",
+		ExtensionsUsed: doc.ExtensionsUsed,
+	}
+	if f.Binary {
+		stats.Format = "glb"
+	}
+
+	if err := checkBuffers(f, resolve, stats); err != nil {
+		return nil, err
+	}
+	if err := checkImages(doc, resolve, stats); err != nil {
+		return nil, err
+	}
+	if err := checkBufferViews(doc); err != nil {
+		return nil, err
+	}
+	if err := checkAccessors(doc); err != nil {
+		return nil, err
+	}
+	if err := countMeshes(doc, stats); err != nil {
+		return nil, err
+	}
+	if err := computeBounds(doc, stats); err != nil {
+		return nil, err
+	}
+	stats.UpAxis = upAxis(doc)
+	return stats, nil
+}
+
+func checkBuffers(f *File, resolve Resolver, stats *Stats) error {
+	for i, b := range f.Doc.Buffers {
+		if b.ByteLength <= 0 {
+			return fmt.Errorf("buffers[%d] 的 byteLength 无效", i)
+		}
+		switch {
+		case b.URI == "":
+			// 没有 uri 的 buffer 只能是 GLB 内嵌的第一个 buffer
+			if !f.Binary || i != 0 {
+				return fmt.Errorf("buffers[%d] 缺少 uri", i)
+			}
+			if f.BINLength < b.ByteLength {
+				return fmt.Errorf("GLB 的 BIN 块长度 %d 小于 buffers[0].byteLength %d", f.BINLength, b.ByteLength)
+			}
+		case strings.HasPrefix(b.URI, "data:"):
+			n, err := dataURILength(b.URI)
+			if err != nil {
+				return fmt.Errorf("buffers[%d] 的 data URI 无效: %w", i, err)
+			}
+			if n < b.ByteLength {
+				return fmt.Errorf("buffers[%d] 的数据长度 %d 小于 byteLength %d", i, n, b.ByteLength)
+			}
+		default:
+			size, err := resolveExternal(resolve, b.URI, stats)
+			if err != nil {
+				return fmt.Errorf("buffers[%d] 引用的文件 %s 不存在", i, b.URI)
+			}
+			if size < b.ByteLength {
+				return fmt.Errorf("buffers[%d] 引用的文件 %s 长度 %d 小于 byteLength %d", i, b.URI, size, b.ByteLength)
+			}
+		}
+	}
+	return nil
+}
+
+func checkImages(doc *Document, resolve Resolver, stats *Stats) error {
+	for i, img := range doc.Images {
+		switch {
+		case img.BufferView != nil:
+			if !validIndex(*img.BufferView, len(doc.BufferViews)) {
+				return fmt.Errorf("images[%d].bufferView 越界", i)
+			}
+		case img.URI == "":
+			return fmt.Errorf("images[%d] 缺少 uri 或 bufferView", i)
+		case strings.HasPrefix(img.URI, "data:"):
+			if _, err := dataURILength(img.URI); err != nil {
+				return fmt.Errorf("images[%d] 的 data URI 无效: %w", i, err)
+			}
+		default:
+			if _, err := resolveExternal(resolve, img.URI, stats); err != nil {
+				return fmt.Errorf("images[%d] 引用的贴图 %s 不存在", i, img.URI)
+			}
+			stats.Images = append(stats.Images, img.URI)
+		}
+	}
+	return nil
+}
+
+func resolveExternal(resolve Resolver, uri string, stats *Stats) (int64, error) {
+	stats.ExternalFiles = append(stats.ExternalFiles, uri)
+	if resolve == nil {
+		return 0, fmt.Errorf("外部文件 %s 无法访问", uri)
+	}
+	return resolve(uri)
+}
+
+func checkBufferViews(doc *Document) error {
+	for i, v := range doc.BufferViews {
+		if !validIndex(v.Buffer, len(doc.Buffers)) {
+			return fmt.Errorf("bufferViews[%d].buffer 越界", i)
+		}
+		if v.ByteOffset < 0 || v.ByteLength <= 0 || v.ByteOffset+v.ByteLength > doc.Buffers[v.Buffer].ByteLength {
+			return fmt.Errorf("bufferViews[%d] 超出 buffer 范围", i)
+		}
+		if v.ByteStride != 0 && (v.ByteStride < 4 || v.ByteStride > 252) {
+			return fmt.Errorf("bufferViews[%d].byteStride 无效", i)
+		}
+	}
+	return nil
+}
+
+func checkAccessors(doc *Document) error {
+	for i, a := range doc.Accessors {
+		compSize, ok := componentSizes[a.ComponentType]
+		if !ok {
+			return fmt.Errorf("accessors[%d].componentType 无效: %d", i, a.ComponentType)
+		}
+		n, ok := typeComponents[a.Type]
+		if !ok {
+			return fmt.Errorf("accessors[%d].type 无效: %s", i, a.Type)
+		}
+		if a.Count <= 0 {
+			return fmt.Errorf("accessors[%d].count 无效", i)
+		}
+		if a.BufferView == nil {
+			continue // 没有 bufferView 的 accessor 数据全部为 0，合法
+		}
+		if !validIndex(*a.BufferView, len(doc.BufferViews)) {
+			return fmt.Errorf("accessors[%d].bufferView 越界", i)
+		}
+		view := doc.BufferViews[*a.BufferView]
+		elemSize := compSize * n
+		stride := view.ByteStride
+		if stride == 0 {
+			stride = elemSize
+		}
+		if a.ByteOffset < 0 || a.ByteOffset+stride*(a.Count-1)+elemSize > view.ByteLength {
+			return fmt.Errorf("accessors[%d] 超出 bufferViews[%d] 范围", i, *a.BufferView)
+		}
+	}
+	return nil
+}
+
+func countMeshes(doc *Document, stats *Stats) error {
+	for i, mesh := range doc.Meshes {
+		if len(mesh.Primitives) == 0 {
+			return fmt.Errorf("meshes[%d] 没有图元", i)
+		}
+		for j, p := range mesh.Primitives {
+			stats.PrimitiveCount++
+			for name, idx := range p.Attributes {
+				if !validIndex(idx, len(doc.Accessors)) {
+					return fmt.Errorf("meshes[%d].primitives[%d].attributes.%s 越界", i, j, name)
+				}
+			}
+			var vertices int64
+			if idx, ok := p.Attributes["POSITION"]; ok {
+				vertices = doc.Accessors[idx].Count
+			}
+			stats.VertexCount += vertices
+
+			elements := vertices
+			if p.Indices != nil {
+				if !validIndex(*p.Indices, len(doc.Accessors)) {
+					return fmt.Errorf("meshes[%d].primitives[%d].indices 越界", i, j)
+				}
+				elements = doc.Accessors[*p.Indices].Count
+			}
+			mode := 4
+			if p.Mode != nil {
+				mode = *p.Mode
+			}
+			switch mode {
+			case 4: // TRIANGLES
+				stats.TriangleCount += elements / 3
+			case 5, 6: // TRIANGLE_STRIP / TRIANGLE_FAN
+				if elements > 2 {
+					stats.TriangleCount += elements - 2
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validIndex(i, n int) bool {
+	return i >= 0 && i < n
+}
+
+// dataURILength 返回 base64 data URI 解码后的字节数
+func dataURILength(uri string) (int64, error) {
+	comma := strings.IndexByte(uri, ',')
+	if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+		return 0, fmt.Errorf("只支持 base64 编码")
+	}
+	data, err := base64.StdEncoding.DecodeString(uri[comma+1:])
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// upAxis 判断模型原始的向上轴
+func upAxis(doc *Document) string {
+	if v, ok := doc.Asset.Extras["upAxis"].(string); ok && v != "" {
+		return strings.ToUpper(v)
+	}
+	roots := sceneRoots(doc)
+	if len(roots) == 1 {
+		r := doc.Nodes[roots[0]].Rotation
+		// 四元数 (±sin45°, 0, 0, cos45°)，即绕 X 轴旋转 ±90 度，常见于 Z 轴向上的软件导出
+		if len(r) == 4 && math.Abs(math.Abs(r[0])-math.Sqrt2/2) < 1e-3 &&
+			math.Abs(r[1]) < 1e-3 && math.Abs(r[2]) < 1e-3 && math.Abs(math.Abs(r[3])-math.Sqrt2/2) < 1e-3 {
+			return "Z"
+		}
+	}
+	return "Y"
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// AdminHandler 运维接口：存储与数据库的对账
+type AdminHandler struct {
+	reconciler *service.Reconciler
+	minAge     time.Duration // 未指定 min_age 时使用的默认值
+}
+
+func NewAdminHandler(reconciler *service.Reconciler, minAge time.Duration) *AdminHandler {
+	return &AdminHandler{reconciler: reconciler, minAge: minAge}
+}
+
+// PreviewReconcile 预览对账结果，不做任何修改
+// GET /api/v1/admin/reconcile?min_age=1h
+func (h *AdminHandler) PreviewReconcile(c *gin.Context) {
+	opts, err := h.parseReconcileOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := h.reconciler.Scan(opts)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// Reconcile 按预览结果修复：删除孤立文件和失效记录，修正内容引用数
+// POST /api/v1/admin/reconcile?min_age=1h&confirm=<预览返回的 fingerprint>
+// 预览之后存储或数据库发生变化时返回 409，需要重新预览
+func (h *AdminHandler) Reconcile(c *gin.Context) {
+	opts, err := h.parseReconcileOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fingerprint := c.Query("confirm")
+	if fingerprint == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先通过 GET 预览，并在 confirm 参数中提供预览结果的 fingerprint"})
+		return
+	}
+	report, err := h.reconciler.Repair(opts, fingerprint)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// parseReconcileOptions 解析 min_age 参数，例如 30m、2h
+func (h *AdminHandler) parseReconcileOptions(c *gin.Context) (service.ReconcileOptions, error) {
+	opts := service.ReconcileOptions{MinAge: h.minAge}
+	if v := c.Query("min_age"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return opts, fmt.Errorf("min_age 格式错误，应为 30m、2h 这样的时长")
+		}
+		opts.MinAge = d
+	}
+	return opts, nil
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// CRSHandler 坐标系查询和坐标转换
+type CRSHandler struct{}
+
+func NewCRSHandler() *CRSHandler {
+	return &CRSHandler{}
+}
+
+// ListCRS 列出支持转换的坐标系
+func (h *CRSHandler) ListCRS(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": geo.SupportedCRS()})
+}
+
+// Transform 批量转换坐标
+// 请求体: {"from": "EPSG:4548", "to": "EPSG:4326", "points": [[x, y], ...]}，经纬度坐标 x 为经度、y 为纬度
+func (h *CRSHandler) Transform(c *gin.Context) {
+	var req struct {
+		From   string       `json:"from" binding:"required"`
+		To     string       `json:"to" binding:"required"`
+		Points [][2]float64 `json:"points" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	points, err := service.ConvertPoints(req.From, req.To, req.Points)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	to, _ := service.NormalizeCRS(req.To)
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"crs": to, "points": points}})
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
 	"Go_for_unity/internal/store"
-	"archive/zip"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"io"
@@ -15,12 +17,19 @@ import (
 )
 
 type DataFileHandler struct {
-	dfStore *store.DataFileStore
-	isStore *store.IslandStore // 需要 IslandStore 来获取岛屿信息以构建路径
+	dfStore  *store.DataFileStore
+	isStore  *store.IslandStore        // 需要 IslandStore 来获取岛屿信息以构建路径
+	ingestor *service.Ingestor         // 负责按类型处理上传文件
+	jobs     *service.JobRunner        // 在后台执行上传处理任务
+	content  *service.ContentStore     // 内容寻址存储，删除文件时释放引用
+	backend  storage.Backend           // 存储后端，用于清理旧版按岛屿目录存储的文件
+	geojson  *service.GeoJSONService   // shp 转 GeoJSON 并缓存
+	thumbs   *service.ThumbnailService // jpg 文件的缩略图
+	editor   *service.DataFileEditor   // 修改名称、类型，以及在岛屿之间移动或复制文件
 }
 
-func NewDataFileHandler(dfStore *store.DataFileStore, isStore *store.IslandStore) *DataFileHandler {
-	return &DataFileHandler{dfStore: dfStore, isStore: isStore}
+func NewDataFileHandler(dfStore *store.DataFileStore, isStore *store.IslandStore, ingestor *service.Ingestor, jobs *service.JobRunner, content *service.ContentStore, backend storage.Backend, geojson *service.GeoJSONService, thumbs *service.ThumbnailService, editor *service.DataFileEditor) *DataFileHandler {
+	return &DataFileHandler{dfStore: dfStore, isStore: isStore, ingestor: ingestor, jobs: jobs, content: content, backend: backend, geojson: geojson, thumbs: thumbs, editor: editor}
 }
 
 // 1. 上传文件接口
@@ -38,103 +47,55 @@ func (h *DataFileHandler) UploadDataFile(c *gin.Context) {
 	isleID, _ := strconv.ParseUint(isleIDStr, 10, 64)
 	height, _ := strconv.ParseFloat(heightStr, 64)
 
-	// --- 2. 获取岛屿信息，构建存储路径 ---
-	island, err := h.isStore.GetByID(uint(isleID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "关联的岛屿不存在"})
+	// --- 2. 先做同步校验，尽早把明显错误返回给前端 ---
+	if err := service.ValidateUpload(dataType, file.Filename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// 路径格式: uploads/用户名/岛屿名/文件类型/
-	baseUploadDir := filepath.Join("uploads", island.BelongTo, island.IsleName, dataType)
-	if err := os.MkdirAll(baseUploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建文件目录失败: " + err.Error()})
+	// 按文件头和内容结构检查是否与声明的类型一致，例如把 PDF 当作 jpg 上传
+	if err := checkUploadedFile(file, func(r io.ReaderAt) error {
+		return service.CheckContent(dataType, file.Filename, r, file.Size)
+	}); err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	if _, err := h.isStore.GetByID(uint(isleID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关联的岛屿不存在"})
 		return
 	}
 
-	var finalFilePath string // 最终要存入数据库的文件路径
-
-	// --- 3. 根据文件类型分别处理 ---
-	switch dataType {
-	case "shp":
-		// 解压zip文件
-		zipPath := filepath.Join(baseUploadDir, file.Filename)
-		if err := c.SaveUploadedFile(file, zipPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存zip文件失败"})
-			return
-		}
-		// 解压到同名文件夹
-		unzipDest := strings.TrimSuffix(zipPath, filepath.Ext(zipPath))
-		if err := unzip(zipPath, unzipDest); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "解压文件失败: " + err.Error()})
-			return
-		}
-		os.Remove(zipPath) // 删除临时的zip包
-
-		// 查找目标文件 (.shp 或 .tif)
-		targetExt := "." + dataType
-		foundPath, err := findFileByExt(unzipDest, targetExt)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("在压缩包中未找到 %s 文件", targetExt)})
-			return
-		}
-		finalFilePath = foundPath
-	case "tif":
-		// 1. 保存并解压 zip 文件
-		zipPath := filepath.Join(baseUploadDir, file.Filename)
-		if err := c.SaveUploadedFile(file, zipPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存zip文件失败"})
-			return
-		}
-		// 解压到与 zip 同名的文件夹
-		unzipDest := strings.TrimSuffix(zipPath, filepath.Ext(zipPath))
-		if err := unzip(zipPath, unzipDest); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "解压文件失败: " + err.Error()})
-			return
-		}
-		os.Remove(zipPath) // 删除临时的zip包
-
-		// 2. 获取 zip 文件的基本名称 (例如 "MyTiles" from "MyTiles.zip")
-		baseName := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
-
-		// 3. 使用新的辅助函数查找索引文件 (.xml 或 .json)
-		foundPath, err := findRasterIndexFile(unzipDest, baseName)
-		if err != nil {
-			// 如果找不到，给前端返回明确的错误信息
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		finalFilePath = foundPath
-
-	case "models", "txt", "jpg", "weather", "mapping":
-		// 直接保存文件
-		savePath := filepath.Join(baseUploadDir, file.Filename)
-		if err := c.SaveUploadedFile(file, savePath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
-			return
-		}
-		finalFilePath = savePath
+	// --- 3. 把文件保存到临时目录 ---
+	stagingDir, err := service.NewStagingDir("form-")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建临时目录失败: " + err.Error()})
+		return
+	}
 
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件类型: " + dataType})
+	stagingPath := filepath.Join(stagingDir, filepath.Base(file.Filename))
+	if err := c.SaveUploadedFile(file, stagingPath); err != nil {
+		os.RemoveAll(stagingDir)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
 		return
 	}
 
-	// --- 4. 创建数据库记录 ---
-	// 首先获取纯数据名
-	cleanDataName := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
-	dataFile := model.DataFile{
-		DataName: cleanDataName,
-		DataType: dataType,
-		DataPath: finalFilePath,
+	// --- 4. 提交后台任务：解压、查找索引文件并创建数据库记录 ---
+	// 临时目录由任务在结束后清理
+	req := service.IngestRequest{
 		IsleID:   uint(isleID),
+		DataType: dataType,
 		Height:   height,
+		FileName: filepath.Base(file.Filename),
+		SrcPath:  stagingPath,
 	}
-	if err := h.dfStore.Create(&dataFile); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库记录创建失败: " + err.Error()})
+	job, err := h.jobs.Submit(service.UploadJob(req), h.ingestor.Task(req, stagingDir))
+	if err != nil {
+		os.RemoveAll(stagingDir)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "文件上传并处理成功", "data": dataFile})
+	// 通过 GET /api/v1/jobs/:id 或 WebSocket 的 job 事件查询处理进度
+	c.JSON(http.StatusAccepted, gin.H{"message": "文件已接收，正在后台处理", "job_id": job.ID, "data": job})
 }
 
 // 2. 按岛屿ID分页获取文件列表
@@ -167,32 +128,38 @@ func (h *DataFileHandler) GetDataFilesByIsle(c *gin.Context) {
 }
 
 // 3. 删除文件
+// 删除整个版本链：id 可以是链中任意一个版本，所有历史版本一并删除
 func (h *DataFileHandler) DeleteDataFile(c *gin.Context) {
 	idStr := c.Param("id")
 	id, _ := strconv.ParseUint(idStr, 10, 64)
 
-	// 先从数据库查找记录，获取文件路径
+	// 先从数据库查找记录和所有版本，获取文件路径
 	file, err := h.dfStore.GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
 		return
 	}
+	versions, err := h.dfStore.ListVersions(file.LineageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询文件版本失败: " + err.Error()})
+		return
+	}
 
 	// 从数据库删除记录
-	if err := h.dfStore.Delete(uint(id)); err != nil {
+	if err := h.dfStore.DeleteLineage(file.LineageID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除数据库记录失败: " + err.Error()})
 		return
 	}
 
-	// 从磁盘删除文件/文件夹
-	// 如果是解压的文件，删除整个解压后的文件夹
-	var pathToDel = file.DataPath
-	if file.DataType == "shp" || file.DataType == "tif" {
-		pathToDel = filepath.Dir(file.DataPath)
+	// 逐个版本清理存储内容，即使失败也返回成功，因为数据库记录已经删了
+	var cleanupErrs []string
+	for i := range versions {
+		if err := h.removeContent(&versions[i]); err != nil {
+			cleanupErrs = append(cleanupErrs, err.Error())
+		}
 	}
-	if err := os.RemoveAll(pathToDel); err != nil {
-		// 即使文件删除失败，也返回成功，因为数据库记录已经删了
-		c.JSON(http.StatusOK, gin.H{"message": "数据库记录删除成功，但清理磁盘文件时出错: " + err.Error()})
+	if len(cleanupErrs) > 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "数据库记录删除成功，但清理存储内容时出错: " + strings.Join(cleanupErrs, "; ")})
 		return
 	}
 
@@ -220,84 +187,159 @@ func (h *DataFileHandler) UpdateDataFileHeight(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "高度更新成功"})
 }
 
-// --- Helper Functions ---
+// 5. 获取 shp 图层的 GeoJSON
+// 可选参数: bbox=minx,miny,maxx,maxy 范围过滤, fields=a,b 属性字段选择, precision=6 坐标小数位数
+func (h *DataFileHandler) GetGeoJSON(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 64)
 
-// unzip 解压 zip 文件
-func unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
+	file, err := h.dfStore.GetByID(uint(id))
 	if err != nil {
-		return err
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
+		return
 	}
-	defer r.Close()
-
-	os.MkdirAll(dest, 0755)
 
-	for _, f := range r.File {
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-		defer rc.Close()
+	req, err := parseGeoJSONQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-		path := filepath.Join(dest, f.Name)
+	key, err := h.geojson.Convert(file, req)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.Header("Content-Type", "application/geo+json")
+	h.backend.Serve(c.Writer, c.Request, key)
+}
 
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
-		} else {
-			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer f.Close()
+// 6. 获取 jpg 文件的缩略图
+// GET /api/v1/data-files/:id/thumbnail?size=256
+func (h *DataFileHandler) GetThumbnail(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 64)
 
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
-			}
-		}
+	file, err := h.dfStore.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
+		return
+	}
+	if file.DataType != "jpg" || !service.IsThumbnailSource(file.DataPath) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该文件不是图片，无法生成缩略图"})
+		return
 	}
-	return nil
+	serveThumbnail(c, h.thumbs, h.backend, file.DataPath)
 }
 
-// findFileByExt 在目录中查找指定后缀的文件
-func findFileByExt(rootDir, ext string) (string, error) {
-	var foundPath string
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ext) {
-			foundPath = path
-			return filepath.SkipDir // 找到后停止遍历
-		}
-		return nil
-	})
+// 7. 修改文件元数据，或把文件移动/复制到其他岛屿
+// PATCH /api/v1/data-files/:id {"data_name":"新名称","data_type":"mapping","height":1.5,"isle_id":2,"copy":false}
+// 显示样式: {"style":{"draw_order":2,"visible":true,"opacity":0.8,"fill_color":"#FF880080","stroke_color":"#333333","line_width":2,"label_field":"NAME"}}
+// 字段均可选；名称、类型和岛屿对整个版本链生效，copy 为 true 时原文件保留并返回新记录
+// 移动到其他岛屿时文件随之移动到目标岛屿的 uploads/<用户>/<岛屿>/<类型> 目录；复制时内容存储中的文件只增加引用
+func (h *DataFileHandler) PatchDataFile(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 64)
+
+	var req service.DataFilePatch
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	file, err := h.editor.Patch(uint(id), req)
 	if err != nil {
-		return "", err
+		respondIngestError(c, err)
+		return
 	}
-	if foundPath == "" {
-		return "", fmt.Errorf("no file with extension %s found", ext)
+	if req.Copy {
+		c.JSON(http.StatusCreated, gin.H{"message": "文件复制成功", "data": file})
+		return
 	}
-	return foundPath, nil
+	c.JSON(http.StatusOK, gin.H{"message": "文件更新成功", "data": file})
 }
 
-// findRasterIndexFile 在解压目录中查找影像或道路模型的索引文件 (.xml 或 .json)
-// 它要求索引文件名必须与原始 zip 包的文件名（不含后缀）相同。
-func findRasterIndexFile(unzipDest, baseName string) (string, error) {
-	// 1. 尝试查找 .xml 文件
-	xmlPath := filepath.Join(unzipDest, baseName+".xml")
-	if _, err := os.Stat(xmlPath); err == nil {
-		// 文件存在，返回路径
-		return xmlPath, nil
+// 8. 调整岛屿中图层的绘制顺序
+// PUT /api/v1/islands/:id/layer-order {"ids":[3,1,2]}
+// 按 ids 的顺序把绘制顺序设为 0, 1, 2...，先画的在下层；未列出的图层保持不变
+func (h *DataFileHandler) ReorderLayers(c *gin.Context) {
+	isleID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
 	}
 
-	// 2. 如果 .xml 不存在，尝试查找 .json 文件
-	jsonPath := filepath.Join(unzipDest, baseName+".json")
-	if _, err := os.Stat(jsonPath); err == nil {
-		// 文件存在，返回路径
-		return jsonPath, nil
+	if err := h.editor.Reorder(uint(isleID), req.IDs); err != nil {
+		respondIngestError(c, err)
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "绘制顺序更新成功"})
+}
 
-	// 3. 如果两者都不存在，返回错误
-	return "", fmt.Errorf("在压缩包中未找到索引文件 %s.xml 或 %s.json", baseName, baseName)
+// --- Helper Functions ---
+
+// removeContent 清理已删除记录的存储内容
+func (h *DataFileHandler) removeContent(file *model.DataFile) error {
+	// 引用内容存储的文件只释放引用，内容在没有其他 DataFile 引用时才会从磁盘删除
+	if file.BlobHash != "" {
+		return h.content.Release(file.BlobHash)
+	}
+
+	// 旧版按岛屿目录存储的文件：从存储中删除文件/文件夹
+	// 如果是解压的文件，删除整个解压后的文件夹
+	var pathToDel = file.DataPath
+	if service.ExtractsArchive(file.DataType) {
+		pathToDel = storage.Dir(file.DataPath)
+	}
+	if service.IsThumbnailSource(file.DataPath) {
+		h.thumbs.Remove(file.DataPath)
+	}
+	return h.backend.RemoveAll(pathToDel)
+}
+
+// parseGeoJSONQuery 解析 GeoJSON 接口的查询参数
+func parseGeoJSONQuery(c *gin.Context) (service.GeoJSONRequest, error) {
+	req := service.GeoJSONRequest{Precision: -1}
+
+	bbox, err := parseBBox(c.Query("bbox"))
+	if err != nil {
+		return req, err
+	}
+	req.BBox = bbox
+
+	if s, ok := c.GetQuery("fields"); ok {
+		req.Fields = []string{}
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				req.Fields = append(req.Fields, name)
+			}
+		}
+	}
+
+	if s := c.Query("precision"); s != "" {
+		p, err := strconv.Atoi(s)
+		if err != nil || p < 0 || p > 15 {
+			return req, fmt.Errorf("precision 应为 0-15 之间的整数")
+		}
+		req.Precision = p
+	}
+	return req, nil
+}
+
+// respondIngestError 根据 Ingestor 返回的错误类型选择合适的 HTTP 状态码
+func respondIngestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrIslandNotFound), errors.Is(err, service.ErrDataFileNotFound), errors.Is(err, service.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDataFileConflict), errors.Is(err, service.ErrReconcileChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case service.IsInputError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// checksumHeader 下载内容的 SHA-256 (十六进制)，打包下载时作为 trailer 在响应末尾发送
+const checksumHeader = "X-Checksum-Sha256"
+
+// DownloadHandler 按上传时的形式下载数据文件
+type DownloadHandler struct {
+	dfStore   *store.DataFileStore
+	downloads *service.DownloadService
+	backend   storage.Backend
+}
+
+func NewDownloadHandler(dfStore *store.DataFileStore, downloads *service.DownloadService, backend storage.Backend) *DownloadHandler {
+	return &DownloadHandler{dfStore: dfStore, downloads: downloads, backend: backend}
+}
+
+// Download 下载数据文件
+// GET /api/v1/data-files/:id/download
+// 单个文件支持 Range 断点续传，响应头带有 ETag、Digest 和 X-Checksum-Sha256；
+// shp、tif 等压缩包类型把解压目录重新打包为 zip 流式返回，不支持 Range，校验和在 trailer 中。
+// 重新打包的 zip 与上传时的 zip 字节不同 (条目顺序、压缩方式等)，校验和只对本次下载的内容有效，不能与上传文件的哈希比较
+func (h *DownloadHandler) Download(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 64)
+
+	// 1. 查询文件记录
+	file, err := h.dfStore.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
+		return
+	}
+	if _, err := h.backend.Stat(file.DataPath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件内容不存在"})
+		return
+	}
+
+	// 2. 确定下载内容
+	d, err := h.downloads.Resolve(file)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.FileName}))
+
+	// 3. 单个文件：由存储后端输出，Range、If-Range 等请求头由后端处理
+	if !d.IsArchive() {
+		sum, _ := hex.DecodeString(d.SHA256)
+		c.Header("ETag", `"`+d.SHA256+`"`)
+		c.Header("Digest", "sha-256="+base64.StdEncoding.EncodeToString(sum))
+		c.Header(checksumHeader, d.SHA256)
+		h.backend.Serve(c.Writer, c.Request, d.Key)
+		return
+	}
+
+	// 4. 压缩包类型：边打包边输出，校验和在写完后作为 trailer 发送
+	c.Header("Content-Type", "application/zip")
+	c.Header("Accept-Ranges", "none")
+	if c.Request.Method == http.MethodHead {
+		c.Status(http.StatusOK)
+		return
+	}
+	c.Header("Trailer", checksumHeader)
+	c.Status(http.StatusOK)
+	sum := sha256.New()
+	if err := h.downloads.WriteZip(io.MultiWriter(c.Writer, sum), file, d); err != nil {
+		// 响应头已经发出，无法再返回错误；不发送校验和，客户端据此判断压缩包不完整
+		log.Printf("打包下载文件 %d 失败: %v", file.ID, err)
+		return
+	}
+	c.Writer.Header().Set(checksumHeader, hex.EncodeToString(sum.Sum(nil)))
+}
@@ -1,13 +1,16 @@
 package handler
 
 import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/service"
 	"Go_for_unity/internal/store"
 	"Go_for_unity/internal/ws"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
-	"strings"
 )
 
 // --- 定义与最终 JSON 结构对应的 Go Struct ---
@@ -25,26 +28,6 @@ type LatLonHeight struct {
 	Height float64 `json:"height"`
 }
 
-// FileEntry 是各类文件列表中的通用条目
-type FileEntry struct {
-	Name string `json:"name"`
-	Path string `json:"path"`
-}
-
-// VectorEntry 是 shp 文件的条目，多了 Height 字段
-type VectorEntry struct {
-	Name   string  `json:"name"`
-	Path   string  `json:"path"`
-	Height float64 `json:"height"`
-}
-
-// RasterEntry 是 tif 文件的条目，多了 Height 字段
-type RasterEntry struct {
-	Name   string  `json:"name"`
-	Path   string  `json:"path"`
-	Height float64 `json:"height"`
-}
-
 // CameraSetting 相机设置结构体
 type CameraSetting struct {
 	MoveSpeed   float64 `json:"moveSpeed"`
@@ -53,18 +36,38 @@ type CameraSetting struct {
 }
 
 // ExportedJSON 是最终生成的 JSON 的根结构
+// 各类文件列表 (vectors、rasters 等) 由数据类型处理器生成，序列化时按注册顺序追加在岛屿字段之后
 type ExportedJSON struct {
-	ProjectName   string        `json:"projectName"`
-	CesiumOrigin  LatLon        `json:"cesiumOrigin"`
-	PlayPosition  LatLonHeight  `json:"playPosition"`
-	CameraSetting CameraSetting `json:"cameraSetting"`
-	Vectors       []VectorEntry `json:"vectors"`
-	Rasters       []RasterEntry `json:"rasters"`
-	Models        []FileEntry   `json:"models"`
-	Pictures      []FileEntry   `json:"pictures"`
-	// Text         []FileEntry   `json:"text"`
-	WeatherFilePath []FileEntry `json:"weatherFilePath"`
-	CsvFilePath     []FileEntry `json:"csvFilePath"`
+	ProjectName   string                  `json:"projectName"`
+	CRS           string                  `json:"crs,omitempty"` // 指定导出坐标系时，cesiumOrigin、playPosition 和照片位置的 lat/lon 为该坐标系下的 Y/X，矢量和栅格的 bbox 也转换到该坐标系
+	CesiumOrigin  LatLon                  `json:"cesiumOrigin"`
+	PlayPosition  LatLonHeight            `json:"playPosition"`
+	CameraSetting CameraSetting           `json:"cameraSetting"`
+	Sections      []service.ExportSection `json:"-"`
+}
+
+// MarshalJSON 先输出岛屿字段，再把每个文件列表作为顶层字段输出，保持与原有 JSON 结构一致
+func (e ExportedJSON) MarshalJSON() ([]byte, error) {
+	type base ExportedJSON
+	head, err := json.Marshal(base(e))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(head[:len(head)-1])
+	for _, s := range e.Sections {
+		key, _ := json.Marshal(s.Name)
+		entries, err := json.Marshal(s.Entries)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(entries)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 // ExportHandler 负责处理导出逻辑
@@ -95,6 +98,15 @@ func (h *ExportHandler) ExportIslandJSON(c *gin.Context) {
 		return
 	}
 
+	// 可选的导出坐标系，岛屿坐标在数据库中保存为 WGS84 经纬度
+	crs := c.Query("crs")
+	if crs != "" {
+		if crs, err = service.NormalizeCRS(crs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 3. 查询该岛屿下的所有文件
 	files, err := h.dfStore.GetAllByIsleID(uint(isleID))
 	if err != nil {
@@ -119,61 +131,23 @@ func (h *ExportHandler) ExportIslandJSON(c *gin.Context) {
 			RotateSpeed: island.RotateSpeed,
 			ScaleSpeed:  island.ScaleSpeed,
 		},
-		// 初始化空的 slice，这样即使没有数据，JSON里也会是 [] 而不是 null
-		Vectors:  []VectorEntry{},
-		Rasters:  []RasterEntry{},
-		Models:   []FileEntry{},
-		Pictures: []FileEntry{},
-		//Text:     []FileEntry{},
-		WeatherFilePath: []FileEntry{},
-		CsvFilePath:     []FileEntry{},
+	}
+
+	// 按请求的坐标系转换岛屿中心和相机位置
+	if crs != "" {
+		if err := convertExportOrigin(&result, crs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
 	const targetHost = "10.7.7.2:9090"
 	const testHost = "localhost:9090"
-	// 5. 遍历文件，分类填充到 result 中
-	for _, file := range files {
-		fileURLPath := toStandardURLPath(testHost, file.DataPath)
-		switch file.DataType {
-		case "shp":
-			result.Vectors = append(result.Vectors, VectorEntry{
-				Name:   file.DataName,
-				Path:   file.DataPath, // 直接使用数据库中的路径
-				Height: file.Height,
-			})
-		case "tif":
-			result.Rasters = append(result.Rasters, RasterEntry{
-				Name: file.DataName,
-				// 将路径转换为静态服务 URL
-				Path:   fileURLPath, // 使用标准化 URL 路径
-				Height: file.Height,
-			})
-		case "models":
-			result.Models = append(result.Models, FileEntry{
-				Name: file.DataName,
-				Path: file.DataPath, // 直接使用数据库中的路径
-			})
-		case "jpg":
-			result.Pictures = append(result.Pictures, FileEntry{
-				Name: file.DataName,
-				Path: file.DataPath, // 直接使用数据库中的路径
-			})
-		//case "txt":
-		//	result.Text = append(result.Text, FileEntry{
-		//		Name: file.DataName,
-		//		Path: file.DataPath, // 直接使用数据库中的路径
-		//	})
-		case "weather":
-			result.WeatherFilePath = append(result.WeatherFilePath, FileEntry{
-				Name: file.DataName,
-				Path: file.DataPath,
-			})
-		case "mapping":
-			result.CsvFilePath = append(result.CsvFilePath, FileEntry{
-				Name: file.DataName,
-				Path: file.DataPath,
-			})
-		}
+	// 5. 按数据类型处理器把文件分类填充到 result 中，没有数据的列表为 [] 而不是 null
+	result.Sections, err = service.BuildExportSections(files, service.ExportContext{Host: testHost, CRS: crs})
+	if err != nil {
+		respondIngestError(c, err)
+		return
 	}
 
 	// 6. 返回 JSON 响应
@@ -186,14 +160,19 @@ func (h *ExportHandler) ExportIslandJSON(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// 辅助函数：将 Windows 路径标准化为 URL 路径，并拼接上目标 Host
-// targetHost: "10.7.7.2:9090"
-// filePath: "uploads\\user\\测试岛1\\tif\\tileset\\tileset.json"
-// 返回值: "http://10.7.7.2:9090/uploads/user/测试岛1/tif/tileset/tileset.json"
-func toStandardURLPath(targetHost string, filePath string) string {
-	// 1. 将 Windows 反斜杠替换为 URL 正斜杠
-	standardPath := strings.ReplaceAll(filePath, "\\", "/")
+// convertExportOrigin 把导出结果中的 WGS84 经纬度转换到 crs 坐标系，X 写入 lon、Y 写入 lat
+func convertExportOrigin(result *ExportedJSON, crs string) error {
+	x, y, err := service.ConvertPoint(geo.CRSWGS84, crs, result.CesiumOrigin.Lon, result.CesiumOrigin.Lat)
+	if err != nil {
+		return fmt.Errorf("转换岛屿中心点失败: %w", err)
+	}
+	result.CesiumOrigin.Lon, result.CesiumOrigin.Lat = x, y
 
-	// 2. 拼接为完整的 HTTP URL
-	return fmt.Sprintf("http://%s/%s", targetHost, standardPath)
+	x, y, err = service.ConvertPoint(geo.CRSWGS84, crs, result.PlayPosition.Lon, result.PlayPosition.Lat)
+	if err != nil {
+		return fmt.Errorf("转换相机位置失败: %w", err)
+	}
+	result.PlayPosition.Lon, result.PlayPosition.Lat = x, y
+	result.CRS = crs
+	return nil
 }
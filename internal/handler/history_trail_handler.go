@@ -2,20 +2,21 @@ package handler
 
 import (
 	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
 	"Go_for_unity/internal/store"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 )
 
 type HistoryTrailHandler struct {
-	store *store.HistoryTrailStore
+	store   *store.HistoryTrailStore
+	backend storage.Backend // 轨迹文件的存储后端
 }
 
-func NewHistoryTrailHandler(store *store.HistoryTrailStore) *HistoryTrailHandler {
-	return &HistoryTrailHandler{store: store}
+func NewHistoryTrailHandler(store *store.HistoryTrailStore, backend storage.Backend) *HistoryTrailHandler {
+	return &HistoryTrailHandler{store: store, backend: backend}
 }
 
 // CreateTrail 1. 上传并持久化历史轨迹文件
@@ -38,15 +39,10 @@ func (h *HistoryTrailHandler) CreateTrail(c *gin.Context) {
 	// 存储路径可以根据 category 再做一层隔离，更清晰
 	// 例如：uploads/trails/岛屿名/history_trail/
 	// 或   uploads/trails/岛屿名/annotation/
-	uploadDir := filepath.Join("uploads", "trails", isleName, category)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建存储目录失败: " + err.Error()})
-		return
-	}
+	savePath := storage.Join("uploads", "trails", isleName, category, filepath.Base(file.Filename))
 
 	// 保存文件
-	savePath := filepath.Join(uploadDir, file.Filename)
-	if err := c.SaveUploadedFile(file, savePath); err != nil {
+	if err := saveUploadedFile(h.backend, file, savePath); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败: " + err.Error()})
 		return
 	}
@@ -61,7 +57,7 @@ func (h *HistoryTrailHandler) CreateTrail(c *gin.Context) {
 
 	if err := h.store.Create(&trail); err != nil {
 		// 如果数据库创建失败，最好把刚刚保存的文件也删掉，保持数据一致性
-		os.Remove(savePath)
+		h.backend.Remove(savePath)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库记录创建失败: " + err.Error()})
 		return
 	}
@@ -119,9 +115,9 @@ func (h *HistoryTrailHandler) GetTrailFile(c *gin.Context) {
 		return
 	}
 
-	// 直接将文件作为响应返回
-	// Gin 会自动设置 Content-Type 为 application/json
-	c.File(trail.TrailPath)
+	// 通过存储后端将文件作为响应返回
+	// Content-Type 根据扩展名推断，.json 即为 application/json
+	h.backend.Serve(c.Writer, c.Request, trail.TrailPath)
 }
 
 // DeleteTrail 4. 删除轨迹/标注文件
@@ -140,8 +136,8 @@ func (h *HistoryTrailHandler) DeleteTrail(c *gin.Context) {
 		return
 	}
 
-	// 2. 从磁盘删除文件
-	if err := os.Remove(trail.TrailPath); err != nil {
+	// 2. 从存储中删除文件
+	if err := h.backend.Remove(trail.TrailPath); err != nil {
 		// 即使文件删除失败（例如文件已不存在），我们仍然继续删除数据库记录
 		// 但在日志中记录这个错误是个好习惯
 		// log.Printf("删除磁盘文件 %s 失败: %v", trail.TrailPath, err)
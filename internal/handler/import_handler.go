@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/store"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImportHandler 整个岛屿的批量导入
+type ImportHandler struct {
+	isStore  *store.IslandStore
+	importer *service.BatchImporter
+	jobs     *service.JobRunner
+}
+
+func NewImportHandler(isStore *store.IslandStore, importer *service.BatchImporter, jobs *service.JobRunner) *ImportHandler {
+	return &ImportHandler{isStore: isStore, importer: importer, jobs: jobs}
+}
+
+// ImportIsland 上传一个按类型分目录的 zip (shp/…、tif/…、models/…、jpg/…、weather/… 等，可附带 manifest.json 指定高度)
+// 在一个后台任务中创建全部 DataFile，任意一个失败时全部撤销；逐项结果见任务的 report 字段
+// POST /api/v1/islands/:isle_id/import (multipart: file)
+func (h *ImportHandler) ImportIsland(c *gin.Context) {
+	// --- 1. 校验参数 ---
+	isleID, _ := strconv.ParseUint(c.Param("isle_id"), 10, 64)
+	if _, err := h.isStore.GetByID(uint(isleID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "岛屿不存在"})
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件上传失败: " + err.Error()})
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(file.Filename), ".zip") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "批量导入只接受 zip 压缩包"})
+		return
+	}
+
+	// --- 2. 把文件保存到临时目录 ---
+	stagingDir, err := service.NewStagingDir("batch-")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建临时目录失败: " + err.Error()})
+		return
+	}
+	zipPath := filepath.Join(stagingDir, filepath.Base(file.Filename))
+	if err := c.SaveUploadedFile(file, zipPath); err != nil {
+		os.RemoveAll(stagingDir)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return
+	}
+
+	// --- 3. 提交后台任务，临时目录由任务在结束后清理 ---
+	job, err := h.jobs.Submit(service.BatchJob(uint(isleID), filepath.Base(file.Filename)), h.importer.Task(uint(isleID), zipPath, stagingDir))
+	if err != nil {
+		os.RemoveAll(stagingDir)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通过 GET /api/v1/jobs/:id 或 WebSocket 的 job 事件查询进度和逐项结果
+	c.JSON(http.StatusAccepted, gin.H{"message": "压缩包已接收，正在后台导入", "job_id": job.ID, "data": job})
+}
@@ -1,23 +1,28 @@
 package handler
 
 import (
+	"Go_for_unity/internal/geo"
 	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
 	"Go_for_unity/internal/store"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 )
 
 type IslandHandler struct {
-	store *store.IslandStore
+	store   *store.IslandStore
+	backend storage.Backend           // 岛屿图片的存储后端
+	thumbs  *service.ThumbnailService // 岛屿图片的缩略图
 }
 
-func NewIslandHandler(s *store.IslandStore) *IslandHandler {
-	return &IslandHandler{store: s}
+func NewIslandHandler(s *store.IslandStore, backend storage.Backend, thumbs *service.ThumbnailService) *IslandHandler {
+	return &IslandHandler{store: s, backend: backend, thumbs: thumbs}
 }
 
 // CreateIsland 1. 创建岛屿接口
@@ -38,6 +43,19 @@ func (h *IslandHandler) CreateIsland(c *gin.Context) {
 	moveSpeed, _ := strconv.ParseFloat(c.DefaultPostForm("moveSpeed", "0.7"), 64)
 	rotateSpeed, _ := strconv.ParseFloat(c.DefaultPostForm("rotateSpeed", "0.5"), 64)
 	scaleSpeed, _ := strconv.ParseFloat(c.DefaultPostForm("scaleSpeed", "1.0"), 64)
+
+	// 提交了 crs 时坐标按该坐标系解析，统一转换为 WGS84 经纬度保存
+	if crs := c.PostForm("crs"); crs != "" {
+		if err := convertFormPair(c, crs, "center_x", "center_y", &centerX, &centerY); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := convertFormPair(c, crs, "camera_x", "camera_y", &cameraX, &cameraY); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 处理文件上传
 	file, err := c.FormFile("isle_pic")
 	if err != nil {
@@ -45,16 +63,18 @@ func (h *IslandHandler) CreateIsland(c *gin.Context) {
 		return
 	}
 
-	// 创建存储路径: ./uploads/用户名/岛屿名/
-	uploadDir := filepath.Join("uploads", belongTo, isleName)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建目录失败: " + err.Error()})
+	if err := checkUploadedFile(file, func(r io.ReaderAt) error {
+		return service.CheckIslandPicture(file.Filename, r, file.Size)
+	}); err != nil {
+		respondIngestError(c, err)
 		return
 	}
 
+	// 存储路径: uploads/用户名/岛屿名/图片名
+	picPath := storage.Join("uploads", belongTo, isleName, filepath.Base(file.Filename))
+
 	// 保存文件
-	picPath := filepath.Join(uploadDir, file.Filename)
-	if err := c.SaveUploadedFile(file, picPath); err != nil {
+	if err := saveUploadedFile(h.backend, file, picPath); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败: " + err.Error()})
 		return
 	}
@@ -82,6 +102,7 @@ func (h *IslandHandler) CreateIsland(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库创建失败: " + err.Error()})
 		return
 	}
+	h.thumbs.Regenerate(picPath)
 
 	c.JSON(http.StatusOK, gin.H{"message": "岛屿创建成功", "data": island})
 }
@@ -116,6 +137,7 @@ func (h *IslandHandler) GetIslandsByOwner(c *gin.Context) {
 		// 这里我们做一个健壮性检查，防止 IslePicPath 为空
 		if islands[i].IslePicPath != "" {
 			islands[i].IslePicPath = fmt.Sprintf("http://%s/%s", c.Request.Host, islands[i].IslePicPath)
+			islands[i].IsleThumbURL = fmt.Sprintf("http://%s/api/v1/islands/%d/thumbnail", c.Request.Host, islands[i].ID)
 		}
 	}
 
@@ -152,10 +174,14 @@ func (h *IslandHandler) DeleteIsland(c *gin.Context) {
 		return
 	}
 
-	// 从磁盘删除整个岛屿目录
+	// 从存储中删除整个岛屿目录
 	// uploads/用户名/岛屿名
-	islandDir := filepath.Dir(island.IslePicPath)
-	if err := os.RemoveAll(islandDir); err != nil {
+	if island.IslePicPath == "" {
+		c.JSON(http.StatusOK, gin.H{"message": "岛屿删除成功"})
+		return
+	}
+	islandDir := storage.Dir(island.IslePicPath)
+	if err := h.backend.RemoveAll(islandDir); err != nil {
 		// 即使文件删除失败，数据库记录也已经删了，这里只记录日志或返回一个警告
 		c.JSON(http.StatusOK, gin.H{"message": "数据库记录删除成功，但清理文件时出错: " + err.Error()})
 		return
@@ -230,15 +256,36 @@ func (h *IslandHandler) UpdateIsland(c *gin.Context) {
 		}
 	}
 
+	// 提交了 crs 时，上面写入的是源坐标系下的坐标，这里成对转换为 WGS84 经纬度
+	if crs := c.PostForm("crs"); crs != "" {
+		if err := convertFormPair(c, crs, "center_x", "center_y", &island.CenterX, &island.CenterY); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := convertFormPair(c, crs, "camera_x", "camera_y", &island.CameraX, &island.CameraY); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 3. 处理可选的图片文件上传
 	newFile, err := c.FormFile("isle_pic") // 假设前端上传的文件字段名为 "isle_pic"
 	if err == nil {
 		// 如果 err 为 nil，说明用户上传了新图片
 		log.Println("检测到新图片上传，开始处理...")
 
-		// 3a. 删除旧图片（如果存在）
+		// 先检查新图片的内容，不合格时保留旧图片
+		if err := checkUploadedFile(newFile, func(r io.ReaderAt) error {
+			return service.CheckIslandPicture(newFile.Filename, r, newFile.Size)
+		}); err != nil {
+			respondIngestError(c, err)
+			return
+		}
+
+		// 3a. 删除旧图片（如果存在）及其缩略图
 		if island.IslePicPath != "" {
-			if removeErr := os.Remove(island.IslePicPath); removeErr != nil {
+			h.thumbs.Remove(island.IslePicPath)
+			if removeErr := h.backend.Remove(island.IslePicPath); removeErr != nil {
 				// 即使删除失败，也只记录日志，不中断主流程
 				log.Printf("删除旧图片失败: %s, 错误: %v", island.IslePicPath, removeErr)
 			} else {
@@ -248,11 +295,9 @@ func (h *IslandHandler) UpdateIsland(c *gin.Context) {
 
 		// 3b. 保存新图片
 		// 使用从数据库读出的 belong_to 和 isle_name 来构建路径，确保路径正确
-		uploadDir := filepath.Join("uploads", island.BelongTo, island.IsleName)
-		os.MkdirAll(uploadDir, 0755) // 确保目录存在
-		newPicPath := filepath.Join(uploadDir, newFile.Filename)
+		newPicPath := storage.Join("uploads", island.BelongTo, island.IsleName, filepath.Base(newFile.Filename))
 
-		if saveErr := c.SaveUploadedFile(newFile, newPicPath); saveErr != nil {
+		if saveErr := saveUploadedFile(h.backend, newFile, newPicPath); saveErr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存新图片失败: " + saveErr.Error()})
 			return
 		}
@@ -268,6 +313,49 @@ func (h *IslandHandler) UpdateIsland(c *gin.Context) {
 		return
 	}
 
+	if newFile != nil {
+		h.thumbs.Regenerate(island.IslePicPath)
+	}
+
 	// 5. 返回成功响应
 	c.JSON(http.StatusOK, gin.H{"message": "岛屿信息更新成功", "data": island})
 }
+
+// GetIslandThumbnail 获取岛屿图片的缩略图
+// GET /api/v1/islands/:isle_id/thumbnail?size=256，返回不小于 size 的最小一档缩略图
+func (h *IslandHandler) GetIslandThumbnail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("isle_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+	island, err := h.store.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "岛屿不存在"})
+		return
+	}
+	if island.IslePicPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "岛屿没有图片"})
+		return
+	}
+	serveThumbnail(c, h.thumbs, h.backend, island.IslePicPath)
+}
+
+// convertFormPair 把表单中以 crs 坐标系提交的一对坐标转换为 WGS84 经纬度 (x 为经度、y 为纬度)
+// 两个字段都没有提交时不做处理；投影坐标无法单独转换，只提交其中一个视为错误
+func convertFormPair(c *gin.Context, crs, xKey, yKey string, x, y *float64) error {
+	_, hasX := c.GetPostForm(xKey)
+	_, hasY := c.GetPostForm(yKey)
+	if !hasX && !hasY {
+		return nil
+	}
+	if hasX != hasY {
+		return fmt.Errorf("指定 crs 时 %s 和 %s 需要同时提交", xKey, yKey)
+	}
+	lon, lat, err := service.ConvertPoint(crs, geo.CRSWGS84, *x, *y)
+	if err != nil {
+		return err
+	}
+	*x, *y = lon, lat
+	return nil
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"Go_for_unity/internal/store"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// JobHandler 负责查询后台任务状态
+type JobHandler struct {
+	store *store.JobStore
+}
+
+func NewJobHandler(s *store.JobStore) *JobHandler {
+	return &JobHandler{store: s}
+}
+
+// GetJob 根据 ID 查询任务的状态和进度
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.store.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// JoinHandler mapping (CSV) 与 shp 图层的属性关联
+type JoinHandler struct {
+	joins   *service.JoinService
+	backend storage.Backend
+}
+
+func NewJoinHandler(joins *service.JoinService, backend storage.Backend) *JoinHandler {
+	return &JoinHandler{joins: joins, backend: backend}
+}
+
+// CreateJoin 声明关联并返回匹配统计，没有任何要素匹配时返回 400
+// 关联跟随两个文件的版本链：上传新版本或回滚后，GeoJSON 和属性表使用当前的生效版本
+// POST /api/v1/joins {"mapping_file_id":1,"shp_file_id":2,"csv_key":"编号","shp_key":"CODE"}
+func (h *JoinHandler) CreateJoin(c *gin.Context) {
+	var req service.JoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	join, err := h.joins.Create(req)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "关联创建成功", "data": join})
+}
+
+// ListJoins 查询岛屿下的关联
+// GET /api/v1/joins?isle_id=1&file_id=2 (file_id 可选，只返回涉及该文件所在版本链的关联)
+func (h *JoinHandler) ListJoins(c *gin.Context) {
+	isleID, err := strconv.ParseUint(c.Query("isle_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的岛屿ID"})
+		return
+	}
+	fileID, _ := strconv.ParseUint(c.Query("file_id"), 10, 64)
+
+	joins, err := h.joins.List(uint(isleID), uint(fileID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询关联失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": joins})
+}
+
+// GetJoin 查询关联及其匹配统计
+// GET /api/v1/joins/:id
+func (h *JoinHandler) GetJoin(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	join, err := h.joins.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关联不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": join})
+}
+
+// DeleteJoin 删除关联
+// DELETE /api/v1/joins/:id
+func (h *JoinHandler) DeleteJoin(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if _, err := h.joins.Get(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关联不存在"})
+		return
+	}
+	if err := h.joins.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除关联失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "关联删除成功"})
+}
+
+// GetJoinGeoJSON 输出带有 CSV 属性的 GeoJSON，参数与 /data-files/:id/geojson 相同 (fields 只筛选 dbf 字段)
+// GET /api/v1/joins/:id/geojson
+func (h *JoinHandler) GetJoinGeoJSON(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	join, err := h.joins.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关联不存在"})
+		return
+	}
+	req, err := parseGeoJSONQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.joins.GeoJSON(join, req)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.Header("Content-Type", "application/geo+json")
+	h.backend.Serve(c.Writer, c.Request, key)
+}
+
+// GetJoinLookup 返回以关键值为索引的关联属性表，Unity 加载 shp 后按关键字段取值着色
+// GET /api/v1/joins/:id/lookup
+func (h *JoinHandler) GetJoinLookup(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	join, err := h.joins.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关联不存在"})
+		return
+	}
+	lookup, err := h.joins.Lookup(join)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": lookup})
+}
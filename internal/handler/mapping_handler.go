@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/store"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// MappingHandler 映射表 (mapping CSV) 的预览接口
+type MappingHandler struct {
+	dfStore *store.DataFileStore
+	mapping *service.MappingService
+}
+
+func NewMappingHandler(dfStore *store.DataFileStore, mapping *service.MappingService) *MappingHandler {
+	return &MappingHandler{dfStore: dfStore, mapping: mapping}
+}
+
+// Preview 分页预览映射表，单元格按上传时推断的列类型返回
+// GET /api/v1/data-files/:id/preview?page=1&pageSize=50
+func (h *MappingHandler) Preview(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	file, err := h.dfStore.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(service.DefaultMappingPageSize)))
+
+	preview, err := h.mapping.Preview(file, page, pageSize)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": preview})
+}
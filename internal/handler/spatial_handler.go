@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 空间查询默认和最大返回条数
+const (
+	defaultSpatialLimit = 100
+	maxSpatialLimit     = 1000
+)
+
+// SpatialHandler 岛屿和数据图层的空间查询
+type SpatialHandler struct {
+	spatial *service.SpatialService
+}
+
+func NewSpatialHandler(spatial *service.SpatialService) *SpatialHandler {
+	return &SpatialHandler{spatial: spatial}
+}
+
+// IslandsNearby 查询某个经纬度周围 radius 米内的岛屿，按距离排序
+// GET /api/v1/spatial/islands/nearby?lat=..&lon=..&radius=..&limit=..
+func (h *SpatialHandler) IslandsNearby(c *gin.Context) {
+	lat, lon, err := parseLatLon(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	radius, err := strconv.ParseFloat(c.Query("radius"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius 必须是以米为单位的数字"})
+		return
+	}
+
+	islands, err := h.spatial.IslandsWithin(lat, lon, radius, parseSpatialLimit(c))
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": islands})
+}
+
+// NearestIsland 查询离某个经纬度最近的岛屿
+// GET /api/v1/spatial/islands/nearest?lat=..&lon=..
+func (h *SpatialHandler) NearestIsland(c *gin.Context) {
+	lat, lon, err := parseLatLon(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	island, err := h.spatial.NearestIsland(lat, lon)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	if island == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有任何岛屿"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": island})
+}
+
+// IslandsInBBox 查询中心点在经纬度范围内的岛屿
+// GET /api/v1/spatial/islands?bbox=minLon,minLat,maxLon,maxLat&limit=..
+func (h *SpatialHandler) IslandsInBBox(c *gin.Context) {
+	bbox, err := parseBBox(c.Query("bbox"))
+	if err != nil || bbox == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bboxError(err)})
+		return
+	}
+
+	islands, err := h.spatial.IslandsInBBox(*bbox, parseSpatialLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询岛屿失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": islands})
+}
+
+// LayersInBBox 查询范围与矩形相交的数据图层
+// GET /api/v1/spatial/layers?bbox=minX,minY,maxX,maxY&data_type=shp&crs=EPSG:4547&limit=..
+// 不传 crs 时 bbox 按经纬度处理，只匹配地理坐标系的图层
+func (h *SpatialHandler) LayersInBBox(c *gin.Context) {
+	bbox, err := parseBBox(c.Query("bbox"))
+	if err != nil || bbox == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bboxError(err)})
+		return
+	}
+
+	files, err := h.spatial.LayersInBBox(*bbox, c.Query("crs"), c.Query("data_type"), parseSpatialLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询图层失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": files})
+}
+
+// --- Helper Functions ---
+
+// parseBBox 解析 minx,miny,maxx,maxy 格式的范围参数，参数为空时返回 nil
+func parseBBox(s string) (*[4]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox 格式应为 minx,miny,maxx,maxy")
+	}
+	var bbox [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox 包含无效数字: %s", p)
+		}
+		bbox[i] = v
+	}
+	if bbox[0] > bbox[2] || bbox[1] > bbox[3] {
+		return nil, fmt.Errorf("bbox 的最小值不能大于最大值")
+	}
+	return &bbox, nil
+}
+
+// bboxError 必填的 bbox 参数缺失或格式错误时的提示
+func bboxError(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "缺少 bbox 参数"
+}
+
+func parseLatLon(c *gin.Context) (float64, float64, error) {
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("lat 必须是数字")
+	}
+	lon, err := strconv.ParseFloat(c.Query("lon"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("lon 必须是数字")
+	}
+	return lat, lon, nil
+}
+
+func parseSpatialLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultSpatialLimit
+	}
+	if limit > maxSpatialLimit {
+		return maxSpatialLimit
+	}
+	return limit
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/storage"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// StorageHandler 通过存储后端对外提供 /uploads 下的文件访问
+type StorageHandler struct {
+	backend storage.Backend
+}
+
+func NewStorageHandler(backend storage.Backend) *StorageHandler {
+	return &StorageHandler{backend: backend}
+}
+
+// ServeUpload 处理 GET /uploads/*filepath
+// 本地存储直接输出文件，S3 存储根据配置重定向到预签名 URL 或由服务端代理
+func (h *StorageHandler) ServeUpload(c *gin.Context) {
+	key := storage.Join("uploads", c.Param("filepath"))
+	// 临时目录 (例如 uploads/.staging、uploads/.sessions) 不对外提供访问
+	if storage.IsHidden(key) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	h.backend.Serve(c.Writer, c.Request, key)
+}
+
+// --- Helper Functions ---
+
+// saveUploadedFile 把表单上传的文件写入存储后端，替代 c.SaveUploadedFile
+func saveUploadedFile(backend storage.Backend, file *multipart.FileHeader, key string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return backend.Put(key, src, file.Size)
+}
+
+// checkUploadedFile 打开表单上传的文件并交给 check 检查内容
+func checkUploadedFile(file *multipart.FileHeader, check func(r io.ReaderAt) error) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return check(src)
+}
+
+// serveThumbnail 按 size 参数 (默认 256) 输出原图的缩略图，缩略图缺失或过期时先生成
+func serveThumbnail(c *gin.Context, thumbs *service.ThumbnailService, backend storage.Backend, srcKey string) {
+	size, err := strconv.Atoi(c.DefaultQuery("size", "256"))
+	if err != nil || size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size 必须是正整数"})
+		return
+	}
+	key, err := thumbs.Get(srcKey, size)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "原图不存在"})
+			return
+		}
+		respondIngestError(c, err)
+		return
+	}
+	c.Header("Content-Type", "image/jpeg")
+	backend.Serve(c.Writer, c.Request, key)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// UploadSessionHandler 负责分片上传 (断点续传) 相关接口
+type UploadSessionHandler struct {
+	service *service.UploadSessionService
+}
+
+func NewUploadSessionHandler(s *service.UploadSessionService) *UploadSessionHandler {
+	return &UploadSessionHandler{service: s}
+}
+
+// CreateSession 1. 创建上传会话
+func (h *UploadSessionHandler) CreateSession(c *gin.Context) {
+	var req service.CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	session, err := h.service.Create(req)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "上传会话创建成功", "data": session})
+}
+
+// GetSession 2. 查询会话状态，返回已收到和缺失的分片，客户端据此续传
+func (h *UploadSessionHandler) GetSession(c *gin.Context) {
+	session, status, err := h.service.Get(c.Param("id"))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": session, "chunks": status})
+}
+
+// UploadChunk 3. 上传单个分片，请求体即为分片的原始字节
+func (h *UploadSessionHandler) UploadChunk(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分片序号"})
+		return
+	}
+
+	if err := h.service.WriteChunk(c.Param("id"), index, c.Request.Body); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "分片上传成功", "index": index})
+}
+
+// CompleteSession 4. 完成上传：提交后台任务合并分片并按文件类型处理
+func (h *UploadSessionHandler) CompleteSession(c *gin.Context) {
+	job, err := h.service.Complete(c.Param("id"))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	// 通过 GET /api/v1/jobs/:id 或 WebSocket 的 job 事件查询处理进度
+	c.JSON(http.StatusAccepted, gin.H{"message": "分片已齐全，正在后台处理", "job_id": job.ID, "data": job})
+}
+
+// AbortSession 5. 取消上传会话
+func (h *UploadSessionHandler) AbortSession(c *gin.Context) {
+	if err := h.service.Abort(c.Param("id")); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "上传会话已取消"})
+}
+
+// respondSessionError 把上传会话相关的错误映射为 HTTP 状态码
+func respondSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSessionClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrQueueFull):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		respondIngestError(c, err)
+	}
+}
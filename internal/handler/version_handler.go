@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// VersionHandler 数据文件的版本历史、差异比较和回滚
+type VersionHandler struct {
+	versions *service.VersionService
+}
+
+func NewVersionHandler(versions *service.VersionService) *VersionHandler {
+	return &VersionHandler{versions: versions}
+}
+
+// ListVersions 查询文件所在版本链的全部版本，id 可以是链中任意一个版本
+// GET /api/v1/data-files/:id/versions
+func (h *VersionHandler) ListVersions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	files, err := h.versions.List(uint(id))
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+
+	// 与文件列表接口一致，将本地路径转换为可访问的URL
+	for i := range files {
+		files[i].DataPath = fmt.Sprintf("http://%s/%s", c.Request.Host, files[i].DataPath)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": files})
+}
+
+// DiffVersions 比较两个版本的元数据，to 默认为生效版本，from 默认为 to 的上一个版本
+// GET /api/v1/data-files/:id/versions/diff?from=1&to=2
+func (h *VersionHandler) DiffVersions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	from, err := parseVersionQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := parseVersionQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := h.versions.Diff(uint(id), from, to)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+// Rollback 把版本链的生效版本切换为指定版本，导出和文件列表随之使用该版本
+// POST /api/v1/data-files/:id/rollback {"version":2}
+func (h *VersionHandler) Rollback(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	var req struct {
+		Version int `json:"version" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	file, err := h.versions.Rollback(uint(id), req.Version)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已回滚到第 %d 个版本", file.Version), "data": file})
+}
+
+// parseVersionQuery 解析可选的版本号参数，未提供时返回 0
+func parseVersionQuery(c *gin.Context, key string) (int, error) {
+	s := c.Query(key)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("%s 应为正整数版本号", key)
+	}
+	return v, nil
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"Go_for_unity/internal/service"
+	"Go_for_unity/internal/store"
+	"Go_for_unity/internal/weather"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WeatherHandler 气象文件的时间序列查询，Unity 回放天气时按需拉取
+type WeatherHandler struct {
+	dfStore *store.DataFileStore
+	weather *service.WeatherService
+}
+
+func NewWeatherHandler(dfStore *store.DataFileStore, weather *service.WeatherService) *WeatherHandler {
+	return &WeatherHandler{dfStore: dfStore, weather: weather}
+}
+
+// GetWindow 查询一段时间内的时间步，from/to 省略时分别取文件的开始和结束时间
+// GET /api/v1/data-files/:id/weather?from=..&to=..&vars=temperature,humidity&limit=500
+// 返回的 next 不为空时，用 from=next 继续查询下一页
+func (h *WeatherHandler) GetWindow(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	file, err := h.dfStore.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
+		return
+	}
+	if file.Weather == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该文件不是已解析的气象文件"})
+		return
+	}
+
+	from, to := file.Weather.Start, file.Weather.End
+	if s := c.Query("from"); s != "" {
+		if from, err = parseQueryTime(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from " + err.Error()})
+			return
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if to, err = parseQueryTime(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to " + err.Error()})
+			return
+		}
+	}
+	limit := 0
+	if s := c.Query("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须是正整数"})
+			return
+		}
+	}
+
+	window, err := h.weather.Window(file, from, to, splitList(c.Query("vars")), limit)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": window})
+}
+
+// GetAt 查询某一时刻的气象数据，不在时间步上时按变量的插值方式插值
+// GET /api/v1/data-files/:id/weather/at?time=2024-05-01T08:30:00%2B08:00&vars=temperature
+func (h *WeatherHandler) GetAt(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	file, err := h.dfStore.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件记录不存在"})
+		return
+	}
+	t, err := parseQueryTime(c.Query("time"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time " + err.Error()})
+		return
+	}
+
+	sample, err := h.weather.At(file, t, splitList(c.Query("vars")))
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sample})
+}
+
+// parseQueryTime 解析查询参数中的时间，没有时区时按服务器所在时区解释
+// 未编码的 "+08:00" 在查询字符串中会变成空格，这里按原意恢复
+func parseQueryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("不能为空")
+	}
+	t, err := weather.ParseTime(s, time.Local)
+	if err == nil {
+		return t, nil
+	}
+	if i := strings.LastIndex(s, " "); i > 0 && strings.Contains(s[:i], "T") {
+		if t, err2 := weather.ParseTime(s[:i]+"+"+s[i+1:], time.Local); err2 == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// splitList 拆分逗号分隔的参数，忽略空项
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,366 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrNoEXIF 图片中没有 EXIF 信息 (非 JPEG 或未写入 APP1 段)
+var ErrNoEXIF = errors.New("图片中没有 EXIF 信息")
+
+// EXIF 照片中与定位和方向相关的 EXIF 标签
+type EXIF struct {
+	Make        string
+	Model       string
+	Orientation int // 1-8，0 表示未记录
+
+	// 拍摄时间：DateTimeOriginal 为相机本地时间，OffsetTimeOriginal 为其时区 (EXIF 2.31)
+	DateTimeOriginal   string
+	OffsetTimeOriginal string
+
+	// GPS 信息，HasGPS 为 false 时其余字段无意义
+	HasGPS     bool
+	Lat        float64    // 纬度，南纬为负
+	Lon        float64    // 经度，西经为负
+	Altitude   *float64   // 海拔 (米)，海平面以下为负
+	Heading    *float64   // 拍摄方向 (度，0-360)
+	HeadingRef string     // T 真北 / M 磁北
+	GPSTime    *time.Time // GPSDateStamp + GPSTimeStamp，UTC
+}
+
+// CaptureTime 返回拍摄时间：优先使用带时区的 DateTimeOriginal，其次使用 GPS 时间 (UTC)，
+// 都没有时把 DateTimeOriginal 按 loc 解释；无法解析时返回 nil
+func (e *EXIF) CaptureTime(loc *time.Location) *time.Time {
+	const layout = "2006:01:02 15:04:05"
+	if e.DateTimeOriginal != "" && e.OffsetTimeOriginal != "" {
+		if t, err := time.Parse(layout+"-07:00", e.DateTimeOriginal+e.OffsetTimeOriginal); err == nil {
+			return &t
+		}
+	}
+	if e.GPSTime != nil {
+		return e.GPSTime
+	}
+	if e.DateTimeOriginal != "" {
+		if t, err := time.ParseInLocation(layout, e.DateTimeOriginal, loc); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// 使用到的 TIFF 标签
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetTimeOrig   = 0x9011
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+	tagGPSAltitudeRef   = 0x0005
+	tagGPSAltitude      = 0x0006
+	tagGPSTimeStamp     = 0x0007
+	tagGPSImgDirRef     = 0x0010
+	tagGPSImgDirection  = 0x0011
+	tagGPSDateStamp     = 0x001d
+	maxIFDEntries       = 1000 // 单个 IFD 条目数上限，防止损坏数据导致大量分配
+)
+
+// ReadEXIF 从 JPEG 数据流中读取 EXIF，只读取到第一个 APP1 Exif 段为止，不会读取图像数据
+func ReadEXIF(r io.Reader) (*EXIF, error) {
+	br := bufio.NewReader(r)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return nil, ErrNoEXIF
+	}
+
+	for {
+		// 1. 读取段标记，允许标记前有填充的 0xff
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, ErrNoEXIF
+		}
+		if b != 0xff {
+			return nil, fmt.Errorf("JPEG 段标记无效: 0x%02x", b)
+		}
+		marker, err := br.ReadByte()
+		for err == nil && marker == 0xff {
+			marker, err = br.ReadByte()
+		}
+		if err != nil {
+			return nil, ErrNoEXIF
+		}
+		// 没有长度的独立标记
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			continue
+		}
+		// 图像数据开始或结束，之后不会再有 EXIF
+		if marker == 0xda || marker == 0xd9 {
+			return nil, ErrNoEXIF
+		}
+
+		// 2. 读取段长度 (包含长度字段本身的 2 字节)
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(br, lenBuf[:]); err != nil {
+			return nil, ErrNoEXIF
+		}
+		length := int(binary.BigEndian.Uint16(lenBuf[:])) - 2
+		if length < 0 {
+			return nil, fmt.Errorf("JPEG 段长度无效")
+		}
+		if marker != 0xe1 {
+			if _, err := br.Discard(length); err != nil {
+				return nil, ErrNoEXIF
+			}
+			continue
+		}
+
+		// 3. APP1 段：Exif 标识后是完整的 TIFF 结构 (XMP 也使用 APP1，跳过)
+		seg := make([]byte, length)
+		if _, err := io.ReadFull(br, seg); err != nil {
+			return nil, ErrNoEXIF
+		}
+		if !bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			continue
+		}
+		return parseTIFF(seg[6:])
+	}
+}
+
+// tiff 解析 EXIF 中的 TIFF 结构，所有偏移相对于 TIFF 头
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// typeSizes TIFF 各数据类型的字节数，下标为类型编号
+var typeSizes = [...]uint32{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+func parseTIFF(data []byte) (*EXIF, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("EXIF 数据过短")
+	}
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("EXIF 字节序标识无效")
+	}
+	if t.order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("EXIF TIFF 标识无效")
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	e := &EXIF{}
+	var exifOff, gpsOff uint32
+	for _, entry := range ifd0 {
+		switch entry.tag {
+		case tagMake:
+			e.Make = entry.ascii()
+		case tagModel:
+			e.Model = entry.ascii()
+		case tagOrientation:
+			if v, ok := t.uint(entry, 0); ok && v >= 1 && v <= 8 {
+				e.Orientation = int(v)
+			}
+		case tagExifIFD:
+			exifOff, _ = t.uint(entry, 0)
+		case tagGPSIFD:
+			gpsOff, _ = t.uint(entry, 0)
+		}
+	}
+
+	// 子 IFD 损坏时保留已经读到的信息
+	if exifOff > 0 {
+		if entries, err := t.readIFD(exifOff); err == nil {
+			for _, entry := range entries {
+				switch entry.tag {
+				case tagDateTimeOriginal:
+					e.DateTimeOriginal = entry.ascii()
+				case tagOffsetTimeOrig:
+					e.OffsetTimeOriginal = entry.ascii()
+				}
+			}
+		}
+	}
+	if gpsOff > 0 {
+		if entries, err := t.readIFD(gpsOff); err == nil {
+			t.readGPS(e, entries)
+		}
+	}
+	return e, nil
+}
+
+// readGPS 解析 GPS IFD，经纬度缺失或超出范围时 HasGPS 为 false
+func (t *tiff) readGPS(e *EXIF, entries []ifdEntry) {
+	var latRef, lonRef, dateStamp string
+	var lat, lon, timeStamp []float64
+	altRef := uint32(0)
+	for _, entry := range entries {
+		switch entry.tag {
+		case tagGPSLatitudeRef:
+			latRef = entry.ascii()
+		case tagGPSLatitude:
+			lat = t.rationals(entry)
+		case tagGPSLongitudeRef:
+			lonRef = entry.ascii()
+		case tagGPSLongitude:
+			lon = t.rationals(entry)
+		case tagGPSAltitudeRef:
+			altRef, _ = t.uint(entry, 0)
+		case tagGPSAltitude:
+			if v := t.rationals(entry); len(v) == 1 {
+				alt := v[0]
+				e.Altitude = &alt
+			}
+		case tagGPSTimeStamp:
+			timeStamp = t.rationals(entry)
+		case tagGPSImgDirRef:
+			e.HeadingRef = entry.ascii()
+		case tagGPSImgDirection:
+			if v := t.rationals(entry); len(v) == 1 && v[0] >= 0 && v[0] <= 360 {
+				heading := math.Mod(v[0], 360)
+				e.Heading = &heading
+			}
+		case tagGPSDateStamp:
+			dateStamp = entry.ascii()
+		}
+	}
+	if e.Altitude != nil && altRef == 1 {
+		*e.Altitude = -*e.Altitude
+	}
+
+	if len(lat) == 3 && len(lon) == 3 {
+		e.Lat = dms(lat)
+		e.Lon = dms(lon)
+		if strings.EqualFold(latRef, "S") {
+			e.Lat = -e.Lat
+		}
+		if strings.EqualFold(lonRef, "W") {
+			e.Lon = -e.Lon
+		}
+		// 部分设备在没有定位时写入 0,0
+		e.HasGPS = e.Lat >= -90 && e.Lat <= 90 && e.Lon >= -180 && e.Lon <= 180 &&
+			!(e.Lat == 0 && e.Lon == 0)
+	}
+
+	if dateStamp != "" && len(timeStamp) == 3 {
+		if day, err := time.Parse("2006:01:02", dateStamp); err == nil {
+			seconds := timeStamp[0]*3600 + timeStamp[1]*60 + timeStamp[2]
+			t := day.Add(time.Duration(seconds * float64(time.Second)))
+			e.GPSTime = &t
+		}
+	}
+}
+
+// dms 度分秒转换为十进制度
+func dms(v []float64) float64 {
+	return v[0] + v[1]/60 + v[2]/3600
+}
+
+// readIFD 读取 off 处的一个 IFD，值不超过 4 字节时保存在条目内，否则按偏移读取
+func (t *tiff) readIFD(off uint32) ([]ifdEntry, error) {
+	if uint64(off)+2 > uint64(len(t.data)) {
+		return nil, fmt.Errorf("EXIF IFD 偏移越界")
+	}
+	n := int(t.order.Uint16(t.data[off:]))
+	if n > maxIFDEntries || uint64(off)+2+uint64(n)*12 > uint64(len(t.data)) {
+		return nil, fmt.Errorf("EXIF IFD 条目越界")
+	}
+
+	entries := make([]ifdEntry, 0, n)
+	for i := 0; i < n; i++ {
+		p := t.data[int(off)+2+i*12:]
+		entry := ifdEntry{
+			tag:   t.order.Uint16(p),
+			typ:   t.order.Uint16(p[2:]),
+			count: t.order.Uint32(p[4:]),
+		}
+		if int(entry.typ) >= len(typeSizes) || typeSizes[entry.typ] == 0 {
+			continue // 未知类型，跳过
+		}
+		size := uint64(typeSizes[entry.typ]) * uint64(entry.count)
+		if size <= 4 {
+			entry.value = p[8 : 8+size]
+		} else {
+			valueOff := uint64(t.order.Uint32(p[8:]))
+			if valueOff+size > uint64(len(t.data)) {
+				continue // 值越界，跳过该条目
+			}
+			entry.value = t.data[valueOff : valueOff+size]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// uint 读取 BYTE/SHORT/LONG 类型条目的第 i 个值
+func (t *tiff) uint(e ifdEntry, i int) (uint32, bool) {
+	switch e.typ {
+	case 1, 7:
+		if i < len(e.value) {
+			return uint32(e.value[i]), true
+		}
+	case 3:
+		if (i+1)*2 <= len(e.value) {
+			return uint32(t.order.Uint16(e.value[i*2:])), true
+		}
+	case 4:
+		if (i+1)*4 <= len(e.value) {
+			return t.order.Uint32(e.value[i*4:]), true
+		}
+	}
+	return 0, false
+}
+
+// rationals 读取 RATIONAL/SRATIONAL 类型条目的全部值，分母为 0 时返回 nil
+func (t *tiff) rationals(e ifdEntry) []float64 {
+	if e.typ != 5 && e.typ != 10 {
+		return nil
+	}
+	out := make([]float64, 0, len(e.value)/8)
+	for i := 0; i+8 <= len(e.value); i += 8 {
+		num, den := t.order.Uint32(e.value[i:]), t.order.Uint32(e.value[i+4:])
+		if den == 0 {
+			return nil
+		}
+		if e.typ == 10 {
+			out = append(out, float64(int32(num))/float64(int32(den)))
+		} else {
+			out = append(out, float64(num)/float64(den))
+		}
+	}
+	return out
+}
+
+// ascii 读取 ASCII 条目，去掉结尾的 NUL 和空白
+func (e ifdEntry) ascii() string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
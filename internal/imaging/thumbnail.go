@@ -0,0 +1,158 @@
+// Package imaging 生成图片缩略图，读取照片的 EXIF 拍摄信息
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif" // 注册 GIF 解码器
+	_ "image/png" // 注册 PNG 解码器
+)
+
+// ThumbnailQuality 缩略图的 JPEG 质量
+const ThumbnailQuality = 85
+
+// ErrTooLarge 图片的像素数超过上限
+var ErrTooLarge = errors.New("图片尺寸过大")
+
+// Decode 解码 JPEG/PNG/GIF 图片
+// maxPixels 大于 0 时先读取文件头中的尺寸，宽×高超过上限时返回 ErrTooLarge 而不解码，避免按伪造的尺寸分配内存
+func Decode(r io.Reader, maxPixels int64) (image.Image, error) {
+	if maxPixels > 0 {
+		var head bytes.Buffer
+		if err := CheckSize(io.TeeReader(r, &head), maxPixels); err != nil {
+			return nil, err
+		}
+		r = io.MultiReader(&head, r)
+	}
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// CheckSize 只读取文件头，检查图片的宽×高是否超过 maxPixels
+func CheckSize(r io.Reader, maxPixels int64) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return fmt.Errorf("%w: %dx%d，上限为 %d 像素", ErrTooLarge, cfg.Width, cfg.Height, maxPixels)
+	}
+	return nil
+}
+
+// EncodeJPEG 以缩略图质量编码为 JPEG
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: ThumbnailQuality})
+}
+
+// Fit 把图片等比缩小到长边不超过 maxSize，不会放大
+// 使用区域平均 (box filter)，透明像素按白色背景合成，结果可以直接编码为 JPEG
+func Fit(src image.Image, maxSize int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw > maxSize || sh > maxSize {
+		if sw >= sh {
+			dw, dh = maxSize, max(1, sh*maxSize/sw)
+		} else {
+			dw, dh = max(1, sw*maxSize/sh), maxSize
+		}
+	}
+
+	// 每个目标像素累加落在其范围内的源像素
+	sums := make([]uint64, dw*dh*3)
+	counts := make([]uint32, dw*dh)
+	xmap := make([]int, sw)
+	for x := 0; x < sw; x++ {
+		xmap[x] = x * dw / sw
+	}
+	for y := 0; y < sh; y++ {
+		row := (y * dh / sh) * dw
+		for x := 0; x < sw; x++ {
+			r, g, bl := rgbAt(src, b.Min.X+x, b.Min.Y+y)
+			i := row + xmap[x]
+			sums[i*3] += uint64(r)
+			sums[i*3+1] += uint64(g)
+			sums[i*3+2] += uint64(bl)
+			counts[i]++
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		dst.Pix[i*4] = uint8(sums[i*3] / uint64(n))
+		dst.Pix[i*4+1] = uint8(sums[i*3+1] / uint64(n))
+		dst.Pix[i*4+2] = uint8(sums[i*3+2] / uint64(n))
+		dst.Pix[i*4+3] = 0xff
+	}
+	return dst
+}
+
+// Orient 按 EXIF Orientation (1-8) 翻转或旋转图片，使其按拍摄时的方向显示
+func Orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // 5-8 需要交换宽高
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = h-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// rgbAt 读取像素并与白色背景合成，常见的图片类型走快速路径
+func rgbAt(img image.Image, x, y int) (uint8, uint8, uint8) {
+	switch m := img.(type) {
+	case *image.YCbCr:
+		yi, ci := m.YOffset(x, y), m.COffset(x, y)
+		return color.YCbCrToRGB(m.Y[yi], m.Cb[ci], m.Cr[ci])
+	case *image.Gray:
+		v := m.Pix[m.PixOffset(x, y)]
+		return v, v, v
+	case *image.RGBA:
+		i := m.PixOffset(x, y)
+		return overWhite(m.Pix[i], m.Pix[i+3]), overWhite(m.Pix[i+1], m.Pix[i+3]), overWhite(m.Pix[i+2], m.Pix[i+3])
+	}
+	r, g, b, a := img.At(x, y).RGBA()
+	// RGBA() 返回预乘 alpha 的 16 位分量
+	white := 0xffff - a
+	return uint8((r + white) >> 8), uint8((g + white) >> 8), uint8((b + white) >> 8)
+}
+
+// overWhite 预乘 alpha 的分量与白色背景合成
+func overWhite(c, a uint8) uint8 {
+	return c + (0xff - a)
+}
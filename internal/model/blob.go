@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Blob 内容寻址存储中的一份内容，以上传文件的 SHA-256 和保存方式 (原样保存或解压) 确定主键
+// 相同内容以相同方式保存的文件只存储一次，DataFile 通过 BlobHash 引用，RefCount 记录引用数
+type Blob struct {
+	Hash      string    `gorm:"type:char(64);primaryKey" json:"hash"`  // 主键，按 SHA-256 和保存方式计算；早期记录直接使用 SHA-256
+	SHA256    string    `gorm:"type:char(64)" json:"sha256"`           // 上传文件 (zip 包或单个文件) 的 SHA-256，早期记录为空 (即 Hash)
+	Size      int64     `json:"size"`                                  // 上传文件的字节数
+	Dir       string    `gorm:"type:varchar(512);not null" json:"dir"` // 内容在存储后端中的前缀: uploads/blobs/<前两位>/<hash>
+	Entry     string    `gorm:"type:varchar(255)" json:"entry"`        // 单文件类型的文件名，压缩包类型为空 (内容即解压后的目录)
+	RefCount  int       `gorm:"not null;default:0" json:"ref_count"`   // 引用该内容的 DataFile 数量
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Blob) TableName() string {
+	return "blobs"
+}
@@ -10,6 +10,28 @@ type DataFile struct {
 	DataPath   string  `gorm:"type:varchar(512);not null"`      // 文件在服务器上的存储路径
 	IsleID     uint    `gorm:"not null;index"`                  // 外键，关联到 isles 表的 ID
 	Height     float64 // 高度值，仅用于 shp 和 tif
+	BlobHash   string  `gorm:"type:char(64);index"` // 引用的内容记录 (Blob) 主键，为空表示旧版按岛屿目录存储的文件
+	SHA256     string  `gorm:"type:char(64)"`       // 单文件类型下载内容的 SHA-256，压缩包类型为空；旧记录在首次下载时补齐
+
+	// 版本链：同一岛屿下类型和名称相同的文件再次上传时生成新版本，旧版本保留
+	LineageID uint `gorm:"index"`                       // 版本链 ID，等于第一个版本的记录 ID
+	Version   int  `gorm:"not null;default:1"`          // 版本号，从 1 开始
+	Active    bool `gorm:"not null;default:true;index"` // 是否为生效版本，列表、导出和空间查询只返回生效版本
+
+	// 上传时解析出的元数据
+	BBox      BBox             `gorm:"embedded;embeddedPrefix:bbox_"` // 数据范围，坐标系见 CRS
+	CRS       string           `gorm:"type:varchar(255)"`             // 坐标系，能识别时为 EPSG:xxxx，否则为名称
+	Shapefile *ShapefileMeta   `gorm:"type:text;serializer:json"`     // shp 的几何类型、要素数量、属性字段等
+	Raster    *RasterIndexMeta `gorm:"type:text;serializer:json"`     // tif 索引的格式、层级和瓦片完整性
+	Mesh      *ModelMeta       `gorm:"type:text;serializer:json"`     // glTF/GLB 模型的网格、顶点、材质统计
+	Photo     *PhotoMeta       `gorm:"type:text;serializer:json"`     // jpg 照片 EXIF 中的位置、方向和拍摄时间
+	Weather   *WeatherMeta     `gorm:"type:text;serializer:json"`     // weather 文件的变量和时间范围
+	Mapping   *MappingMeta     `gorm:"type:text;serializer:json"`     // mapping 文件的编码、分隔符和列类型
+
+	// 显示样式：绘制顺序、默认可见性、颜色等，为 nil 时使用默认样式 (见 LayerStyle)
+	Style *LayerStyle `gorm:"type:text;serializer:json"`
+
+	WeatherSteps []WeatherStep `gorm:"foreignKey:DataFileID" json:"-"` // weather 文件的时间步，仅在创建记录时随之写入
 
 	Island Island `gorm:"foreignKey:IsleID" json:"-"` // 定义与 Island 的关联,用json:"-"告诉 JSON 序列化器忽略这个字段。
 }
@@ -12,12 +12,14 @@ type Island struct {
 	IsleName        string  `gorm:"type:varchar(255);not null;unique"` // 岛屿名，设为唯一
 	IsleDesc        string  `gorm:"type:text"`                         // 岛屿描述
 	BelongTo        string  `gorm:"type:varchar(255);not null;index"`  // 所属用户, 建立索引方便查询
-	CenterX         float64 // 中心点坐标X
-	CenterY         float64 // 中心点坐标Y
+	CenterX         float64 // 中心点坐标X (经度)
+	CenterY         float64 // 中心点坐标Y (纬度)
+	GridCell        int     `gorm:"not null;default:-1;index" json:"-"` // 中心点所在的 1° 网格编号，不支持空间索引的数据库上用于空间查询
 	CameraX         float64 // 默认相机位置X
 	CameraY         float64 // 默认相机位置Y
 	CameraZ         float64 // 默认相机位置Z
 	IslePicPath     string  `gorm:"type:varchar(512)"`       // 岛屿图片存储路径
+	IsleThumbURL    string  `gorm:"-"`                       // 缩略图地址，只在列表接口中填充，使用时追加 ?size=256
 	ArchipelagoName string  `gorm:"type:varchar(255);index"` // 群岛名称，加上索引方便查询
 	Country         string  `gorm:"type:varchar(255);index"` // 所属国家，也加上索引
 	MoveSpeed       float64 `gorm:"default:0.7"`             // 相机移动速度为新字段设置了 default 值。这样，即使在创建时没有提供这些参数，数据库中也会有合理的默认值。
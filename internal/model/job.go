@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// 后台任务的状态，依次经历 queued -> extracting -> indexing -> ready/failed
+const (
+	JobStatusQueued     = "queued"     // 已排队，等待 worker 处理
+	JobStatusExtracting = "extracting" // 正在保存/解压文件
+	JobStatusIndexing   = "indexing"   // 正在查找索引文件并校验
+	JobStatusReady      = "ready"      // 处理完成，DataFileID 指向生成的记录
+	JobStatusFailed     = "failed"     // 处理失败，Error 中记录原因
+)
+
+// Job 记录一个后台处理任务 (例如上传文件的解压与校验)
+type Job struct {
+	ID         string       `gorm:"type:varchar(64);primaryKey" json:"id"`
+	Kind       string       `gorm:"type:varchar(50);not null" json:"kind"` // 任务类型，例如 upload
+	Status     string       `gorm:"type:varchar(20);not null;index" json:"status"`
+	Progress   float64      `json:"progress"` // 进度百分比 0-100
+	IsleID     uint         `gorm:"index" json:"isle_id"`
+	DataType   string       `gorm:"type:varchar(50)" json:"data_type"`
+	FileName   string       `gorm:"type:varchar(255)" json:"file_name"`
+	DataFileID uint         `json:"data_file_id"`                                            // 成功后生成的 DataFile ID
+	Error      string       `gorm:"type:text" json:"error"`                                  // 失败原因
+	Report     *BatchReport `gorm:"type:mediumtext;serializer:json" json:"report,omitempty"` // 批量导入任务的逐项结果
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// 批量导入中单个文件的处理结果
+const (
+	BatchItemPending    = "pending"     // 等待处理
+	BatchItemCreated    = "created"     // 已创建 DataFile
+	BatchItemFailed     = "failed"      // 处理失败，整个批次随之回滚
+	BatchItemRolledBack = "rolled_back" // 已创建，但因其他文件失败被撤销
+	BatchItemSkipped    = "skipped"     // 前面的文件失败，未处理
+	BatchItemLeftBehind = "left_behind" // 已创建，撤销失败仍保留在岛屿中
+)
+
+// BatchItem 批量导入压缩包中的一个文件 (或一个 shp/tif 文件夹)
+type BatchItem struct {
+	Path       string  `json:"path"` // 在压缩包中的路径，例如 shp/roads
+	DataType   string  `json:"data_type"`
+	DataName   string  `json:"data_name"`
+	Height     float64 `json:"height"`
+	Status     string  `json:"status"`
+	DataFileID uint    `json:"data_file_id,omitempty"`
+	Version    int     `json:"version,omitempty"` // 岛屿中已有同名文件时为新建的版本号
+	Error      string  `json:"error,omitempty"`
+}
+
+// BatchReport 批量导入任务的逐项结果
+type BatchReport struct {
+	Total           int         `json:"total"`
+	Created         int         `json:"created"`
+	PartialRollback bool        `json:"partial_rollback,omitempty"` // 撤销失败，Created 个条目 (状态为 left_behind) 仍保留在岛屿中
+	Items           []BatchItem `json:"items"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// IsFinished 判断任务是否已经结束
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusReady || j.Status == JobStatusFailed
+}
@@ -0,0 +1,26 @@
+package model
+
+// LayerStyle 图层在 Unity 场景中的显示方式，随导出 JSON 下发，保证各工作站看到的场景一致
+// 样式属于整个版本链：上传新版本时沿用，回滚版本时不变
+type LayerStyle struct {
+	DrawOrder   int     `json:"drawOrder"`             // 绘制顺序，小的先画 (在下层)，导出时按它排序
+	Visible     bool    `json:"visible"`               // 打开场景时是否显示
+	Opacity     float64 `json:"opacity"`               // 不透明度 0~1
+	FillColor   string  `json:"fillColor,omitempty"`   // 填充色 #RRGGBB 或 #RRGGBBAA，仅 shp
+	StrokeColor string  `json:"strokeColor,omitempty"` // 描边色 #RRGGBB 或 #RRGGBBAA，仅 shp
+	LineWidth   float64 `json:"lineWidth"`             // 线宽 (像素)，仅 shp
+	LabelField  string  `json:"labelField,omitempty"`  // 作为标注显示的属性字段，仅 shp
+}
+
+// DefaultLayerStyle 未设置样式的图层使用的默认值
+func DefaultLayerStyle() LayerStyle {
+	return LayerStyle{Visible: true, Opacity: 1, LineWidth: 1}
+}
+
+// LayerStyle 返回文件的显示样式，未设置时为默认样式
+func (f *DataFile) LayerStyle() LayerStyle {
+	if f.Style == nil {
+		return DefaultLayerStyle()
+	}
+	return *f.Style
+}
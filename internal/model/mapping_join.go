@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// MappingJoin 把 mapping (CSV) 文件的属性按关键字段关联到 shp 图层的要素上
+// 关联属于两个版本链：上传新版本或回滚后，读取时使用各自当前的生效版本；版本链整个删除时关联一并删除
+// 创建时统计一次匹配情况，统计对应的是 MappingFileID、ShpFileID 这两个版本
+type MappingJoin struct {
+	ID               uint   `gorm:"primaryKey" json:"id"`
+	IsleID           uint   `gorm:"not null;index" json:"isle_id"`
+	MappingLineageID uint   `gorm:"index" json:"mapping_lineage_id"`           // CSV 文件的版本链
+	ShpLineageID     uint   `gorm:"index" json:"shp_lineage_id"`               // shp 文件的版本链
+	MappingFileID    uint   `gorm:"not null;index" json:"mapping_file_id"`     // 创建关联时 CSV 的生效版本
+	ShpFileID        uint   `gorm:"not null;index" json:"shp_file_id"`         // 创建关联时 shp 的生效版本
+	CSVKey           string `gorm:"type:varchar(255);not null" json:"csv_key"` // CSV 中的关键列
+	ShpKey           string `gorm:"type:varchar(255);not null" json:"shp_key"` // dbf 中的关键字段
+
+	// 匹配统计
+	FeatureCount      int         `json:"feature_count"`      // shp 要素数量 (不含已删除的记录)
+	RowCount          int         `json:"row_count"`          // CSV 数据行数
+	MatchedFeatures   int         `json:"matched_features"`   // 找到 CSV 行的要素数量
+	UnmatchedFeatures int         `json:"unmatched_features"` // 没有对应 CSV 行的要素数量
+	UnmatchedRows     int         `json:"unmatched_rows"`     // 没有对应要素的 CSV 行数
+	DuplicateKeys     int         `json:"duplicate_keys"`     // CSV 中重复的关键值数量，重复时取第一行
+	Samples           JoinSamples `gorm:"type:text;serializer:json" json:"samples"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (MappingJoin) TableName() string {
+	return "mapping_joins"
+}
+
+// JoinSamples 未匹配和重复的关键值示例，便于排查关键字段是否选错
+type JoinSamples struct {
+	UnmatchedFeatureKeys []string `json:"unmatched_feature_keys,omitempty"`
+	UnmatchedRowKeys     []string `json:"unmatched_row_keys,omitempty"`
+	DuplicateKeys        []string `json:"duplicate_keys,omitempty"`
+}
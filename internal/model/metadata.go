@@ -0,0 +1,125 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BBox 数据的外包矩形，坐标与 DataFile.CRS 所述坐标系一致
+// Valid 为 false 表示尚未解析出范围，JSON 中输出为 null
+// 嵌入表中时 MinY/MinX 组成联合索引，范围相交查询先按它们缩小扫描范围
+type BBox struct {
+	MinX  float64 `json:"minX" gorm:"index:,composite:bbox_min,priority:2"`
+	MinY  float64 `json:"minY" gorm:"index:,composite:bbox_min,priority:1"`
+	MaxX  float64 `json:"maxX"`
+	MaxY  float64 `json:"maxY"`
+	Valid bool    `json:"-"`
+}
+
+func (b BBox) MarshalJSON() ([]byte, error) {
+	if !b.Valid {
+		return []byte("null"), nil
+	}
+	type plain BBox
+	return json.Marshal(plain(b))
+}
+
+// AttributeField 矢量数据的一个属性字段
+type AttributeField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`     // string / number / float / bool / date / memo
+	Length   int    `json:"length"`   // 字段宽度
+	Decimals int    `json:"decimals"` // 小数位数
+}
+
+// ShapefileMeta 上传 shp 时从 .shp/.dbf/.prj 中解析出的元数据
+type ShapefileMeta struct {
+	GeometryType string           `json:"geometryType"` // 几何类型，例如 Polygon、PolyLineZ
+	FeatureCount int              `json:"featureCount"` // 要素数量
+	Fields       []AttributeField `json:"fields"`       // 属性字段
+	Encoding     string           `json:"encoding"`     // 属性表编码 (.cpg 内容或推断结果)
+	CRSName      string           `json:"crsName"`      // .prj 中的坐标系名称
+	EPSG         int              `json:"epsg"`         // 识别出的 EPSG 代码，无法识别时为 0
+	Geographic   bool             `json:"geographic"`   // 是否为经纬度坐标
+	WKT          string           `json:"wkt"`          // .prj 原文
+}
+
+// RasterIndexMeta 上传 tif (影像瓦片或 3D Tiles) 时从索引文件中解析出的信息
+type RasterIndexMeta struct {
+	Format       string   `json:"format"`             // 3dtiles / tms / xml
+	Version      string   `json:"version"`            // 索引声明的版本
+	Levels       int      `json:"levels"`             // 层级数：3D Tiles 为瓦片树深度，TMS 为 TileSet 数量
+	TileCount    int      `json:"tileCount"`          // 索引引用的瓦片内容数量
+	MissingCount int      `json:"missingCount"`       // 压缩包中缺失的瓦片数量
+	Missing      []string `json:"missing,omitempty"`  // 缺失瓦片的前若干个路径
+	Warnings     []string `json:"warnings,omitempty"` // 不影响加载的问题
+}
+
+// ModelMeta 上传模型时解析出的统计信息，Unity 据此预估加载开销
+type ModelMeta struct {
+	Format         string      `json:"format"`               // gltf / glb / obj / fbx
+	Generator      string      `json:"generator,omitempty"`  // 导出工具
+	MeshCount      int         `json:"meshCount"`            // 网格数量
+	PrimitiveCount int         `json:"primitiveCount"`       // 图元 (子网格) 数量
+	VertexCount    int64       `json:"vertexCount"`          // 顶点数
+	TriangleCount  int64       `json:"triangleCount"`        // 三角形数
+	MaterialCount  int         `json:"materialCount"`        // 材质数量
+	TextureCount   int         `json:"textureCount"`         // 纹理数量
+	BoundsMin      *[3]float64 `json:"boundsMin"`            // 包围盒最小点，无法计算时为 null
+	BoundsMax      *[3]float64 `json:"boundsMax"`            // 包围盒最大点
+	UpAxis         string      `json:"upAxis"`               // 原始模型向上的轴 (Y / Z)
+	Extensions     []string    `json:"extensions,omitempty"` // 使用的 glTF 扩展
+
+	// 模型压缩包中的贴图
+	Textures        []string `json:"textures,omitempty"`        // 引用的贴图 (相对压缩包根目录)
+	MissingTextures []string `json:"missingTextures,omitempty"` // 缺失的贴图，只有 FBX 允许缺失 (贴图可能已内嵌)
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// PhotoMeta 上传 jpg 时从 EXIF 中读取的拍摄信息，Unity 据此把照片作为广告牌放到场景中
+type PhotoMeta struct {
+	HasGPS      bool       `json:"hasGps"`               // 是否带有有效的 GPS 位置
+	Lat         float64    `json:"lat"`                  // WGS84 纬度
+	Lon         float64    `json:"lon"`                  // WGS84 经度
+	Altitude    *float64   `json:"altitude"`             // 海拔 (米)，未记录时为 null
+	Heading     *float64   `json:"heading"`              // 拍摄方向 (度，以北为 0 顺时针)，未记录时为 null
+	HeadingRef  string     `json:"headingRef,omitempty"` // T 真北 / M 磁北
+	CapturedAt  *time.Time `json:"capturedAt"`           // 拍摄时间
+	Orientation int        `json:"orientation"`          // EXIF 方向 (1-8)，0 表示未记录
+	Camera      string     `json:"camera,omitempty"`     // 相机厂商和型号
+	Warnings    []string   `json:"warnings,omitempty"`
+}
+
+// WeatherVariable 气象文件中的一个变量
+type WeatherVariable struct {
+	Name   string `json:"name"`
+	Unit   string `json:"unit,omitempty"`
+	Interp string `json:"interp"` // 插值方式: linear / angle / step
+}
+
+// WeatherMeta 上传 weather 文件时解析出的时间序列信息，时间步保存在 weather_steps 表中
+type WeatherMeta struct {
+	Format          string            `json:"format"` // json / csv
+	Variables       []WeatherVariable `json:"variables"`
+	StepCount       int               `json:"stepCount"`
+	Start           time.Time         `json:"start"`
+	End             time.Time         `json:"end"`
+	IntervalSeconds float64           `json:"intervalSeconds"` // 时间步间隔，间隔不固定时为 0
+}
+
+// MappingColumn 映射表的一列
+type MappingColumn struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"` // integer / number / boolean / datetime / string
+	Nullable bool     `json:"nullable"`
+	Min      *float64 `json:"min,omitempty"` // 数值列的最小值
+	Max      *float64 `json:"max,omitempty"` // 数值列的最大值
+}
+
+// MappingMeta 上传 mapping (CSV) 文件时识别出的编码、分隔符和表头结构
+type MappingMeta struct {
+	Encoding  string          `json:"encoding"`  // UTF-8 / GBK
+	Delimiter string          `json:"delimiter"` // , ; \t |
+	RowCount  int             `json:"rowCount"`  // 数据行数 (不含表头)
+	Columns   []MappingColumn `json:"columns"`
+}
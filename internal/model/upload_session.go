@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// 分片上传会话的状态
+const (
+	UploadStatusUploading  = "uploading"  // 正在接收分片
+	UploadStatusCompleting = "completing" // 已提交后台任务，正在合并分片并处理
+	UploadStatusCompleted  = "completed"  // 已完成，DataFileID 指向生成的记录
+)
+
+// UploadSession 记录一次可断点续传的分片上传
+// 已收到的分片以磁盘上的分片文件为准，不在数据库中逐个记录
+type UploadSession struct {
+	ID          string    `gorm:"type:varchar(64);primaryKey" json:"id"`
+	IsleID      uint      `gorm:"not null;index" json:"isle_id"`
+	DataType    string    `gorm:"type:varchar(50);not null" json:"data_type"`
+	Height      float64   `json:"height"`
+	FileName    string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	TotalSize   int64     `gorm:"not null" json:"total_size"`                    // 文件总字节数
+	ChunkSize   int64     `gorm:"not null" json:"chunk_size"`                    // 每个分片的字节数 (最后一片可以更小)
+	TotalChunks int       `gorm:"not null" json:"total_chunks"`                  // 分片总数
+	Status      string    `gorm:"type:varchar(20);not null;index" json:"status"` // uploading / completing / completed
+	JobID       string    `gorm:"type:varchar(64)" json:"job_id"`                // 完成时提交的后台处理任务
+	DataFileID  uint      `json:"data_file_id"`                                  // 完成后生成的 DataFile ID
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`                       // 超过该时间仍未完成的会话会被清理
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// WeatherStep 气象文件的一个时间步
+// 按 (DataFileID, Time) 建立唯一索引，查询时间段或插值时只读取需要的时间步，不必解析整个文件
+type WeatherStep struct {
+	ID         uint                `gorm:"primaryKey" json:"-"`
+	DataFileID uint                `gorm:"not null;uniqueIndex:idx_weather_steps_file_time,priority:1" json:"-"`
+	Time       time.Time           `gorm:"not null;uniqueIndex:idx_weather_steps_file_time,priority:2" json:"time"`
+	Values     map[string]*float64 `gorm:"type:text;serializer:json" json:"values"` // 变量名 -> 数值，null 表示缺测
+}
+
+func (WeatherStep) TableName() string {
+	return "weather_steps"
+}
@@ -0,0 +1,102 @@
+package model3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"regexp"
+)
+
+// fbxBinaryMagic 二进制 FBX 的文件头
+var fbxBinaryMagic = []byte("Kaydara FBX Binary  \x00")
+
+// 二进制 FBX 中贴图路径保存在 RelativeFilename 节点的字符串属性里：
+// 名称长度 (1 字节) + 名称 + 属性类型 'S' + 字符串长度 (4 字节) + 内容
+var fbxRelativeFilename = []byte("\x10RelativeFilenameS")
+
+// ASCII FBX 中的写法: RelativeFilename: "textures\wall.jpg"
+var fbxASCIIPattern = regexp.MustCompile(`RelativeFilename:\s*"([^"]*)"`)
+
+// fbxScanWindow 流式扫描时每次读取的字节数，相邻两块之间保留 fbxScanOverlap 字节防止匹配被截断
+const (
+	fbxScanWindow  = 1 << 20
+	fbxScanOverlap = 4096
+)
+
+// FBXTextureRefs 扫描 FBX 中引用的外部贴图 (RelativeFilename)，支持二进制和 ASCII 格式
+// 只做字节级扫描，不解析完整的节点树；内嵌贴图同样会留下文件名
+func FBXTextureRefs(r io.Reader) ([]string, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+
+	buf := make([]byte, 0, fbxScanWindow+fbxScanOverlap)
+	chunk := make([]byte, fbxScanWindow)
+	binaryFormat := false
+	first := true
+	for {
+		n, err := io.ReadFull(r, chunk)
+		buf = append(buf, chunk[:n]...)
+		if first {
+			binaryFormat = bytes.HasPrefix(buf, fbxBinaryMagic)
+			first = false
+		}
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			return nil, err
+		}
+
+		// 只处理完整落在缓冲区内的匹配，末尾可能被截断的部分留到下一轮
+		limit := len(buf)
+		if !eof && limit > fbxScanOverlap {
+			limit -= fbxScanOverlap
+		}
+		consumed := scanFBX(buf, limit, binaryFormat, add)
+		if eof {
+			return refs, nil
+		}
+		if consumed < limit {
+			consumed = limit
+		}
+		buf = append(buf[:0], buf[consumed:]...)
+	}
+}
+
+// scanFBX 在 buf 中查找起点小于 limit 的贴图引用，返回已处理到的位置
+func scanFBX(buf []byte, limit int, binaryFormat bool, add func(string)) int {
+	if !binaryFormat {
+		pos := 0
+		for _, m := range fbxASCIIPattern.FindAllSubmatchIndex(buf, -1) {
+			if m[0] >= limit {
+				break
+			}
+			add(string(buf[m[2]:m[3]]))
+			pos = m[1]
+		}
+		return pos
+	}
+
+	pos := 0
+	for {
+		i := bytes.Index(buf[pos:], fbxRelativeFilename)
+		if i < 0 || pos+i >= limit {
+			return pos
+		}
+		start := pos + i + len(fbxRelativeFilename)
+		if start+4 > len(buf) {
+			return pos + i
+		}
+		size := int(binary.LittleEndian.Uint32(buf[start:]))
+		if size > fbxScanOverlap || start+4+size > len(buf) {
+			pos = start
+			continue
+		}
+		add(string(buf[start+4 : start+4+size]))
+		pos = start + 4 + size
+	}
+}
@@ -0,0 +1,155 @@
+// Package model3d 解析 OBJ/MTL/FBX 模型中与上传校验相关的信息：网格统计和贴图引用
+package model3d
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OBJStats OBJ 文件的统计信息
+type OBJStats struct {
+	VertexCount   int64      // 顶点 (v) 数量
+	TriangleCount int64      // 面三角化后的三角形数量
+	HasBounds     bool       // 是否有顶点
+	BoundsMin     [3]float64 // 顶点包围盒
+	BoundsMax     [3]float64
+	MaterialLibs  []string // mtllib 引用的材质库文件
+}
+
+// ReadOBJ 逐行扫描 OBJ，统计顶点、三角形和包围盒
+func ReadOBJ(r io.Reader) (*OBJStats, error) {
+	stats := &OBJStats{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("第 %d 行顶点坐标不完整", line)
+			}
+			var p [3]float64
+			for i := 0; i < 3; i++ {
+				v, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 行顶点坐标无效: %s", line, fields[i+1])
+				}
+				p[i] = v
+			}
+			stats.extend(p)
+			stats.VertexCount++
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("第 %d 行的面少于 3 个顶点", line)
+			}
+			stats.TriangleCount += int64(len(fields) - 3)
+		case "mtllib":
+			// 文件名可能包含空格，取整行剩余部分
+			name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "mtllib"))
+			if name != "" {
+				stats.MaterialLibs = append(stats.MaterialLibs, name)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 OBJ 失败: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *OBJStats) extend(p [3]float64) {
+	if !s.HasBounds {
+		s.BoundsMin, s.BoundsMax, s.HasBounds = p, p, true
+		return
+	}
+	for i := 0; i < 3; i++ {
+		s.BoundsMin[i] = math.Min(s.BoundsMin[i], p[i])
+		s.BoundsMax[i] = math.Max(s.BoundsMax[i], p[i])
+	}
+}
+
+// mtlTextureKeys MTL 中引用贴图的语句
+var mtlTextureKeys = map[string]bool{
+	"map_ka": true, "map_kd": true, "map_ks": true, "map_ke": true, "map_ns": true, "map_d": true,
+	"map_bump": true, "bump": true, "disp": true, "decal": true, "refl": true, "norm": true,
+	"map_pr": true, "map_pm": true, "map_ps": true,
+}
+
+// MTL 材质库中的材质数量和贴图引用
+type MTL struct {
+	MaterialCount int
+	Textures      []string
+}
+
+// ReadMTL 解析 MTL 材质库，贴图语句中的选项 (-s 1 1 1 等) 会被跳过，只保留文件名
+func ReadMTL(r io.Reader) (*MTL, error) {
+	mtl := &MTL{}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		key := strings.ToLower(fields[0])
+		if key == "newmtl" {
+			mtl.MaterialCount++
+			continue
+		}
+		if !mtlTextureKeys[key] || len(fields) < 2 {
+			continue
+		}
+		name := textureFileName(fields[1:])
+		if name != "" && !seen[name] {
+			seen[name] = true
+			mtl.Textures = append(mtl.Textures, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 MTL 失败: %w", err)
+	}
+	return mtl, nil
+}
+
+// mtlOptionArgs 贴图选项及其参数个数
+var mtlOptionArgs = map[string]int{
+	"-blendu": 1, "-blendv": 1, "-bm": 1, "-boost": 1, "-cc": 1, "-clamp": 1, "-imfchan": 1,
+	"-mm": 2, "-o": 3, "-s": 3, "-t": 3, "-texres": 1, "-type": 1,
+}
+
+// textureFileName 跳过选项后剩余的部分就是文件名 (可能包含空格)
+func textureFileName(args []string) string {
+	i := 0
+	for i < len(args) {
+		n, ok := mtlOptionArgs[strings.ToLower(args[i])]
+		if !ok {
+			break
+		}
+		i++
+		if n == 1 {
+			i++
+			continue
+		}
+		// -mm/-o/-s/-t 的参数都是数字，后面的可以省略
+		for j := 0; j < n && i < len(args)-1; j++ {
+			if _, err := strconv.ParseFloat(args[i], 64); err != nil {
+				break
+			}
+			i++
+		}
+	}
+	if i >= len(args) {
+		return ""
+	}
+	return strings.Join(args[i:], " ")
+}
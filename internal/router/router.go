@@ -11,10 +11,24 @@ func Setup(engine *gin.Engine,
 	exportHandler *handler.ExportHandler,
 	wsHandler *handler.WebsocketHandler,
 	historyTrailHandler *handler.HistoryTrailHandler,
-	logHandler *handler.LogHandler) {
+	logHandler *handler.LogHandler,
+	uploadSessionHandler *handler.UploadSessionHandler,
+	jobHandler *handler.JobHandler,
+	storageHandler *handler.StorageHandler,
+	spatialHandler *handler.SpatialHandler,
+	crsHandler *handler.CRSHandler,
+	weatherHandler *handler.WeatherHandler,
+	mappingHandler *handler.MappingHandler,
+	joinHandler *handler.JoinHandler,
+	versionHandler *handler.VersionHandler,
+	importHandler *handler.ImportHandler,
+	downloadHandler *handler.DownloadHandler,
+	adminHandler *handler.AdminHandler) {
 	// 设置静态文件服务，用于访问上传的图片
-	// 前端访问 http://localhost:8080/uploads/xxx.jpg 就会映射到 ./uploads/xxx.jpg 文件
-	engine.Static("/uploads", "./uploads")
+	// 前端访问 http://localhost:8080/uploads/xxx.jpg 会交给存储后端处理：
+	// 本地存储映射到 ./uploads/xxx.jpg 文件，S3 存储重定向到预签名 URL 或由服务端代理
+	engine.GET("/uploads/*filepath", storageHandler.ServeUpload)
+	engine.HEAD("/uploads/*filepath", storageHandler.ServeUpload)
 
 	// 新增 WebSocket 路由
 	engine.GET("/ws", wsHandler.ServeWS)
@@ -33,9 +47,15 @@ func Setup(engine *gin.Engine,
 			islandGroup.DELETE("/:id", islandHandler.DeleteIsland)
 			// PUT /api/v1/islands/:id - 更新岛屿信息
 			islandGroup.PUT("/:id", islandHandler.UpdateIsland)
+			// PUT /api/v1/islands/:id/layer-order - 按给定的文件顺序设置图层的绘制顺序
+			islandGroup.PUT("/:id/layer-order", dataFileHandler.ReorderLayers)
+			// GET /api/v1/islands/:isle_id/thumbnail?size=256 - 获取岛屿图片的缩略图
+			islandGroup.GET("/:isle_id/thumbnail", islandHandler.GetIslandThumbnail)
+			// POST /api/v1/islands/:isle_id/import - 上传按类型分目录的 zip，批量创建岛屿的数据文件
+			islandGroup.POST("/:isle_id/import", importHandler.ImportIsland)
 
 			// 导出结构化 json 接口
-			// GET /api/v1/islands/:isle_id/export
+			// GET /api/v1/islands/:isle_id/export?crs=EPSG:4548 (crs 可选，默认 WGS84 经纬度)
 			islandGroup.GET("/:isle_id/export", exportHandler.ExportIslandJSON)
 		}
 
@@ -50,6 +70,85 @@ func Setup(engine *gin.Engine,
 			dataFileGroup.DELETE("/:id", dataFileHandler.DeleteDataFile)
 			// PUT /api/v1/data-files/:id/height - 修改文件高度
 			dataFileGroup.PUT("/:id/height", dataFileHandler.UpdateDataFileHeight)
+			// PATCH /api/v1/data-files/:id - 修改名称、类型、高度、显示样式，或移动/复制到其他岛屿
+			dataFileGroup.PATCH("/:id", dataFileHandler.PatchDataFile)
+			// GET /api/v1/data-files/:id/download - 下载文件，shp、tif 等重新打包为 zip
+			dataFileGroup.GET("/:id/download", downloadHandler.Download)
+			dataFileGroup.HEAD("/:id/download", downloadHandler.Download)
+			// GET /api/v1/data-files/:id/geojson - 获取 shp 图层的 GeoJSON
+			dataFileGroup.GET("/:id/geojson", dataFileHandler.GetGeoJSON)
+			// GET /api/v1/data-files/:id/thumbnail?size=256 - 获取 jpg 文件的缩略图
+			dataFileGroup.GET("/:id/thumbnail", dataFileHandler.GetThumbnail)
+			// GET /api/v1/data-files/:id/weather?from=..&to=..&vars=..&limit=.. - 气象文件一段时间内的时间步
+			dataFileGroup.GET("/:id/weather", weatherHandler.GetWindow)
+			// GET /api/v1/data-files/:id/weather/at?time=..&vars=.. - 气象文件某一时刻的插值结果
+			dataFileGroup.GET("/:id/weather/at", weatherHandler.GetAt)
+			// GET /api/v1/data-files/:id/preview?page=1&pageSize=50 - 分页预览映射表 (mapping CSV)
+			dataFileGroup.GET("/:id/preview", mappingHandler.Preview)
+			// GET /api/v1/data-files/:id/versions - 文件所在版本链的全部版本
+			dataFileGroup.GET("/:id/versions", versionHandler.ListVersions)
+			// GET /api/v1/data-files/:id/versions/diff?from=1&to=2 - 比较两个版本的元数据
+			dataFileGroup.GET("/:id/versions/diff", versionHandler.DiffVersions)
+			// POST /api/v1/data-files/:id/rollback - 切换生效版本
+			dataFileGroup.POST("/:id/rollback", versionHandler.Rollback)
+		}
+
+		// 分片上传 (断点续传) 相关路由
+		uploadGroup := apiV1.Group("/uploads")
+		{
+			// POST /api/v1/uploads - 创建上传会话
+			uploadGroup.POST("", uploadSessionHandler.CreateSession)
+			// GET /api/v1/uploads/:id - 查询已完成/缺失的分片
+			uploadGroup.GET("/:id", uploadSessionHandler.GetSession)
+			// PUT /api/v1/uploads/:id/chunks/:index - 上传第 index 个分片
+			uploadGroup.PUT("/:id/chunks/:index", uploadSessionHandler.UploadChunk)
+			// POST /api/v1/uploads/:id/complete - 合并分片并处理文件
+			uploadGroup.POST("/:id/complete", uploadSessionHandler.CompleteSession)
+			// DELETE /api/v1/uploads/:id - 取消上传会话
+			uploadGroup.DELETE("/:id", uploadSessionHandler.AbortSession)
+		}
+
+		// 后台任务相关路由
+		// GET /api/v1/jobs/:id - 查询上传处理任务的状态和进度
+		apiV1.GET("/jobs/:id", jobHandler.GetJob)
+
+		// 空间查询相关路由
+		spatialGroup := apiV1.Group("/spatial")
+		{
+			// GET /api/v1/spatial/islands?bbox=minLon,minLat,maxLon,maxLat - 范围内的岛屿
+			spatialGroup.GET("/islands", spatialHandler.IslandsInBBox)
+			// GET /api/v1/spatial/islands/nearby?lat=..&lon=..&radius=.. - 半径 (米) 内的岛屿
+			spatialGroup.GET("/islands/nearby", spatialHandler.IslandsNearby)
+			// GET /api/v1/spatial/islands/nearest?lat=..&lon=.. - 最近的岛屿
+			spatialGroup.GET("/islands/nearest", spatialHandler.NearestIsland)
+			// GET /api/v1/spatial/layers?bbox=..&data_type=..&crs=.. - 范围相交的数据图层
+			spatialGroup.GET("/layers", spatialHandler.LayersInBBox)
+		}
+
+		// 坐标系相关路由
+		crsGroup := apiV1.Group("/crs")
+		{
+			// GET /api/v1/crs - 支持转换的坐标系列表
+			crsGroup.GET("", crsHandler.ListCRS)
+			// POST /api/v1/crs/transform - 批量坐标转换
+			crsGroup.POST("/transform", crsHandler.Transform)
+		}
+
+		// mapping (CSV) 与 shp 图层的属性关联
+		joinGroup := apiV1.Group("/joins")
+		{
+			// POST /api/v1/joins - 按关键字段关联 CSV 与 shp，返回匹配统计
+			joinGroup.POST("", joinHandler.CreateJoin)
+			// GET /api/v1/joins?isle_id=..&file_id=.. - 岛屿下的关联
+			joinGroup.GET("", joinHandler.ListJoins)
+			// GET /api/v1/joins/:id - 关联及匹配统计
+			joinGroup.GET("/:id", joinHandler.GetJoin)
+			// DELETE /api/v1/joins/:id - 删除关联
+			joinGroup.DELETE("/:id", joinHandler.DeleteJoin)
+			// GET /api/v1/joins/:id/geojson - 带有 CSV 属性的 GeoJSON
+			joinGroup.GET("/:id/geojson", joinHandler.GetJoinGeoJSON)
+			// GET /api/v1/joins/:id/lookup - 以关键值为索引的属性表
+			joinGroup.GET("/:id/lookup", joinHandler.GetJoinLookup)
 		}
 
 		// 新增历史轨迹相关路由
@@ -68,5 +167,14 @@ func Setup(engine *gin.Engine,
 		// 新增日志接口
 		// GET /api/v1/logs
 		apiV1.GET("/logs", logHandler.GetSystemLog)
+
+		// 运维接口
+		adminGroup := apiV1.Group("/admin")
+		{
+			// GET /api/v1/admin/reconcile?min_age=1h - 预览 uploads 与数据库的对账结果
+			adminGroup.GET("/reconcile", adminHandler.PreviewReconcile)
+			// POST /api/v1/admin/reconcile?confirm=<fingerprint> - 按预览结果修复
+			adminGroup.POST("/reconcile", adminHandler.Reconcile)
+		}
 	}
 }
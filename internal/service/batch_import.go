@@ -0,0 +1,340 @@
+package service
+
+import (
+	"Go_for_unity/internal/archive"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/store"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// batchManifestName 批量导入压缩包根目录下可选的清单文件
+const batchManifestName = "manifest.json"
+
+// BatchManifest 批量导入的清单，heights 的键为条目路径 (例如 shp/roads 或 tif/dom.zip，扩展名可省略)
+type BatchManifest struct {
+	Heights map[string]float64 `json:"heights"`
+}
+
+// batchEntry 压缩包中待导入的一个条目
+type batchEntry struct {
+	item    model.BatchItem
+	srcPath string // 本地路径：文件直接使用，shp/tif/models 文件夹先打包为 zip
+	isDir   bool
+}
+
+// BatchImporter 从一个按类型分目录的压缩包 (shp/…、tif/…、models/… 等) 为岛屿批量创建 DataFile
+// 所有条目在同一个任务中依次处理，任意一个失败时撤销本批次已经创建的记录
+type BatchImporter struct {
+	ingestor *Ingestor
+	dfStore  *store.DataFileStore
+}
+
+func NewBatchImporter(ingestor *Ingestor, dfStore *store.DataFileStore) *BatchImporter {
+	return &BatchImporter{ingestor: ingestor, dfStore: dfStore}
+}
+
+// BatchJob 根据批量导入请求构造任务记录
+func BatchJob(isleID uint, fileName string) model.Job {
+	return model.Job{
+		Kind:     JobKindBatch,
+		IsleID:   isleID,
+		FileName: fileName,
+	}
+}
+
+// Task 把一次批量导入包装成后台任务，结束后删除 stagingDir
+func (b *BatchImporter) Task(isleID uint, zipPath, stagingDir string) JobTask {
+	return func(p *JobProgress) (*model.DataFile, error) {
+		defer os.RemoveAll(stagingDir)
+		return nil, b.run(p, isleID, zipPath, stagingDir)
+	}
+}
+
+func (b *BatchImporter) run(p *JobProgress, isleID uint, zipPath, stagingDir string) error {
+	// --- 1. 解压整个压缩包 ---
+	p.Stage(model.JobStatusExtracting)
+	extractDir := filepath.Join(stagingDir, "extracted")
+	if err := archive.Extract(zipPath, extractDir, archive.ExtractOptions{Progress: p.Extract}); err != nil {
+		return inputErrorf("解压文件失败: %v", err)
+	}
+	os.Remove(zipPath)
+
+	// --- 2. 按目录结构和清单列出所有条目 ---
+	entries, err := scanBatchArchive(extractDir)
+	if err != nil {
+		return err
+	}
+	report := &model.BatchReport{Total: len(entries)}
+	for _, e := range entries {
+		report.Items = append(report.Items, e.item)
+	}
+	p.Report(report)
+
+	// --- 3. 逐个创建 DataFile，失败时撤销已创建的记录 ---
+	p.Stage(model.JobStatusIndexing)
+	p.Extract(0, int64(len(entries)))
+	var created []*ingestResult
+	for idx, e := range entries {
+		res, err := b.ingest(isleID, e, stagingDir)
+		if err != nil {
+			report.Items[idx].Status = model.BatchItemFailed
+			report.Items[idx].Error = err.Error()
+			for j := idx + 1; j < len(entries); j++ {
+				report.Items[j].Status = model.BatchItemSkipped
+			}
+			if rbErr := b.rollback(created, report); rbErr != nil {
+				p.Report(report)
+				return fmt.Errorf("%s 处理失败: %w；撤销本批次创建的文件失败，%d 个文件仍保留在岛屿中: %v", e.item.Path, err, report.Created, rbErr)
+			}
+			p.Report(report)
+			return fmt.Errorf("%s 处理失败，已撤销本批次创建的 %d 个文件: %w", e.item.Path, len(created), err)
+		}
+		created = append(created, res)
+		dataFile := res.file
+		report.Created++
+		report.Items[idx].Status = model.BatchItemCreated
+		report.Items[idx].DataFileID = dataFile.ID
+		report.Items[idx].Version = dataFile.Version
+		p.Extract(int64(idx+1), int64(len(entries)))
+		p.Report(report)
+	}
+	return nil
+}
+
+// ingest 处理单个条目，文件夹先打包为 zip 再按普通上传处理
+func (b *BatchImporter) ingest(isleID uint, e batchEntry, stagingDir string) (*ingestResult, error) {
+	srcPath, fileName := e.srcPath, filepath.Base(e.srcPath)
+	if e.isDir {
+		fileName += ".zip"
+		srcPath = filepath.Join(stagingDir, fmt.Sprintf("%s-%s", e.item.DataType, fileName))
+		if err := archive.ZipDir(e.srcPath, srcPath); err != nil {
+			return nil, fmt.Errorf("打包文件夹失败: %w", err)
+		}
+	}
+	return b.ingestor.ingest(IngestRequest{
+		IsleID:   isleID,
+		DataType: e.item.DataType,
+		Height:   e.item.Height,
+		FileName: fileName,
+		SrcPath:  srcPath,
+	})
+}
+
+// rollback 在一个事务中删除本批次创建的记录，同名文件恢复为导入前生效的版本；事务成功后再释放内容引用
+// 本批次新写入的内容上生成的缩略图和 GeoJSON 缓存一并删除，复用的已有内容上的缓存仍属于原来的文件，保留
+// 撤销失败时所有记录都保留，对应条目标记为 left_behind，report.PartialRollback 为 true
+func (b *BatchImporter) rollback(created []*ingestResult, report *model.BatchReport) error {
+	// 持有涉及的所有版本链的锁，避免撤销期间同名文件的上传或修改插入新版本；按 key 排序加锁，避免互相等待
+	var keys []string
+	seen := make(map[string]bool)
+	undos := make([]store.VersionUndo, 0, len(created))
+	for _, res := range created {
+		if key := lineageKey(res.file); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+		undos = append(undos, store.VersionUndo{File: res.file, PreviousID: res.previousID})
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		unlock := b.ingestor.lineage.lock(key)
+		defer unlock()
+	}
+
+	if err := b.dfStore.UndoVersions(undos); err != nil {
+		log.Printf("撤销批量导入失败: %v", err)
+		report.PartialRollback = true
+		for _, res := range created {
+			setBatchItemStatus(report, res.file.ID, model.BatchItemLeftBehind)
+		}
+		return err
+	}
+	for i := len(created) - 1; i >= 0; i-- {
+		file := created[i].file
+		if !created[i].reused {
+			b.ingestor.removeDerived(file)
+		}
+		b.ingestor.releaseBlob(file.BlobHash)
+		setBatchItemStatus(report, file.ID, model.BatchItemRolledBack)
+		report.Created--
+	}
+	return nil
+}
+
+// setBatchItemStatus 修改 DataFile 对应条目的状态
+func setBatchItemStatus(report *model.BatchReport, dataFileID uint, status string) {
+	for j := range report.Items {
+		if report.Items[j].DataFileID == dataFileID {
+			report.Items[j].Status = status
+		}
+	}
+}
+
+// --- Helper Functions ---
+
+// scanBatchArchive 列出解压目录中的条目：第一层目录为文件类型，其下每个文件或文件夹是一个条目
+// 压缩包只包了一层外部文件夹时自动进入该文件夹
+func scanBatchArchive(root string) ([]batchEntry, error) {
+	root, err := batchRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readBatchManifest(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []batchEntry
+	var unknown []string
+	seen := make(map[string]string) // 类型/名称 -> 条目路径，同一批次中不能重名
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if isIgnoredBatchEntry(d.Name()) || d.Name() == batchManifestName {
+			continue
+		}
+		if !d.IsDir() || !IsSupportedDataType(d.Name()) {
+			unknown = append(unknown, d.Name())
+			continue
+		}
+		dataType := d.Name()
+		children, err := os.ReadDir(filepath.Join(root, dataType))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range children {
+			if isIgnoredBatchEntry(c.Name()) {
+				continue
+			}
+			itemPath := path.Join(dataType, c.Name())
+			if err := checkBatchEntry(dataType, c.Name(), c.IsDir()); err != nil {
+				return nil, inputErrorf("%s: %v", itemPath, err)
+			}
+			name := c.Name()
+			if !c.IsDir() {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if other, ok := seen[dataType+"/"+name]; ok {
+				return nil, inputErrorf("%s 与 %s 的文件名相同", itemPath, other)
+			}
+			seen[dataType+"/"+name] = itemPath
+
+			entries = append(entries, batchEntry{
+				item: model.BatchItem{
+					Path:     itemPath,
+					DataType: dataType,
+					DataName: name,
+					Height:   manifest.height(itemPath),
+					Status:   model.BatchItemPending,
+				},
+				srcPath: filepath.Join(root, dataType, c.Name()),
+				isDir:   c.IsDir(),
+			})
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, inputErrorf("无法识别的条目: %s (第一层目录应为文件类型: %s)",
+			strings.Join(unknown, ", "), strings.Join(DataTypes(), ", "))
+	}
+	if len(entries) == 0 {
+		return nil, inputErrorf("压缩包中没有可导入的文件")
+	}
+	if err := manifest.check(entries); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].item.Path < entries[j].item.Path })
+	return entries, nil
+}
+
+// batchRoot 找到包含类型目录的根目录
+func batchRoot(dir string) (string, error) {
+	for {
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		var visible []os.DirEntry
+		for _, c := range children {
+			if !isIgnoredBatchEntry(c.Name()) {
+				visible = append(visible, c)
+			}
+		}
+		if len(visible) != 1 || !visible[0].IsDir() || IsSupportedDataType(visible[0].Name()) {
+			return dir, nil
+		}
+		dir = filepath.Join(dir, visible[0].Name())
+	}
+}
+
+// checkBatchEntry 按类型处理器检查条目形式：需要解压的类型可以是文件夹 (导入时打包为 zip)，其余类型为单个文件
+func checkBatchEntry(dataType, name string, isDir bool) error {
+	proc, _ := LookupProcessor(dataType)
+	if isDir {
+		if proc.Archive == ArchiveNever {
+			return fmt.Errorf("%s 类型应为单个文件", dataType)
+		}
+		return nil
+	}
+	if proc.Archive == ArchiveAlways && !strings.EqualFold(filepath.Ext(name), ".zip") {
+		return fmt.Errorf("应为文件夹或 zip 压缩包")
+	}
+	return ValidateUpload(dataType, name)
+}
+
+// isIgnoredBatchEntry 忽略隐藏文件和 macOS 打包时附带的元数据
+func isIgnoredBatchEntry(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "__MACOSX"
+}
+
+// readBatchManifest 读取可选的清单文件，不存在时返回空清单
+func readBatchManifest(root string) (*BatchManifest, error) {
+	manifest := &BatchManifest{}
+	data, err := os.ReadFile(filepath.Join(root, batchManifestName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, inputErrorf("%s 格式错误: %v", batchManifestName, err)
+	}
+	return manifest, nil
+}
+
+// height 返回条目的高度，清单中的键可以省略扩展名
+func (m *BatchManifest) height(itemPath string) float64 {
+	if h, ok := m.Heights[itemPath]; ok {
+		return h
+	}
+	return m.Heights[strings.TrimSuffix(itemPath, path.Ext(itemPath))]
+}
+
+// check 清单中的每个键都必须对应一个条目，避免拼写错误的高度被悄悄忽略
+func (m *BatchManifest) check(entries []batchEntry) error {
+	known := make(map[string]bool, len(entries)*2)
+	for _, e := range entries {
+		known[e.item.Path] = true
+		known[strings.TrimSuffix(e.item.Path, path.Ext(e.item.Path))] = true
+	}
+	var missing []string
+	for key := range m.Heights {
+		if !known[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return inputErrorf("%s 中的条目在压缩包中不存在: %s", batchManifestName, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// blobRoot 内容寻址存储的根目录
+const blobRoot = "uploads/blobs"
+
+// FillFunc 把内容写入 dir (一个本地临时目录，写完后整体导入存储后端)
+// 单文件类型返回写入的文件名，压缩包类型返回空字符串
+type FillFunc func(dir string) (entry string, err error)
+
+// ContentStore 按 SHA-256 存储上传的内容，相同内容以相同方式 (原样保存或解压) 保存时只保存一份
+// 多个岛屿上传同一份底图或模型时，DataFile 指向同一个 Blob，删除时仅在无人引用后才清理磁盘
+type ContentStore struct {
+	store   *store.BlobStore
+	backend storage.Backend
+	locks   keyedMutex // 按 hash 加锁，保证同一内容的写入和释放串行进行
+}
+
+func NewContentStore(store *store.BlobStore, backend storage.Backend) *ContentStore {
+	return &ContentStore{store: store, backend: backend}
+}
+
+// Acquire 获取内容 SHA-256 为 hash、保存方式为 extract 的内容并增加引用：已存在时直接复用，否则调用 fill 写入
+// 返回值 reused 表示是否命中了已有内容；DataFile 通过返回的 blob.Hash 引用内容
+func (s *ContentStore) Acquire(hash string, size int64, extract bool, fill FillFunc) (blob *model.Blob, reused bool, err error) {
+	key := blobKey(hash, extract)
+	unlock := s.locks.lock(key)
+	defer unlock()
+
+	existing, err := s.store.GetByHash(key)
+	if err == nil {
+		if storage.Exists(s.backend, existing.Dir) {
+			if err := s.store.IncRef(key); err != nil {
+				return nil, false, fmt.Errorf("增加内容引用失败: %w", err)
+			}
+			existing.RefCount++
+			return existing, true, nil
+		}
+		// 记录存在但存储中的内容丢失，删除旧记录后重新写入
+		log.Printf("内容 %s 在存储中已丢失，重新写入", key)
+		if err := s.store.Delete(key); err != nil {
+			return nil, false, fmt.Errorf("删除失效的内容记录失败: %w", err)
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("查询内容记录失败: %w", err)
+	}
+
+	// 先在本地临时目录中准备好内容，再整体导入存储后端
+	tmpDir, err := NewStagingDir("blob-")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.RemoveAll(tmpDir)
+	entry, err := fill(tmpDir)
+	if err != nil {
+		return nil, false, err
+	}
+	dir := blobDir(key)
+	if err := s.backend.Import(tmpDir, dir); err != nil {
+		s.backend.RemoveAll(dir)
+		return nil, false, fmt.Errorf("保存内容失败: %w", err)
+	}
+
+	blob = &model.Blob{Hash: key, SHA256: hash, Size: size, Dir: dir, Entry: entry, RefCount: 1}
+	if err := s.store.Create(blob); err != nil {
+		s.backend.RemoveAll(dir)
+		return nil, false, fmt.Errorf("创建内容记录失败: %w", err)
+	}
+	return blob, false, nil
+}
+
+// Retain 为已存在的内容增加一次引用，复制 DataFile 时新记录与原记录共用同一份内容
+func (s *ContentStore) Retain(hash string) error {
+	unlock := s.locks.lock(hash)
+	defer unlock()
+
+	if _, err := s.store.GetByHash(hash); err != nil {
+		return fmt.Errorf("查询内容记录失败: %w", err)
+	}
+	if err := s.store.IncRef(hash); err != nil {
+		return fmt.Errorf("增加内容引用失败: %w", err)
+	}
+	return nil
+}
+
+// Get 查询 hash 对应的内容记录
+func (s *ContentStore) Get(hash string) (*model.Blob, error) {
+	blob, err := s.store.GetByHash(hash)
+	if err != nil {
+		return nil, fmt.Errorf("查询内容记录失败: %w", err)
+	}
+	return blob, nil
+}
+
+// CopyOut 把 hash 对应的内容复制到存储中的 dst
+// 单文件类型复制为 dst 本身，压缩包类型把解压目录复制到 dst/ 下；缩略图等隐藏的派生文件不复制
+func (s *ContentStore) CopyOut(hash, dst string) error {
+	unlock := s.locks.lock(hash)
+	defer unlock()
+
+	blob, err := s.Get(hash)
+	if err != nil {
+		return err
+	}
+	if blob.Entry != "" {
+		return storage.Copy(s.backend, storage.Join(blob.Dir, blob.Entry), dst)
+	}
+	return s.backend.Walk(blob.Dir, func(obj storage.ObjectInfo) error {
+		rel := strings.TrimPrefix(obj.Key, blob.Dir+"/")
+		if storage.IsHidden(rel) {
+			return nil
+		}
+		return storage.Copy(s.backend, obj.Key, storage.Join(dst, rel))
+	})
+}
+
+// Release 释放一次引用，引用数归零时删除记录和磁盘内容
+func (s *ContentStore) Release(hash string) error {
+	unlock := s.locks.lock(hash)
+	defer unlock()
+
+	remaining, err := s.store.DecRef(hash)
+	if err != nil {
+		return fmt.Errorf("减少内容引用失败: %w", err)
+	}
+	if remaining > 0 {
+		return nil
+	}
+
+	if err := s.store.Delete(hash); err != nil {
+		return fmt.Errorf("删除内容记录失败: %w", err)
+	}
+	return s.backend.RemoveAll(blobDir(hash))
+}
+
+// --- Helper Functions ---
+
+// HashFile 计算文件的 SHA-256 和大小
+func HashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// blobKey 内容记录的主键：由上传文件的 SHA-256 和保存方式共同决定
+// 同一个 zip 作为模型原样保存、作为 shp 解压保存时内容目录不同，不能共用同一条记录
+func blobKey(hash string, extract bool) string {
+	mode := "file"
+	if extract {
+		mode = "extract"
+	}
+	sum := sha256.Sum256([]byte(mode + ":" + hash))
+	return hex.EncodeToString(sum[:])
+}
+
+// blobDir 内容目录: uploads/blobs/<hash 前两位>/<hash>，用前缀分桶避免单个目录下文件过多
+func blobDir(hash string) string {
+	return storage.Join(blobRoot, hash[:2], hash)
+}
@@ -0,0 +1,40 @@
+package service
+
+import "Go_for_unity/internal/geo"
+
+// maxTransformPoints 单次坐标转换请求最多包含的点数
+const maxTransformPoints = 10000
+
+// ConvertPoint 把坐标 (x, y) 从 from 坐标系转换到 to 坐标系，坐标系不支持或坐标非法时返回 InputError
+func ConvertPoint(from, to string, x, y float64) (float64, float64, error) {
+	rx, ry, err := geo.Transform(from, to, x, y)
+	if err != nil {
+		return 0, 0, &InputError{Msg: err.Error()}
+	}
+	return rx, ry, nil
+}
+
+// ConvertPoints 批量转换坐标，points 中每个元素为 [x, y]，出错时指明是第几个点
+func ConvertPoints(from, to string, points [][2]float64) ([][2]float64, error) {
+	if len(points) > maxTransformPoints {
+		return nil, inputErrorf("单次最多转换 %d 个点", maxTransformPoints)
+	}
+	result := make([][2]float64, len(points))
+	for i, p := range points {
+		x, y, err := geo.Transform(from, to, p[0], p[1])
+		if err != nil {
+			return nil, inputErrorf("第 %d 个点转换失败: %v", i+1, err)
+		}
+		result[i] = [2]float64{x, y}
+	}
+	return result, nil
+}
+
+// NormalizeCRS 统一坐标系写法，不支持时返回 InputError
+func NormalizeCRS(code string) (string, error) {
+	canonical, err := geo.NormalizeCRS(code)
+	if err != nil {
+		return "", &InputError{Msg: err.Error()}
+	}
+	return canonical, nil
+}
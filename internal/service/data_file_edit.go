@@ -0,0 +1,362 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"path"
+	"strings"
+)
+
+// DataFilePatch 修改文件元数据的请求，未提供的字段保持不变
+type DataFilePatch struct {
+	DataName *string  `json:"data_name"` // 新名称
+	DataType *string  `json:"data_type"` // 新类型，只能改为处理器 ConvertsTo 中的类型 (例如 txt、weather、mapping 之间)
+	Height   *float64 `json:"height"`    // 高度，只修改 id 对应的版本
+	IsleID   *uint    `json:"isle_id"`   // 目标岛屿
+	Copy     bool     `json:"copy"`      // true 时复制到目标位置，原文件保留；否则移动
+
+	Style *LayerStylePatch `json:"style"` // 显示样式，对整个版本链生效
+}
+
+// DataFileEditor 修改文件的名称、类型、高度，以及在岛屿之间移动或复制文件
+// 名称、类型和岛屿是版本链的标识，修改时整个版本链一起变化
+type DataFileEditor struct {
+	dfStore  *store.DataFileStore
+	isStore  *store.IslandStore
+	content  *ContentStore
+	backend  storage.Backend
+	thumbs   *ThumbnailService
+	ingestor *Ingestor // 重新解析元数据和分配版本号
+}
+
+func NewDataFileEditor(dfStore *store.DataFileStore, isStore *store.IslandStore, content *ContentStore, backend storage.Backend, thumbs *ThumbnailService, ingestor *Ingestor) *DataFileEditor {
+	return &DataFileEditor{dfStore: dfStore, isStore: isStore, content: content, backend: backend, thumbs: thumbs, ingestor: ingestor}
+}
+
+// Patch 按请求修改或复制 id 对应的文件，返回修改后的记录 (复制时为新记录)
+// 移动到其他岛屿时，文件随之移动到目标岛屿的 uploads/<用户>/<岛屿>/<类型> 目录
+func (e *DataFileEditor) Patch(id uint, p DataFilePatch) (*model.DataFile, error) {
+	file, err := e.dfStore.GetByID(id)
+	if err != nil {
+		return nil, ErrDataFileNotFound
+	}
+
+	// 1. 计算目标名称、类型、岛屿并校验
+	target := *file
+	if p.DataName != nil {
+		name := strings.TrimSpace(*p.DataName)
+		if name == "" {
+			return nil, inputErrorf("文件名称不能为空")
+		}
+		target.DataName = name
+	}
+	if p.DataType != nil && *p.DataType != file.DataType {
+		if !IsSupportedDataType(*p.DataType) {
+			return nil, inputErrorf("不支持的文件类型: %s", *p.DataType)
+		}
+		if proc, ok := LookupProcessor(file.DataType); !ok || !proc.CanConvertTo(*p.DataType) {
+			return nil, inputErrorf("不能把 %s 文件改为 %s 类型", file.DataType, *p.DataType)
+		}
+		target.DataType = *p.DataType
+	}
+	if p.IsleID != nil && *p.IsleID != file.IsleID {
+		if _, err := e.isStore.GetByID(*p.IsleID); err != nil {
+			return nil, ErrIslandNotFound
+		}
+		target.IsleID = *p.IsleID
+	}
+	if p.Height != nil {
+		target.Height = *p.Height
+	}
+	if p.Style != nil {
+		style, err := p.Style.apply(&target)
+		if err != nil {
+			return nil, err
+		}
+		target.Style = style
+	}
+
+	// 2. 复制或原地修改
+	if p.Copy {
+		return e.copy(file, &target)
+	}
+	return e.update(file, &target)
+}
+
+// update 修改整个版本链的名称、类型和岛屿；改变岛屿时所有版本的文件都移动到目标岛屿的目录下
+func (e *DataFileEditor) update(file, target *model.DataFile) (*model.DataFile, error) {
+	typeChanged := target.DataType != file.DataType
+	isleChanged := target.IsleID != file.IsleID
+	if target.DataName == file.DataName && !typeChanged && !isleChanged {
+		if target.Height != file.Height {
+			if err := e.dfStore.UpdateHeight(file.ID, target.Height); err != nil {
+				return nil, err
+			}
+		}
+		if target.Style != file.Style {
+			if err := e.dfStore.UpdateStyles(map[uint]*model.LayerStyle{file.LineageID: target.Style}); err != nil {
+				return nil, err
+			}
+		}
+		return target, nil
+	}
+
+	// 目标位置已有其他版本链的生效文件时拒绝，避免两个版本链同名
+	unlock := e.ingestor.lineage.lock(lineageKey(target))
+	defer unlock()
+	if other, err := e.dfStore.FindActive(target.IsleID, target.DataType, target.DataName); err == nil && other.LineageID != file.LineageID {
+		return nil, ErrDataFileConflict
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	versions, err := e.dfStore.ListVersions(file.LineageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 文件移动到目标岛屿的 uploads/<用户>/<岛屿>/<类型> 目录
+	//    旧版文件整体移动；内容存储中的文件可能被其他记录共享，移动岛屿时复制一份到目标目录，
+	//    记录改为按岛屿目录存储，保存成功后再释放原内容的引用
+	var moves []legacyMove
+	var copies []string   // 从内容存储复制出的文件，失败时删除
+	var released []string // 保存成功后需要释放引用的内容
+	undo := func() {
+		for _, m := range moves {
+			if err := storage.Move(e.backend, m.to, m.from); err != nil {
+				log.Printf("恢复文件位置失败 %s -> %s: %v", m.to, m.from, err)
+			}
+		}
+		for _, key := range copies {
+			e.backend.RemoveAll(key)
+		}
+	}
+	for i := range versions {
+		v := &versions[i]
+		if v.BlobHash != "" {
+			if !isleChanged {
+				continue // 只改名称或类型时内容与岛屿目录无关，只需修改记录
+			}
+			newPath, unit, err := e.materialize(v, target)
+			if err != nil {
+				undo()
+				return nil, err
+			}
+			copies = append(copies, unit)
+			released = append(released, v.BlobHash)
+			v.DataPath = newPath
+			v.BlobHash = ""
+			continue
+		}
+		if !isleChanged && !typeChanged {
+			continue
+		}
+		newPath, move, err := e.relocate(v, target, false)
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		if move == nil {
+			continue
+		}
+		if IsThumbnailSource(v.DataPath) {
+			e.thumbs.Remove(v.DataPath)
+		}
+		v.DataPath = newPath
+		moves = append(moves, *move)
+	}
+
+	// 2. 修改每个版本，类型改变时按新类型重新解析元数据
+	var result *model.DataFile
+	for i := range versions {
+		v := &versions[i]
+		v.DataName = target.DataName
+		v.DataType = target.DataType
+		v.IsleID = target.IsleID
+		v.Style = target.Style
+		if v.ID == file.ID {
+			v.Height = target.Height
+			result = v
+		}
+		if typeChanged {
+			resetMetadata(v)
+			if err := e.ingestor.extractMetadata(v); err != nil {
+				undo()
+				return nil, fmt.Errorf("第 %d 个版本无法作为 %s 文件: %w", v.Version, v.DataType, err)
+			}
+		}
+	}
+
+	// 3. 保存；CSV 关联只在同一岛屿内有效，类型改变后也不再适用，一并删除
+	if err := e.dfStore.SaveVersions(versions, typeChanged, typeChanged || isleChanged); err != nil {
+		undo()
+		return nil, fmt.Errorf("更新文件记录失败: %w", err)
+	}
+	for _, hash := range released {
+		e.ingestor.releaseBlob(hash)
+	}
+	result.WeatherSteps = nil
+	return result, nil
+}
+
+// copy 把文件复制到目标位置，成为目标岛屿中的新文件 (或同名文件的新版本)
+// 内容存储中的文件只增加引用，旧版按岛屿目录存储的文件复制到目标岛屿的目录下
+func (e *DataFileEditor) copy(file, target *model.DataFile) (*model.DataFile, error) {
+	if target.DataName == file.DataName && target.DataType == file.DataType && target.IsleID == file.IsleID {
+		return nil, inputErrorf("复制时需要指定不同的岛屿、名称或类型")
+	}
+
+	dup := *target
+	dup.Model = gorm.Model{}
+	dup.LineageID = 0
+	dup.Version = 0
+	dup.Active = false
+	dup.WeatherSteps = nil
+
+	// 1. 复制内容
+	var cleanup func()
+	if dup.BlobHash != "" {
+		if err := e.content.Retain(dup.BlobHash); err != nil {
+			return nil, err
+		}
+		cleanup = func() { e.ingestor.releaseBlob(dup.BlobHash) }
+	} else {
+		newPath, move, err := e.relocate(file, target, true)
+		if err != nil {
+			return nil, err
+		}
+		dup.DataPath = newPath
+		cleanup = func() { e.backend.RemoveAll(move.to) }
+	}
+
+	// 2. 重新解析元数据：类型可能改变，气象时间步等派生数据也不在记录中
+	resetMetadata(&dup)
+	if err := e.ingestor.extractMetadata(&dup); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	// 3. 写入版本链
+	if _, err := e.ingestor.createVersion(&dup); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("数据库记录创建失败: %w", err)
+	}
+	dup.WeatherSteps = nil
+	return &dup, nil
+}
+
+// materialize 把内容存储中的版本复制到 target 所在岛屿的 uploads/<用户>/<岛屿>/<类型> 目录，返回新的 DataPath 和复制出的对象
+// 版本链中的每个版本各占一个位置：第 1 个版本使用文件名，之后的版本加上 _v<版本号>
+func (e *DataFileEditor) materialize(file, target *model.DataFile) (string, string, error) {
+	dst, err := e.isStore.GetByID(target.IsleID)
+	if err != nil {
+		return "", "", ErrIslandNotFound
+	}
+	name := target.DataName
+	if file.Version > 1 {
+		name = fmt.Sprintf("%s_v%d", name, file.Version)
+	}
+	unit := storage.Join("uploads", dst.BelongTo, dst.IsleName, target.DataType, name)
+	blob, err := e.content.Get(file.BlobHash)
+	if err != nil {
+		return "", "", err
+	}
+	if blob.Entry != "" {
+		unit += path.Ext(blob.Entry) // 单个文件保留扩展名
+	}
+	if storage.Exists(e.backend, unit) {
+		return "", "", fmt.Errorf("%w: 存储位置 %s 已被占用", ErrDataFileConflict, unit)
+	}
+
+	if err := e.content.CopyOut(blob.Hash, unit); err != nil {
+		e.backend.RemoveAll(unit)
+		return "", "", fmt.Errorf("移动文件失败: %w", err)
+	}
+	if blob.Entry != "" {
+		return unit, unit, nil
+	}
+	return unit + strings.TrimPrefix(storage.Key(file.DataPath), blob.Dir), unit, nil
+}
+
+// legacyMove 记录一次旧版文件的移动，失败时用于恢复
+type legacyMove struct {
+	from, to string
+}
+
+// relocate 把旧版文件复制 (keep 为 true) 或移动到 target 所在岛屿的 uploads/<用户>/<岛屿>/<类型> 目录，返回新的 DataPath
+// 文件已经在目标位置时不做任何操作，返回的 legacyMove 为 nil
+func (e *DataFileEditor) relocate(file, target *model.DataFile, keep bool) (string, *legacyMove, error) {
+	src, err := e.isStore.GetByID(file.IsleID)
+	if err != nil {
+		return "", nil, ErrIslandNotFound
+	}
+	dst, err := e.isStore.GetByID(target.IsleID)
+	if err != nil {
+		return "", nil, ErrIslandNotFound
+	}
+
+	// 1. 确定文件在原岛屿目录中占用的对象，以及在目标目录中的位置
+	unit := legacyUnit(file, storage.Join("uploads", src.BelongTo, src.IsleName, file.DataType))
+	name := target.DataName
+	if unit == storage.Key(file.DataPath) {
+		name += path.Ext(unit) // 单个文件保留扩展名
+	}
+	move := &legacyMove{from: unit, to: storage.Join("uploads", dst.BelongTo, dst.IsleName, target.DataType, name)}
+	newPath := move.to + strings.TrimPrefix(storage.Key(file.DataPath), unit)
+	if move.to == move.from {
+		if keep {
+			return "", nil, fmt.Errorf("%w: 存储位置 %s 已被占用", ErrDataFileConflict, move.to)
+		}
+		return newPath, nil, nil
+	}
+	if storage.Exists(e.backend, move.to) {
+		return "", nil, fmt.Errorf("%w: 存储位置 %s 已被占用", ErrDataFileConflict, move.to)
+	}
+
+	// 2. 复制或移动
+	if keep {
+		err = storage.Copy(e.backend, move.from, move.to)
+	} else {
+		err = storage.Move(e.backend, move.from, move.to)
+	}
+	if err != nil {
+		e.backend.RemoveAll(move.to)
+		return "", nil, fmt.Errorf("移动文件失败: %w", err)
+	}
+	return newPath, move, nil
+}
+
+// legacyUnit 旧版文件在岛屿目录中占用的对象：压缩包类型为解压出的文件夹，其余类型为文件本身
+// typeDir 为上传时的 uploads/<用户>/<岛屿>/<类型> 目录
+func legacyUnit(file *model.DataFile, typeDir string) string {
+	key := storage.Key(file.DataPath)
+	if rel := strings.TrimPrefix(key, typeDir+"/"); rel != key {
+		if i := strings.Index(rel, "/"); i >= 0 {
+			return storage.Join(typeDir, rel[:i])
+		}
+		return key
+	}
+	// 不在预期的目录下 (例如岛屿改过名)，按删除文件时的规则处理
+	if ExtractsArchive(file.DataType) {
+		return storage.Dir(key)
+	}
+	return key
+}
+
+// resetMetadata 清空类型相关的元数据，重新解析前调用
+func resetMetadata(file *model.DataFile) {
+	file.BBox = model.BBox{}
+	file.CRS = ""
+	file.Shapefile = nil
+	file.Raster = nil
+	file.Mesh = nil
+	file.Photo = nil
+	file.Weather = nil
+	file.Mapping = nil
+	file.WeatherSteps = nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+)
+
+// Download 数据文件的下载内容：单个对象直接输出，压缩包类型的目录重新打包为 zip
+type Download struct {
+	FileName string // 下载时的文件名，例如 道路.zip
+	Key      string // 单个文件的对象 key，打包下载时为空
+	Dir      string // 需要重新打包的目录，单个文件时为空
+	SHA256   string // 单个文件内容的 SHA-256 (十六进制)，打包下载时为空
+}
+
+// IsArchive 判断下载内容是否需要重新打包
+func (d *Download) IsArchive() bool {
+	return d.Dir != ""
+}
+
+// DownloadService 把数据文件还原为上传时的形式供下载
+type DownloadService struct {
+	dfStore *store.DataFileStore
+	blobs   *store.BlobStore
+	isStore *store.IslandStore
+	backend storage.Backend
+}
+
+func NewDownloadService(dfStore *store.DataFileStore, blobs *store.BlobStore, isStore *store.IslandStore, backend storage.Backend) *DownloadService {
+	return &DownloadService{dfStore: dfStore, blobs: blobs, isStore: isStore, backend: backend}
+}
+
+// Resolve 确定文件的下载内容
+// 内容存储中的文件按 Blob 判断：单文件类型直接下载，压缩包类型下载整个解压目录；旧版文件按所在目录判断
+// 单个文件的 SHA-256 记录在 DataFile 中，早期记录没有时计算一次并保存，之后的请求 (包括续传的 Range 请求) 直接使用
+func (s *DownloadService) Resolve(file *model.DataFile) (*Download, error) {
+	key := storage.Key(file.DataPath)
+	dir, sum := "", file.SHA256
+	if file.BlobHash != "" {
+		blob, err := s.blobs.GetByHash(file.BlobHash)
+		if err != nil {
+			return nil, fmt.Errorf("查询文件内容失败: %w", err)
+		}
+		if blob.Entry == "" {
+			dir = blob.Dir
+		}
+		if sum == "" {
+			sum = blob.SHA256
+		}
+		if sum == "" {
+			sum = blob.Hash // 早期的内容记录以 SHA-256 作为主键
+		}
+	} else {
+		typeDir := ""
+		if island, err := s.isStore.GetByID(file.IsleID); err == nil {
+			typeDir = storage.Join("uploads", island.BelongTo, island.IsleName, file.DataType)
+		}
+		if unit := legacyUnit(file, typeDir); unit != key {
+			dir = unit
+		}
+	}
+
+	if dir != "" {
+		return &Download{FileName: file.DataName + ".zip", Dir: dir}, nil
+	}
+
+	if sum == "" {
+		var err error
+		if sum, err = hashObject(s.backend, key); err != nil {
+			return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+		}
+	}
+	if sum != file.SHA256 {
+		if err := s.dfStore.SetSHA256(file.ID, sum); err != nil {
+			log.Printf("保存文件 %d 的哈希失败: %v", file.ID, err)
+		}
+	}
+
+	d := &Download{FileName: file.DataName, Key: key, SHA256: sum}
+	if ext := path.Ext(key); !strings.EqualFold(path.Ext(d.FileName), ext) {
+		d.FileName += ext
+	}
+	return d, nil
+}
+
+// hashObject 读取存储中的对象并计算 SHA-256
+func hashObject(backend storage.Backend, key string) (string, error) {
+	r, err := backend.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteZip 把下载目录打包写入 w，条目按路径顺序写入
+// 缩略图、GeoJSON 缓存等服务端生成的文件不会被打包
+func (s *DownloadService) WriteZip(w io.Writer, file *model.DataFile, d *Download) error {
+	zw := zip.NewWriter(w)
+	err := s.backend.Walk(d.Dir, func(info storage.ObjectInfo) error {
+		rel := strings.TrimPrefix(info.Key, d.Dir+"/")
+		if storage.IsHidden(rel) || (file.DataType == "shp" && isGeoJSONCache(file.DataPath, info.Key)) {
+			return nil
+		}
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: rel, Method: zip.Deflate, Modified: info.ModTime})
+		if err != nil {
+			return err
+		}
+		r, err := s.backend.Open(info.Key)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(entry, r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
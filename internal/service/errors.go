@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIslandNotFound 关联的岛屿不存在
+var ErrIslandNotFound = errors.New("关联的岛屿不存在")
+
+// ErrDataFileNotFound 引用的数据文件不存在
+var ErrDataFileNotFound = errors.New("文件记录不存在")
+
+// ErrVersionNotFound 版本链中不存在请求的版本
+var ErrVersionNotFound = errors.New("文件版本不存在")
+
+// ErrDataFileConflict 目标岛屿中已有同类型、同名的文件，或目标存储位置已被占用
+var ErrDataFileConflict = errors.New("目标岛屿中已存在同名文件")
+
+// ErrReconcileChanged 修复时重新扫描的结果与确认的预览 (dry-run) 不一致，需要重新预览
+var ErrReconcileChanged = errors.New("存储状态已变化，请重新预览后再修复")
+
+// InputError 表示由客户端输入导致的错误 (例如压缩包内容不符合要求)，handler 应返回 400
+type InputError struct {
+	Msg string
+}
+
+func (e *InputError) Error() string {
+	return e.Msg
+}
+
+// inputErrorf 构造一个 InputError
+func inputErrorf(format string, a ...interface{}) error {
+	return &InputError{Msg: fmt.Sprintf(format, a...)}
+}
+
+// IsInputError 判断错误是否由客户端输入引起
+func IsInputError(err error) bool {
+	var ie *InputError
+	return errors.As(err, &ie)
+}
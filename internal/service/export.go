@@ -0,0 +1,303 @@
+package service
+
+import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/model"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reservedExportKeys 导出 JSON 中岛屿本身使用的字段，处理器的导出字段不能与之重名
+var reservedExportKeys = map[string]bool{
+	"projectName":   true,
+	"crs":           true,
+	"cesiumOrigin":  true,
+	"playPosition":  true,
+	"cameraSetting": true,
+}
+
+// ExportContext 生成导出条目时的参数
+type ExportContext struct {
+	Host string // 文件 URL 使用的主机，例如 localhost:9090
+	CRS  string // 导出坐标系，为空时为 WGS84 经纬度
+}
+
+// URL 将数据库中的路径 (可能是 Windows 反斜杠路径) 转换为完整的 HTTP URL
+// 例如 uploads\user\测试岛1\tif\tileset\tileset.json -> http://10.7.7.2:9090/uploads/user/测试岛1/tif/tileset/tileset.json
+func (ctx ExportContext) URL(filePath string) string {
+	return fmt.Sprintf("http://%s/%s", ctx.Host, strings.ReplaceAll(filePath, "\\", "/"))
+}
+
+// ExportSection 导出 JSON 中一种数据的文件列表，Name 为字段名
+type ExportSection struct {
+	Name    string
+	Entries []interface{}
+}
+
+// BuildExportSections 按处理器的注册顺序生成导出 JSON 中的各个文件列表
+// 所有导出字段都会出现，没有文件时为 [] 而不是 null；列表中的文件按绘制顺序排列
+func BuildExportSections(files []model.DataFile, ctx ExportContext) ([]ExportSection, error) {
+	files = append([]model.DataFile(nil), files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].LayerStyle().DrawOrder < files[j].LayerStyle().DrawOrder
+	})
+
+	var sections []ExportSection
+	index := make(map[string]int)
+	for _, p := range Processors() {
+		if p.Export == nil {
+			continue
+		}
+		if _, ok := index[p.Export.Section]; !ok {
+			index[p.Export.Section] = len(sections)
+			sections = append(sections, ExportSection{Name: p.Export.Section, Entries: []interface{}{}})
+		}
+	}
+
+	for i := range files {
+		file := &files[i]
+		p, ok := LookupProcessor(file.DataType)
+		if !ok || p.Export == nil {
+			continue
+		}
+		entry, err := p.Export.Entry(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		s := &sections[index[p.Export.Section]]
+		s.Entries = append(s.Entries, entry)
+	}
+	return sections, nil
+}
+
+// --- 内置类型的导出条目 ---
+
+// FileEntry 是各类文件列表中的通用条目
+type FileEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// VectorEntry 是 shp 文件的条目，多了 Height 字段
+// 上传时解析出的范围、坐标系等信息用于 Unity 定位相机和提示坐标系不一致，Style 为绘制顺序、颜色等显示样式
+// CRS 始终是文件本身的坐标系；导出指定了坐标系时 BBox 转换到该坐标系，文件坐标系未知或无法转换时为 null
+type VectorEntry struct {
+	Name         string                 `json:"name"`
+	Path         string                 `json:"path"`
+	Height       float64                `json:"height"`
+	BBox         model.BBox             `json:"bbox"`
+	CRS          string                 `json:"crs,omitempty"`
+	Geographic   bool                   `json:"geographic"`
+	GeometryType string                 `json:"geometryType,omitempty"`
+	FeatureCount int                    `json:"featureCount"`
+	Fields       []model.AttributeField `json:"fields,omitempty"`
+	Style        model.LayerStyle       `json:"style"`
+}
+
+// RasterEntry 是 tif 文件的条目，多了 Height 字段
+// 范围和层级来自上传时对索引文件的解析，Style 中只有绘制顺序、可见性和不透明度有意义；BBox 的坐标系同 VectorEntry
+type RasterEntry struct {
+	Name   string           `json:"name"`
+	Path   string           `json:"path"`
+	Height float64          `json:"height"`
+	BBox   model.BBox       `json:"bbox"`
+	CRS    string           `json:"crs,omitempty"`
+	Format string           `json:"format,omitempty"`
+	Levels int              `json:"levels"`
+	Style  model.LayerStyle `json:"style"`
+}
+
+// ModelEntry 是 models 文件的条目，glTF/GLB 模型附带上传时统计的网格信息
+type ModelEntry struct {
+	Name  string           `json:"name"`
+	Path  string           `json:"path"`
+	Stats *model.ModelMeta `json:"stats,omitempty"`
+}
+
+// PictureEntry 是 jpg 文件的条目，带有 GPS 的照片附带拍摄位置，Unity 据此放置广告牌
+// 指定导出坐标系时，lat/lon 为该坐标系下的 Y/X
+type PictureEntry struct {
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	Located    bool       `json:"located"` // 是否带有位置，为 false 时 lat/lon 无意义
+	Lat        float64    `json:"lat"`
+	Lon        float64    `json:"lon"`
+	Altitude   *float64   `json:"altitude"`
+	Heading    *float64   `json:"heading"`
+	CapturedAt *time.Time `json:"capturedAt"`
+}
+
+// WeatherEntry 是 weather 文件的条目，SeriesURL 为时间序列查询接口，Unity 可以按时间段流式拉取
+type WeatherEntry struct {
+	Name            string                  `json:"name"`
+	Path            string                  `json:"path"`
+	SeriesURL       string                  `json:"seriesUrl"`
+	Start           *time.Time              `json:"start"`
+	End             *time.Time              `json:"end"`
+	IntervalSeconds float64                 `json:"intervalSeconds"`
+	Variables       []model.WeatherVariable `json:"variables"`
+}
+
+// MappingEntry 是 mapping 文件的条目，附带上传时识别的编码、分隔符和列类型，Unity 按此解析 CSV
+type MappingEntry struct {
+	Name      string                `json:"name"`
+	Path      string                `json:"path"`
+	Encoding  string                `json:"encoding,omitempty"`
+	Delimiter string                `json:"delimiter,omitempty"`
+	Columns   []model.MappingColumn `json:"columns,omitempty"`
+}
+
+// exportFileEntry 只包含名称和路径的条目，useURL 为 true 时路径转换为完整 URL
+func exportFileEntry(useURL bool) func(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	return func(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+		entry := FileEntry{Name: file.DataName, Path: file.DataPath}
+		if useURL {
+			entry.Path = ctx.URL(file.DataPath)
+		}
+		return entry, nil
+	}
+}
+
+func exportVector(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	entry := VectorEntry{
+		Name:   file.DataName,
+		Path:   file.DataPath, // 直接使用数据库中的路径
+		Height: file.Height,
+		BBox:   exportBBox(file, ctx.CRS),
+		CRS:    file.CRS,
+		Style:  file.LayerStyle(),
+	}
+	if meta := file.Shapefile; meta != nil {
+		entry.Geographic = meta.Geographic
+		entry.GeometryType = meta.GeometryType
+		entry.FeatureCount = meta.FeatureCount
+		entry.Fields = meta.Fields
+	}
+	return entry, nil
+}
+
+func exportRaster(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	entry := RasterEntry{
+		Name: file.DataName,
+		// 将路径转换为静态服务 URL
+		Path:   ctx.URL(file.DataPath),
+		Height: file.Height,
+		BBox:   exportBBox(file, ctx.CRS),
+		CRS:    file.CRS,
+		Style:  file.LayerStyle(),
+	}
+	if meta := file.Raster; meta != nil {
+		entry.Format = meta.Format
+		entry.Levels = meta.Levels
+	}
+	return entry, nil
+}
+
+func exportModel(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	return ModelEntry{
+		Name: file.DataName,
+		// 模型压缩包解压后，指向主模型文件的静态服务 URL
+		Path:  ctx.URL(file.DataPath),
+		Stats: file.Mesh,
+	}, nil
+}
+
+func exportPicture(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	entry := PictureEntry{
+		Name: file.DataName,
+		Path: file.DataPath, // 直接使用数据库中的路径
+	}
+	if err := fillPicturePosition(&entry, file.Photo, ctx.CRS); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func exportWeather(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	entry := WeatherEntry{
+		Name:      file.DataName,
+		Path:      file.DataPath,
+		SeriesURL: ctx.URL(fmt.Sprintf("api/v1/data-files/%d/weather", file.ID)),
+	}
+	if meta := file.Weather; meta != nil {
+		entry.Start = &meta.Start
+		entry.End = &meta.End
+		entry.IntervalSeconds = meta.IntervalSeconds
+		entry.Variables = meta.Variables
+	}
+	return entry, nil
+}
+
+func exportMapping(ctx ExportContext, file *model.DataFile) (interface{}, error) {
+	entry := MappingEntry{
+		Name: file.DataName,
+		Path: file.DataPath,
+	}
+	if meta := file.Mapping; meta != nil {
+		entry.Encoding = meta.Encoding
+		entry.Delimiter = meta.Delimiter
+		entry.Columns = meta.Columns
+	}
+	return entry, nil
+}
+
+// bboxSamples 转换范围时每条边上的采样点数：投影后矩形的边会弯曲，只转换四个角点会漏掉边上的极值
+const bboxSamples = 16
+
+// exportBBox 返回文件在导出坐标系 crs 下的范围，crs 为空时为文件本身的范围
+// 范围的四条边各取 bboxSamples 个点转换后取外包矩形；文件坐标系未知或无法转换时返回无效范围 (导出为 null)
+func exportBBox(file *model.DataFile, crs string) model.BBox {
+	b := file.BBox
+	if crs == "" || !b.Valid || file.CRS == crs {
+		return b
+	}
+	from, err := geo.NormalizeCRS(file.CRS)
+	if err != nil {
+		return model.BBox{}
+	}
+	out := model.BBox{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1), Valid: true}
+	for i := 0; i <= bboxSamples; i++ {
+		t := float64(i) / bboxSamples
+		x := b.MinX + (b.MaxX-b.MinX)*t
+		y := b.MinY + (b.MaxY-b.MinY)*t
+		for _, p := range [][2]float64{{x, b.MinY}, {x, b.MaxY}, {b.MinX, y}, {b.MaxX, y}} {
+			px, py, err := geo.Transform(from, crs, p[0], p[1])
+			if err != nil {
+				return model.BBox{}
+			}
+			out.MinX, out.MaxX = math.Min(out.MinX, px), math.Max(out.MaxX, px)
+			out.MinY, out.MaxY = math.Min(out.MinY, py), math.Max(out.MaxY, py)
+		}
+	}
+	return out
+}
+
+// fillPicturePosition 把照片 EXIF 中的位置写入导出条目，指定 crs 时转换到该坐标系
+func fillPicturePosition(entry *PictureEntry, photo *model.PhotoMeta, crs string) error {
+	if photo == nil {
+		return nil
+	}
+	entry.Altitude = photo.Altitude
+	entry.Heading = photo.Heading
+	entry.CapturedAt = photo.CapturedAt
+	if !photo.HasGPS {
+		return nil
+	}
+	entry.Located = true
+	entry.Lat, entry.Lon = photo.Lat, photo.Lon
+	if crs == "" {
+		return nil
+	}
+	x, y, err := ConvertPoint(geo.CRSWGS84, crs, photo.Lon, photo.Lat)
+	if err != nil {
+		return inputErrorf("转换照片 %s 的位置失败: %v", entry.Name, err)
+	}
+	entry.Lon, entry.Lat = x, y
+	return nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GeoJSONRequest shp 转 GeoJSON 的可选参数
+type GeoJSONRequest struct {
+	BBox      *[4]float64 // 范围过滤 minX,minY,maxX,maxY
+	Fields    []string    // 属性字段选择，nil 表示全部
+	Precision int         // 坐标小数位数，小于 0 表示保留原始精度
+
+	// 附加属性 (例如关联的 CSV 属性)，Variant 标识附加属性的来源和内容，参与缓存 key
+	Variant string
+	Extra   func(values []interface{}) map[string]interface{}
+}
+
+// cacheSuffix 不同参数的转换结果分别缓存，默认参数使用 <图层名>.geojson
+func (r GeoJSONRequest) cacheSuffix() string {
+	if r.BBox == nil && r.Fields == nil && r.Precision < 0 && r.Variant == "" {
+		return ".geojson"
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "bbox=%v;fields=%q;precision=%d", r.BBox, r.Fields, r.Precision)
+	if r.Variant != "" {
+		fmt.Fprintf(h, ";variant=%s", r.Variant)
+	}
+	return "." + hex.EncodeToString(h.Sum(nil))[:12] + ".geojson"
+}
+
+// isGeoJSONCache 判断 key 是否为 shpPath 图层的 GeoJSON 缓存 (<图层名>.geojson 或 <图层名>.<摘要>.geojson)
+func isGeoJSONCache(shpPath, key string) bool {
+	shpKey, key := storage.Key(shpPath), storage.Key(key)
+	base := strings.TrimSuffix(shpKey, path.Ext(shpKey))
+	if key == base+".geojson" {
+		return true
+	}
+	digest := strings.TrimSuffix(strings.TrimPrefix(key, base+"."), ".geojson")
+	if len(digest) != 12 || key != base+"."+digest+".geojson" {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
+// removeGeoJSONCaches 删除 shpPath 图层的所有 GeoJSON 缓存
+func removeGeoJSONCaches(backend storage.Backend, shpPath string) error {
+	var keys []string
+	err := backend.Walk(storage.Dir(storage.Key(shpPath)), func(obj storage.ObjectInfo) error {
+		if isGeoJSONCache(shpPath, obj.Key) {
+			keys = append(keys, obj.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := backend.Remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GeoJSONService 把已上传的 shp 图层转换为 GeoJSON，结果缓存在 shp 所在的解压目录中
+// 内容存储中的文件不会被修改，同一 Blob 的缓存可以被所有引用它的 DataFile 复用
+type GeoJSONService struct {
+	backend storage.Backend
+	locks   keyedMutex // 同一缓存文件只转换一次
+}
+
+func NewGeoJSONService(backend storage.Backend) *GeoJSONService {
+	return &GeoJSONService{backend: backend}
+}
+
+// Convert 返回转换结果在存储中的 key，已有缓存时直接返回
+func (s *GeoJSONService) Convert(file *model.DataFile, req GeoJSONRequest) (string, error) {
+	if file.DataType != "shp" {
+		return "", inputErrorf("只有 shp 类型的数据可以转换为 GeoJSON")
+	}
+	shpKey := storage.Key(file.DataPath)
+	cacheKey := strings.TrimSuffix(shpKey, path.Ext(shpKey)) + req.cacheSuffix()
+
+	unlock := s.locks.lock(cacheKey)
+	defer unlock()
+	if _, err := s.backend.Stat(cacheKey); err == nil {
+		return cacheKey, nil
+	}
+
+	// 1. 打开 .shp 和可选的 .dbf
+	shpFile, err := s.backend.Open(shpKey)
+	if err != nil {
+		return "", fmt.Errorf("读取 shp 文件失败: %w", err)
+	}
+	defer shpFile.Close()
+	shp, err := geo.NewShpReader(shpFile)
+	if err != nil {
+		return "", err
+	}
+
+	dbf, closeDBF, err := openDBF(s.backend, shpKey)
+	if err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return "", err
+	}
+	defer closeDBF()
+
+	// 2. 先写到本地临时文件，完成后再放入存储
+	stagingDir, err := NewStagingDir("geojson-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stagingDir)
+	tmpPath := filepath.Join(stagingDir, path.Base(cacheKey))
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	_, err = geo.WriteGeoJSON(out, shp, dbf, geo.GeoJSONOptions{
+		BBox:      req.BBox,
+		Fields:    req.Fields,
+		Precision: req.Precision,
+		CRS:       file.CRS,
+		Extra:     req.Extra,
+	})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if errors.Is(err, geo.ErrUnknownField) {
+			return "", &InputError{Msg: err.Error()}
+		}
+		return "", fmt.Errorf("转换 GeoJSON 失败: %w", err)
+	}
+
+	if err := s.backend.Import(tmpPath, cacheKey); err != nil {
+		return "", fmt.Errorf("保存 GeoJSON 缓存失败: %w", err)
+	}
+	return cacheKey, nil
+}
+
+// openDBF 打开 shp 的属性表，编码优先使用 .cpg 中的声明；没有 .dbf 时返回 storage.ErrNotExist
+// 返回的关闭函数总是可以调用
+func openDBF(backend storage.Backend, shpKey string) (*geo.DBFReader, func(), error) {
+	noop := func() {}
+	dbfKey, err := findSidecar(backend, shpKey, ".dbf")
+	if err != nil {
+		return nil, noop, err
+	}
+	dbfFile, err := backend.Open(dbfKey)
+	if err != nil {
+		return nil, noop, fmt.Errorf("读取 dbf 文件失败: %w", err)
+	}
+	var decode geo.Decoder
+	if cpg, err := readSidecar(backend, shpKey, ".cpg"); err == nil {
+		decode = geo.DecoderFor(cpg, 0)
+	}
+	dbf, err := geo.NewDBFReader(dbfFile, decode)
+	if err != nil {
+		dbfFile.Close()
+		return nil, noop, err
+	}
+	return dbf, func() { dbfFile.Close() }, nil
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"Go_for_unity/internal/archive"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stagingRoot 上传过程中临时文件的根目录，由 SetStagingDir 根据配置设置
+// 本地存储时默认放在 <storage.local.root>/uploads 下，与最终存储目录在同一文件系统，移动文件时直接 rename 即可
+var stagingRoot = filepath.Join("uploads", ".staging")
+
+// SetStagingDir 设置临时文件的根目录：普通临时文件放在 <dir>/.staging，分片上传的分片放在 <dir>/.sessions
+func SetStagingDir(dir string) {
+	stagingRoot = filepath.Join(dir, ".staging")
+	sessionRoot = filepath.Join(dir, ".sessions")
+}
+
+// NewStagingDir 在临时目录下创建一个唯一的子目录，调用方负责清理
+func NewStagingDir(prefix string) (string, error) {
+	if err := os.MkdirAll(stagingRoot, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(stagingRoot, prefix)
+}
+
+// IngestRequest 描述一个已经完整落盘、等待处理的上传文件
+type IngestRequest struct {
+	IsleID   uint
+	DataType string
+	Height   float64
+	FileName string               // 用户上传时的原始文件名
+	SrcPath  string               // 文件当前所在的本地路径，处理后会被移走或删除
+	Progress archive.ProgressFunc // 解压进度回调，可为 nil
+	Stage    func(status string)  // 处理阶段回调 (model.JobStatusExtracting / JobStatusIndexing)，可为 nil
+}
+
+// stage 通知调用方进入新的处理阶段
+func (r IngestRequest) stage(status string) {
+	if r.Stage != nil {
+		r.Stage(status)
+	}
+}
+
+// Ingestor 负责把上传的文件按类型处理 (解压、查找索引文件) 并创建 DataFile 记录
+// 普通表单上传和分片上传最终都会走到这里；内容按 SHA-256 存入 ContentStore，相同内容只保存一份
+type Ingestor struct {
+	dfStore *store.DataFileStore
+	isStore *store.IslandStore
+	content *ContentStore
+	backend storage.Backend
+	thumbs  *ThumbnailService
+	lineage keyedMutex // 按岛屿、类型和名称加锁，保证同名文件的版本号依次分配
+}
+
+func NewIngestor(dfStore *store.DataFileStore, isStore *store.IslandStore, content *ContentStore, backend storage.Backend, thumbs *ThumbnailService) *Ingestor {
+	return &Ingestor{dfStore: dfStore, isStore: isStore, content: content, backend: backend, thumbs: thumbs}
+}
+
+// ingestResult 一次 Ingest 的结果，批量导入撤销时需要其中的版本和内容信息
+type ingestResult struct {
+	file       *model.DataFile
+	previousID uint // 被新版本取代的原生效版本 ID，开始新版本链时为 0
+	reused     bool // 内容是否复用了已有的 Blob
+}
+
+// Ingest 处理上传文件并创建数据库记录
+func (i *Ingestor) Ingest(req IngestRequest) (*model.DataFile, error) {
+	res, err := i.ingest(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.file, nil
+}
+
+func (i *Ingestor) ingest(req IngestRequest) (*ingestResult, error) {
+	defer os.Remove(req.SrcPath) // 命中已有内容时源文件不会被移动，这里统一清理
+
+	// --- 1. 校验岛屿和文件类型 ---
+	if _, err := i.isStore.GetByID(req.IsleID); err != nil {
+		return nil, ErrIslandNotFound
+	}
+	if err := ValidateUpload(req.DataType, req.FileName); err != nil {
+		return nil, err
+	}
+	if err := CheckContentFile(req.DataType, req.SrcPath, req.FileName); err != nil {
+		return nil, err
+	}
+	proc, _ := LookupProcessor(req.DataType)
+
+	// --- 2. 计算内容哈希，写入或复用内容存储 ---
+	req.stage(model.JobStatusExtracting)
+	hash, size, err := HashFile(req.SrcPath)
+	if err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+	blob, reused, err := i.content.Acquire(hash, size, proc.IsArchive(req.FileName), i.fillFunc(req, proc))
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		log.Printf("文件 %s 与已有内容 %s 相同，直接复用", req.FileName, blob.Hash)
+	}
+
+	// --- 3. 根据文件类型定位最终要存入数据库的文件路径 ---
+	req.stage(model.JobStatusIndexing)
+	finalFilePath, err := i.locateDataPath(req, proc, blob)
+	if err != nil {
+		i.releaseBlob(blob.Hash)
+		return nil, err
+	}
+
+	// --- 4. 创建数据库记录 ---
+	// 首先获取纯数据名
+	cleanDataName := strings.TrimSuffix(req.FileName, filepath.Ext(req.FileName))
+	dataFile := model.DataFile{
+		DataName: cleanDataName,
+		DataType: req.DataType,
+		DataPath: finalFilePath,
+		IsleID:   req.IsleID,
+		Height:   req.Height,
+		BlobHash: blob.Hash,
+	}
+	if !proc.IsArchive(req.FileName) {
+		dataFile.SHA256 = hash
+	}
+
+	// --- 5. 解析类型相关的元数据 ---
+	if err := i.extractMetadata(&dataFile); err != nil {
+		i.releaseBlob(blob.Hash)
+		return nil, err
+	}
+
+	// --- 6. 写入版本链：已有同名文件时成为它的新版本，旧版本保留 ---
+	previousID, err := i.createVersion(&dataFile)
+	if err != nil {
+		i.releaseBlob(blob.Hash)
+		return nil, fmt.Errorf("数据库记录创建失败: %w", err)
+	}
+	if dataFile.Version > 1 {
+		log.Printf("文件 %s 已存在，创建为第 %d 个版本", dataFile.DataName, dataFile.Version)
+	}
+	return &ingestResult{file: &dataFile, previousID: previousID, reused: reused}, nil
+}
+
+// fillFunc 返回首次写入内容时的处理方式：压缩包类型解压，其余类型直接保存文件
+func (i *Ingestor) fillFunc(req IngestRequest, proc *Processor) FillFunc {
+	return func(dir string) (string, error) {
+		if proc.IsArchive(req.FileName) {
+			if err := archive.Extract(req.SrcPath, dir, archive.ExtractOptions{Progress: req.Progress}); err != nil {
+				return "", fmt.Errorf("解压文件失败: %w", err)
+			}
+			return "", nil
+		}
+		if err := moveFile(req.SrcPath, filepath.Join(dir, req.FileName)); err != nil {
+			return "", fmt.Errorf("保存文件失败: %w", err)
+		}
+		return req.FileName, nil
+	}
+}
+
+// extractMetadata 按类型处理器校验文件并把元数据填充到 dataFile
+func (i *Ingestor) extractMetadata(dataFile *model.DataFile) error {
+	proc, ok := LookupProcessor(dataFile.DataType)
+	if !ok {
+		return inputErrorf("不支持的文件类型: %s", dataFile.DataType)
+	}
+	if proc.Extract == nil {
+		return nil
+	}
+	return proc.Extract(ProcessEnv{Backend: i.backend, Thumbs: i.thumbs}, dataFile)
+}
+
+// createVersion 把记录写入版本链，同一岛屿下同类型、同名的文件串行分配版本号，返回被取代的原生效版本 ID
+func (i *Ingestor) createVersion(dataFile *model.DataFile) (uint, error) {
+	unlock := i.lineage.lock(lineageKey(dataFile))
+	defer unlock()
+	return i.dfStore.CreateVersion(dataFile)
+}
+
+// lineageKey 版本链的逻辑标识：岛屿、类型和名称
+func lineageKey(dataFile *model.DataFile) string {
+	return fmt.Sprintf("%d/%s/%s", dataFile.IsleID, dataFile.DataType, dataFile.DataName)
+}
+
+// releaseBlob 处理失败时释放刚刚获取的内容引用
+func (i *Ingestor) releaseBlob(hash string) {
+	if err := i.content.Release(hash); err != nil {
+		log.Printf("释放内容 %s 失败: %v", hash, err)
+	}
+}
+
+// removeDerived 删除由文件生成的缩略图和 GeoJSON 缓存
+func (i *Ingestor) removeDerived(file *model.DataFile) {
+	if i.thumbs != nil && IsThumbnailSource(file.DataPath) {
+		i.thumbs.Remove(file.DataPath)
+	}
+	if file.DataType == "shp" {
+		if err := removeGeoJSONCaches(i.backend, file.DataPath); err != nil {
+			log.Printf("删除 GeoJSON 缓存失败 %s: %v", file.DataPath, err)
+		}
+	}
+}
+
+// locateDataPath 在内容目录中查找 DataPath 应该指向的文件
+func (i *Ingestor) locateDataPath(req IngestRequest, proc *Processor, blob *model.Blob) (string, error) {
+	if !proc.IsArchive(req.FileName) {
+		// 单文件类型，内容目录中只有一个文件
+		return storage.Join(blob.Dir, blob.Entry), nil
+	}
+	return proc.Locate(i.backend, blob.Dir, req.FileName)
+}
+
+// Task 把一次 Ingest 包装成后台任务，阶段和解压进度汇报给任务，结束后删除 stagingDir
+func (i *Ingestor) Task(req IngestRequest, stagingDir string) JobTask {
+	return func(p *JobProgress) (*model.DataFile, error) {
+		defer os.RemoveAll(stagingDir)
+		req.Progress = p.Extract
+		req.Stage = p.Stage
+		return i.Ingest(req)
+	}
+}
+
+// UploadJob 根据上传请求构造任务记录
+func UploadJob(req IngestRequest) model.Job {
+	return model.Job{
+		Kind:     JobKindUpload,
+		IsleID:   req.IsleID,
+		DataType: req.DataType,
+		FileName: req.FileName,
+	}
+}
+
+// --- Helper Functions ---
+
+// moveFile 移动文件，跨文件系统时退化为复制后删除
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// findFileByExt 在存储目录中查找指定后缀的文件
+func findFileByExt(backend storage.Backend, rootDir, ext string) (string, error) {
+	var foundPath string
+	err := backend.Walk(rootDir, func(obj storage.ObjectInfo) error {
+		if strings.HasSuffix(strings.ToLower(obj.Key), ext) {
+			foundPath = obj.Key
+			return storage.SkipAll // 找到后停止遍历
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if foundPath == "" {
+		return "", fmt.Errorf("no file with extension %s found", ext)
+	}
+	return foundPath, nil
+}
+
+// findRasterIndexFile 在解压目录中查找影像或道路模型的索引文件 (.xml 或 .json)
+// 它要求索引文件名必须与原始 zip 包的文件名（不含后缀）相同。
+func findRasterIndexFile(backend storage.Backend, unzipDest, baseName string) (string, error) {
+	// 1. 尝试查找 .xml 文件
+	xmlPath := storage.Join(unzipDest, baseName+".xml")
+	if _, err := backend.Stat(xmlPath); err == nil {
+		// 文件存在，返回路径
+		return xmlPath, nil
+	}
+
+	// 2. 如果 .xml 不存在，尝试查找 .json 文件
+	jsonPath := storage.Join(unzipDest, baseName+".json")
+	if _, err := backend.Stat(jsonPath); err == nil {
+		// 文件存在，返回路径
+		return jsonPath, nil
+	}
+
+	// 3. 如果两者都不存在，返回错误
+	return "", fmt.Errorf("在压缩包中未找到索引文件 %s.xml 或 %s.json", baseName, baseName)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/store"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	JobKindUpload = "upload" // 上传文件处理任务
+	JobKindBatch  = "batch"  // 整个岛屿的批量导入任务
+)
+
+// ErrQueueFull 任务队列已满
+var ErrQueueFull = errors.New("后台任务队列已满，请稍后重试")
+
+// Notifier 用于推送任务事件，ws.Manager 实现了该接口
+// 进度事件很频繁，实现方应在没有客户端连接时直接丢弃，不逐条写日志
+type Notifier interface {
+	Publish(message interface{})
+}
+
+// JobEvent 通过 WebSocket 推送的任务进度事件
+type JobEvent struct {
+	Type string     `json:"type"` // 固定为 "job"
+	Job  *model.Job `json:"job"`
+}
+
+// JobTask 在后台 worker 中执行的任务，通过 JobProgress 汇报阶段和进度
+type JobTask func(p *JobProgress) (*model.DataFile, error)
+
+type queuedJob struct {
+	job  *model.Job
+	task JobTask
+}
+
+// JobRunner 使用固定数量的 worker 在后台执行任务，任务状态持久化到数据库并通过 WebSocket 推送
+type JobRunner struct {
+	store    *store.JobStore
+	notifier Notifier
+	queue    chan queuedJob
+	workers  int
+}
+
+func NewJobRunner(store *store.JobStore, notifier Notifier, workers, queueSize int) *JobRunner {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &JobRunner{
+		store:    store,
+		notifier: notifier,
+		queue:    make(chan queuedJob, queueSize),
+		workers:  workers,
+	}
+}
+
+// Start 启动 worker，并把上次服务退出时未完成的任务标记为失败
+func (r *JobRunner) Start() {
+	if n, err := r.store.FailUnfinished("服务重启，任务被中断，请重新上传"); err != nil {
+		log.Printf("清理未完成的任务失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已将 %d 个被中断的任务标记为失败", n)
+	}
+
+	for i := 0; i < r.workers; i++ {
+		go r.work()
+	}
+}
+
+// Submit 创建任务记录并放入队列，立即返回排队时的任务快照
+// job 只需要填写 Kind/IsleID/DataType/FileName
+func (r *JobRunner) Submit(job model.Job, task JobTask) (*model.Job, error) {
+	job.ID = newID()
+	job.Status = model.JobStatusQueued
+	if err := r.store.Create(&job); err != nil {
+		return nil, fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	// 入队之后 worker 会修改 running，返回给调用方的是入队前的快照
+	snapshot := job
+	running := job
+	select {
+	case r.queue <- queuedJob{job: &running, task: task}:
+		r.notify(&snapshot)
+		return &snapshot, nil
+	default:
+		snapshot.Status = model.JobStatusFailed
+		snapshot.Error = ErrQueueFull.Error()
+		r.save(&snapshot)
+		return nil, ErrQueueFull
+	}
+}
+
+func (r *JobRunner) work() {
+	for q := range r.queue {
+		r.run(q)
+	}
+}
+
+func (r *JobRunner) run(q queuedJob) {
+	p := &JobProgress{runner: r, job: q.job}
+
+	var dataFile *model.DataFile
+	var err error
+	func() {
+		// 任务 panic 时不能拖垮整个 worker
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("任务执行异常: %v", rec)
+			}
+		}()
+		dataFile, err = q.task(p)
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		q.job.Status = model.JobStatusFailed
+		q.job.Error = err.Error()
+		log.Printf("任务 %s (%s) 失败: %v", q.job.ID, q.job.FileName, err)
+	} else {
+		q.job.Status = model.JobStatusReady
+		q.job.Progress = 100
+		if dataFile != nil {
+			q.job.DataFileID = dataFile.ID
+		}
+	}
+	r.save(q.job)
+	r.notify(q.job)
+}
+
+func (r *JobRunner) save(job *model.Job) {
+	if err := r.store.Update(job); err != nil {
+		log.Printf("保存任务 %s 状态失败: %v", job.ID, err)
+	}
+}
+
+func (r *JobRunner) notify(job *model.Job) {
+	if r.notifier == nil {
+		return
+	}
+	snapshot := *job
+	r.notifier.Publish(JobEvent{Type: "job", Job: &snapshot})
+}
+
+// JobProgress 任务执行过程中用于汇报阶段和进度
+type JobProgress struct {
+	runner    *JobRunner
+	job       *model.Job
+	mu        sync.Mutex
+	lastSaved time.Time
+}
+
+// Stage 切换任务阶段 (extracting / indexing)，会立即持久化并推送
+func (p *JobProgress) Stage(status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.job.Status == status {
+		return
+	}
+	p.job.Status = status
+	p.flush()
+}
+
+// Extract 解压进度回调，可直接作为 archive.ProgressFunc 使用
+// 为了减少数据库写入，进度最多每秒持久化一次
+func (p *JobProgress) Extract(done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	percent := float64(100)
+	if total > 0 {
+		percent = float64(done) * 100 / float64(total)
+	}
+	p.job.Progress = percent
+	if percent < 100 && time.Since(p.lastSaved) < time.Second {
+		return
+	}
+	p.flush()
+}
+
+// Report 更新批量导入的逐项结果，会立即持久化并推送
+func (p *JobProgress) Report(report *model.BatchReport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snapshot := *report
+	snapshot.Items = append([]model.BatchItem(nil), report.Items...)
+	p.job.Report = &snapshot
+	p.flush()
+}
+
+func (p *JobProgress) flush() {
+	p.lastSaved = time.Now()
+	p.runner.save(p.job)
+	p.runner.notify(p.job)
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"Go_for_unity/internal/table"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxJoinSamples 关联统计中每类关键值示例的最大数量
+const maxJoinSamples = 20
+
+// JoinRequest 创建 CSV 与 shp 关联的参数
+type JoinRequest struct {
+	MappingFileID uint   `json:"mapping_file_id" binding:"required"`
+	ShpFileID     uint   `json:"shp_file_id" binding:"required"`
+	CSVKey        string `json:"csv_key" binding:"required"` // CSV 中的关键列
+	ShpKey        string `json:"shp_key" binding:"required"` // dbf 中的关键字段
+}
+
+// JoinLookup 以关键值为索引的关联属性表，只包含能匹配到要素的 CSV 行
+type JoinLookup struct {
+	MappingFileID uint                              `json:"mapping_file_id"` // 本次读取使用的 CSV 版本 (版本链的生效版本)
+	ShpFileID     uint                              `json:"shp_file_id"`     // 本次读取使用的 shp 版本
+	ShpKey        string                            `json:"shp_key"`
+	Columns       []model.MappingColumn             `json:"columns"` // 关联的 CSV 列，与 dbf 字段同名时加 _csv 后缀
+	Rows          map[string]map[string]interface{} `json:"rows"`
+}
+
+// JoinService 管理 mapping (CSV) 与 shp 图层的属性关联
+// 关键值统一转换为字符串比较，dbf 关键字段为数值类型时 CSV 中的值按数值规范化 (例如 "12.0" 与 12 匹配)
+// 关联保存的是版本链，读取时总是使用两个版本链当前的生效版本
+type JoinService struct {
+	joins   *store.MappingJoinStore
+	dfStore *store.DataFileStore
+	backend storage.Backend
+	mapping *MappingService
+	geojson *GeoJSONService
+}
+
+func NewJoinService(joins *store.MappingJoinStore, dfStore *store.DataFileStore, backend storage.Backend, mapping *MappingService, geojson *GeoJSONService) *JoinService {
+	return &JoinService{joins: joins, dfStore: dfStore, backend: backend, mapping: mapping, geojson: geojson}
+}
+
+// joinSource 一次关联涉及的两个文件和关键字段的位置
+type joinSource struct {
+	mapping    *model.DataFile
+	shp        *model.DataFile
+	csvIndex   int  // 关键列在 CSV 中的位置
+	shpIndex   int  // 关键字段在 dbf 中的位置
+	numericKey bool // dbf 关键字段为数值类型
+}
+
+// csvRows 按关键值索引的 CSV 行
+type csvRows struct {
+	columns    []model.MappingColumn // 除关键列外的列，已处理重名
+	rows       map[string]map[string]interface{}
+	keys       []string // 关键值，按 CSV 中的顺序
+	duplicates []string
+	dupCount   int
+}
+
+// Create 校验文件和关键字段，统计匹配情况并保存关联；没有任何要素匹配时返回 InputError
+// 请求中的文件可以是版本链中的任意版本，统计和之后的读取都使用生效版本
+func (s *JoinService) Create(req JoinRequest) (*model.MappingJoin, error) {
+	mapping, err := s.dfStore.GetByID(req.MappingFileID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: mapping 文件 %d", ErrDataFileNotFound, req.MappingFileID)
+	}
+	shp, err := s.dfStore.GetByID(req.ShpFileID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: shp 文件 %d", ErrDataFileNotFound, req.ShpFileID)
+	}
+	src, err := s.resolve(mapping.LineageID, shp.LineageID, req.CSVKey, req.ShpKey)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.loadRows(src)
+	if err != nil {
+		return nil, err
+	}
+
+	join := &model.MappingJoin{
+		IsleID:           src.shp.IsleID,
+		MappingLineageID: src.mapping.LineageID,
+		ShpLineageID:     src.shp.LineageID,
+		MappingFileID:    src.mapping.ID,
+		ShpFileID:        src.shp.ID,
+		CSVKey:           req.CSVKey,
+		ShpKey:           req.ShpKey,
+		RowCount:         len(rows.keys) + rows.dupCount,
+		DuplicateKeys:    rows.dupCount,
+	}
+	join.Samples.DuplicateKeys = rows.duplicates
+
+	// 遍历 dbf 统计要素的匹配情况
+	matched := make(map[string]bool)
+	err = s.scanFeatureKeys(src, func(key string) {
+		join.FeatureCount++
+		if _, ok := rows.rows[key]; ok {
+			join.MatchedFeatures++
+			matched[key] = true
+			return
+		}
+		join.UnmatchedFeatures++
+		if len(join.Samples.UnmatchedFeatureKeys) < maxJoinSamples {
+			join.Samples.UnmatchedFeatureKeys = append(join.Samples.UnmatchedFeatureKeys, key)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range rows.keys {
+		if matched[key] {
+			continue
+		}
+		join.UnmatchedRows++
+		if len(join.Samples.UnmatchedRowKeys) < maxJoinSamples {
+			join.Samples.UnmatchedRowKeys = append(join.Samples.UnmatchedRowKeys, key)
+		}
+	}
+
+	if join.MatchedFeatures == 0 {
+		return nil, inputErrorf("CSV 列 %s 与 dbf 字段 %s 没有任何匹配的值 (要素示例: %s; CSV 示例: %s)",
+			req.CSVKey, req.ShpKey,
+			strings.Join(firstN(join.Samples.UnmatchedFeatureKeys, 5), ", "),
+			strings.Join(firstN(join.Samples.UnmatchedRowKeys, 5), ", "))
+	}
+	if err := s.joins.Create(join); err != nil {
+		return nil, fmt.Errorf("保存关联失败: %w", err)
+	}
+	return join, nil
+}
+
+// Get 根据 ID 查询关联
+func (s *JoinService) Get(id uint) (*model.MappingJoin, error) {
+	return s.joins.GetByID(id)
+}
+
+// List 查询岛屿下的关联，fileID 不为 0 时只返回涉及该文件所在版本链的关联
+func (s *JoinService) List(isleID, fileID uint) ([]model.MappingJoin, error) {
+	if fileID == 0 {
+		return s.joins.ListByIsleID(isleID, 0)
+	}
+	file, err := s.dfStore.GetByID(fileID)
+	if err != nil {
+		return []model.MappingJoin{}, nil
+	}
+	return s.joins.ListByIsleID(isleID, file.LineageID)
+}
+
+// Delete 删除关联，已生成的 GeoJSON 缓存保留在 shp 目录中，可被相同的关联复用
+func (s *JoinService) Delete(id uint) error {
+	return s.joins.Delete(id)
+}
+
+// GeoJSON 输出带有关联属性的 GeoJSON，没有匹配的要素对应属性为 null
+func (s *JoinService) GeoJSON(join *model.MappingJoin, req GeoJSONRequest) (string, error) {
+	src, err := s.resolve(join.MappingLineageID, join.ShpLineageID, join.CSVKey, join.ShpKey)
+	if err != nil {
+		return "", err
+	}
+	rows, err := s.loadRows(src)
+	if err != nil {
+		return "", err
+	}
+
+	empty := make(map[string]interface{}, len(rows.columns))
+	for _, col := range rows.columns {
+		empty[col.Name] = nil
+	}
+	req.Variant = joinVariant(src)
+	req.Extra = func(values []interface{}) map[string]interface{} {
+		if src.shpIndex < len(values) {
+			if row, ok := rows.rows[featureKey(values[src.shpIndex])]; ok {
+				return row
+			}
+		}
+		return empty
+	}
+	return s.geojson.Convert(src.shp, req)
+}
+
+// Lookup 返回以关键值为索引的关联属性表
+func (s *JoinService) Lookup(join *model.MappingJoin) (*JoinLookup, error) {
+	src, err := s.resolve(join.MappingLineageID, join.ShpLineageID, join.CSVKey, join.ShpKey)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.loadRows(src)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := &JoinLookup{
+		MappingFileID: src.mapping.ID,
+		ShpFileID:     src.shp.ID,
+		ShpKey:        join.ShpKey,
+		Columns:       rows.columns,
+		Rows:          map[string]map[string]interface{}{},
+	}
+	err = s.scanFeatureKeys(src, func(key string) {
+		if row, ok := rows.rows[key]; ok {
+			lookup.Rows[key] = row
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lookup, nil
+}
+
+// resolve 查询两个版本链的生效版本并校验类型、所属岛屿和关键字段
+// 新版本中缺少关键列或关键字段时返回 InputError，不会悄悄使用旧版本
+func (s *JoinService) resolve(mappingLineage, shpLineage uint, csvKey, shpKey string) (*joinSource, error) {
+	mapping, err := s.dfStore.GetActive(mappingLineage)
+	if err != nil {
+		return nil, fmt.Errorf("%w: mapping 文件版本链 %d", ErrDataFileNotFound, mappingLineage)
+	}
+	shp, err := s.dfStore.GetActive(shpLineage)
+	if err != nil {
+		return nil, fmt.Errorf("%w: shp 文件版本链 %d", ErrDataFileNotFound, shpLineage)
+	}
+	if mapping.DataType != "mapping" || mapping.Mapping == nil {
+		return nil, inputErrorf("文件 %d 不是已解析的 mapping 文件", mapping.ID)
+	}
+	if shp.DataType != "shp" || shp.Shapefile == nil {
+		return nil, inputErrorf("文件 %d 不是已解析的 shp 文件", shp.ID)
+	}
+	if mapping.IsleID != shp.IsleID {
+		return nil, inputErrorf("mapping 文件与 shp 文件不属于同一个岛屿")
+	}
+
+	src := &joinSource{mapping: mapping, shp: shp, csvIndex: -1, shpIndex: -1}
+	var csvNames, shpNames []string
+	for i, col := range mapping.Mapping.Columns {
+		csvNames = append(csvNames, col.Name)
+		if col.Name == csvKey {
+			src.csvIndex = i
+		}
+	}
+	for i, f := range shp.Shapefile.Fields {
+		shpNames = append(shpNames, f.Name)
+		if f.Name == shpKey {
+			src.shpIndex = i
+			src.numericKey = f.Type == "number" || f.Type == "float"
+		}
+	}
+	if src.csvIndex < 0 {
+		return nil, inputErrorf("CSV (文件 %d) 中没有列 %s，可选: %s", mapping.ID, csvKey, strings.Join(csvNames, ", "))
+	}
+	if src.shpIndex < 0 {
+		return nil, inputErrorf("dbf (文件 %d) 中没有字段 %s，可选: %s", shp.ID, shpKey, strings.Join(shpNames, ", "))
+	}
+	return src, nil
+}
+
+// loadRows 读取 CSV，按关键值建立索引；关键值为空的行忽略，重复的关键值取第一行
+func (s *JoinService) loadRows(src *joinSource) (*csvRows, error) {
+	dbfNames := make(map[string]bool, len(src.shp.Shapefile.Fields))
+	for _, f := range src.shp.Shapefile.Fields {
+		dbfNames[f.Name] = true
+	}
+	rows := &csvRows{rows: make(map[string]map[string]interface{})}
+	names := make([]string, len(src.mapping.Mapping.Columns))
+	for i, col := range src.mapping.Mapping.Columns {
+		if i == src.csvIndex {
+			continue
+		}
+		if dbfNames[col.Name] {
+			col.Name += "_csv"
+		}
+		names[i] = col.Name
+		rows.columns = append(rows.columns, col)
+	}
+
+	err := s.mapping.scan(src.mapping, func(meta *model.MappingMeta, _ int, record []string) (bool, error) {
+		key := csvKey(record[src.csvIndex], src.numericKey)
+		if key == "" {
+			return true, nil
+		}
+		if _, exists := rows.rows[key]; exists {
+			rows.dupCount++
+			if len(rows.duplicates) < maxJoinSamples {
+				rows.duplicates = append(rows.duplicates, key)
+			}
+			return true, nil
+		}
+		row := make(map[string]interface{}, len(rows.columns))
+		for i, cell := range record {
+			if i != src.csvIndex {
+				row[names[i]] = table.Convert(cell, meta.Columns[i].Type)
+			}
+		}
+		rows.rows[key] = row
+		rows.keys = append(rows.keys, key)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// scanFeatureKeys 依次读取 shp 每个要素 (跳过已删除的记录) 的关键值
+func (s *JoinService) scanFeatureKeys(src *joinSource, fn func(key string)) error {
+	dbf, closeDBF, err := openDBF(s.backend, storage.Key(src.shp.DataPath))
+	if errors.Is(err, storage.ErrNotExist) {
+		return inputErrorf("shp 图层没有 dbf 属性表")
+	}
+	if err != nil {
+		return err
+	}
+	defer closeDBF()
+
+	for {
+		values, deleted, err := dbf.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取 dbf 记录失败: %w", err)
+		}
+		if deleted {
+			continue
+		}
+		fn(featureKey(values[src.shpIndex]))
+	}
+}
+
+// joinVariant 标识关联结果的内容，CSV 内容或关键字段变化时生成新的 GeoJSON 缓存
+func joinVariant(src *joinSource) string {
+	content := src.mapping.BlobHash
+	if content == "" {
+		content = fmt.Sprintf("%s@%d", src.mapping.DataPath, src.mapping.UpdatedAt.UnixNano())
+	}
+	return fmt.Sprintf("join:%s:%s:%s", content, src.mapping.Mapping.Columns[src.csvIndex].Name, src.shp.Shapefile.Fields[src.shpIndex].Name)
+}
+
+// featureKey 把 dbf 字段值转换为关键值字符串
+func featureKey(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return strings.TrimSpace(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// csvKey 把 CSV 单元格转换为关键值字符串，dbf 关键字段为数值时按数值规范化
+func csvKey(cell string, numeric bool) string {
+	cell = strings.TrimSpace(cell)
+	if numeric {
+		if v, err := strconv.ParseFloat(cell, 64); err == nil {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return cell
+}
+
+func firstN(s []string, n int) []string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
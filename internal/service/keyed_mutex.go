@@ -0,0 +1,42 @@
+package service
+
+import "sync"
+
+// keyedMutex 按 key 加锁，不同 key 之间互不阻塞
+// 每个 key 的锁按等待者计数，最后一个持有者解锁后删除，避免 hash、会话 ID 等一次性 key 长期占用内存
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+// refMutex 带引用计数的锁，refs 为持有和等待该锁的调用者数量
+type refMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// lock 锁定 key 并返回解锁函数
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*refMutex)
+	}
+	m, ok := k.locks[key]
+	if !ok {
+		m = &refMutex{}
+		k.locks[key] = m
+	}
+	m.refs++
+	k.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		k.mu.Lock()
+		m.refs--
+		if m.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"regexp"
+	"strings"
+)
+
+// hexColor #RGB、#RRGGBB 或 #RRGGBBAA
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// LayerStylePatch 修改显示样式的请求，未提供的字段保持不变；颜色和标注字段传空字符串表示清除
+type LayerStylePatch struct {
+	DrawOrder   *int     `json:"draw_order"`
+	Visible     *bool    `json:"visible"`
+	Opacity     *float64 `json:"opacity"`
+	FillColor   *string  `json:"fill_color"`
+	StrokeColor *string  `json:"stroke_color"`
+	LineWidth   *float64 `json:"line_width"`
+	LabelField  *string  `json:"label_field"`
+}
+
+// apply 在 file 当前样式的基础上应用修改并校验，file 为修改类型、名称后的目标记录
+func (p *LayerStylePatch) apply(file *model.DataFile) (*model.LayerStyle, error) {
+	style := file.LayerStyle()
+	vectorOnly := p.FillColor != nil || p.StrokeColor != nil || p.LineWidth != nil || p.LabelField != nil
+	if vectorOnly && file.DataType != "shp" {
+		return nil, inputErrorf("fill_color、stroke_color、line_width 和 label_field 仅适用于 shp 图层")
+	}
+
+	if p.DrawOrder != nil {
+		style.DrawOrder = *p.DrawOrder
+	}
+	if p.Visible != nil {
+		style.Visible = *p.Visible
+	}
+	if p.Opacity != nil {
+		if *p.Opacity < 0 || *p.Opacity > 1 {
+			return nil, inputErrorf("opacity 应在 0 到 1 之间")
+		}
+		style.Opacity = *p.Opacity
+	}
+	if p.FillColor != nil {
+		color, err := normalizeColor("fill_color", *p.FillColor)
+		if err != nil {
+			return nil, err
+		}
+		style.FillColor = color
+	}
+	if p.StrokeColor != nil {
+		color, err := normalizeColor("stroke_color", *p.StrokeColor)
+		if err != nil {
+			return nil, err
+		}
+		style.StrokeColor = color
+	}
+	if p.LineWidth != nil {
+		if *p.LineWidth < 0 {
+			return nil, inputErrorf("line_width 不能为负数")
+		}
+		style.LineWidth = *p.LineWidth
+	}
+	if p.LabelField != nil {
+		field := strings.TrimSpace(*p.LabelField)
+		if field != "" && !hasAttributeField(file.Shapefile, field) {
+			return nil, inputErrorf("shp 图层中没有属性字段 %s", field)
+		}
+		style.LabelField = field
+	}
+	return &style, nil
+}
+
+// Reorder 按 ids 的顺序设置岛屿中图层的绘制顺序 (0, 1, 2...)，未列出的图层保持不变
+// ids 可以是版本链中任意一个版本，样式对整个版本链生效
+func (e *DataFileEditor) Reorder(isleID uint, ids []uint) error {
+	if _, err := e.isStore.GetByID(isleID); err != nil {
+		return ErrIslandNotFound
+	}
+	if len(ids) == 0 {
+		return inputErrorf("ids 不能为空")
+	}
+	styles := make(map[uint]*model.LayerStyle, len(ids))
+	for order, id := range ids {
+		file, err := e.dfStore.GetByID(id)
+		if err != nil {
+			return ErrDataFileNotFound
+		}
+		if file.IsleID != isleID {
+			return inputErrorf("文件 %d 不属于岛屿 %d", id, isleID)
+		}
+		if _, ok := styles[file.LineageID]; ok {
+			return inputErrorf("文件 %d 在 ids 中重复出现", id)
+		}
+		style := file.LayerStyle()
+		style.DrawOrder = order
+		styles[file.LineageID] = &style
+	}
+	return e.dfStore.UpdateStyles(styles)
+}
+
+// --- Helper Functions ---
+
+// normalizeColor 校验颜色并统一为大写，空字符串表示清除
+func normalizeColor(name, color string) (string, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return "", nil
+	}
+	if !hexColor.MatchString(color) {
+		return "", inputErrorf("%s 应为 #RRGGBB 或 #RRGGBBAA 格式: %s", name, color)
+	}
+	return strings.ToUpper(color), nil
+}
+
+// hasAttributeField 判断 shp 属性表中是否有名为 name 的字段
+func hasAttributeField(meta *model.ShapefileMeta, name string) bool {
+	if meta == nil {
+		return false
+	}
+	for _, f := range meta.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
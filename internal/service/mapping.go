@@ -0,0 +1,164 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/table"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	DefaultMappingPageSize = 50  // 映射表预览默认每页行数
+	MaxMappingPageSize     = 500 // 映射表预览每页最多行数
+)
+
+// extractMappingMeta 检查 mapping 文件：识别编码和分隔符，校验每一行的字段数并推断列类型
+// 存在格式错误的行时拒绝上传，错误信息中列出前若干行
+func extractMappingMeta(backend storage.Backend, key string) (*model.MappingMeta, error) {
+	// 1. 扫描整个文件识别编码
+	r, err := backend.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := table.DetectEncoding(r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 再读一遍，校验格式并推断列类型
+	r, err = backend.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	schema, err := table.Inspect(r, encoding)
+	if errors.Is(err, table.ErrFormat) {
+		return nil, &InputError{Msg: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.RowErrors) > 0 {
+		msgs := make([]string, len(schema.RowErrors))
+		for i, e := range schema.RowErrors {
+			msgs[i] = e.Error()
+		}
+		return nil, inputErrorf("CSV 中有格式错误的行 (%s 分隔, %s 编码): %s",
+			delimiterName(schema.Delimiter), encoding, strings.Join(msgs, "; "))
+	}
+	if schema.RowCount == 0 {
+		return nil, inputErrorf("CSV 中没有数据行")
+	}
+
+	meta := &model.MappingMeta{
+		Encoding:  schema.Encoding,
+		Delimiter: string(schema.Delimiter),
+		RowCount:  schema.RowCount,
+	}
+	for _, col := range schema.Columns {
+		meta.Columns = append(meta.Columns, model.MappingColumn{
+			Name:     col.Name,
+			Type:     col.Type,
+			Nullable: col.Nullable,
+			Min:      col.Min,
+			Max:      col.Max,
+		})
+	}
+	return meta, nil
+}
+
+// delimiterName 分隔符在错误信息中的写法
+func delimiterName(d rune) string {
+	if d == '\t' {
+		return "制表符"
+	}
+	return fmt.Sprintf("%q", d)
+}
+
+// MappingPreview 映射表的一页数据，单元格按列类型转换，空值为 null
+type MappingPreview struct {
+	Columns  []model.MappingColumn `json:"columns"`
+	Rows     [][]interface{}       `json:"rows"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"pageSize"`
+	Total    int                   `json:"total"`
+}
+
+// MappingService 读取已上传的映射表
+type MappingService struct {
+	backend storage.Backend
+}
+
+func NewMappingService(backend storage.Backend) *MappingService {
+	return &MappingService{backend: backend}
+}
+
+// Preview 分页读取映射表，page 从 1 开始
+func (s *MappingService) Preview(file *model.DataFile, page, pageSize int) (*MappingPreview, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultMappingPageSize
+	}
+	if pageSize > MaxMappingPageSize {
+		pageSize = MaxMappingPageSize
+	}
+
+	preview := &MappingPreview{Page: page, PageSize: pageSize, Rows: [][]interface{}{}}
+	err := s.scan(file, func(meta *model.MappingMeta, index int, record []string) (bool, error) {
+		if index < (page-1)*pageSize {
+			return true, nil
+		}
+		row := make([]interface{}, len(record))
+		for i, cell := range record {
+			row[i] = table.Convert(cell, meta.Columns[i].Type)
+		}
+		preview.Rows = append(preview.Rows, row)
+		return len(preview.Rows) < pageSize, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	preview.Columns = file.Mapping.Columns
+	preview.Total = file.Mapping.RowCount
+	return preview, nil
+}
+
+// scan 按上传时识别的编码逐行读取映射表，fn 返回 false 时停止
+func (s *MappingService) scan(file *model.DataFile, fn func(meta *model.MappingMeta, index int, record []string) (bool, error)) error {
+	meta := file.Mapping
+	if file.DataType != "mapping" || meta == nil {
+		return inputErrorf("该文件不是已解析的映射表")
+	}
+	r, err := s.backend.Open(file.DataPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := table.NewReader(r, meta.Encoding)
+	if err != nil {
+		return err
+	}
+	if len(tr.Header) != len(meta.Columns) {
+		return fmt.Errorf("映射表 %s 的表头与记录的列信息不一致", file.DataName)
+	}
+	for index := 0; ; index++ {
+		record, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		more, err := fn(meta, index, record)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
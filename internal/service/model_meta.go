@@ -0,0 +1,297 @@
+package service
+
+import (
+	"Go_for_unity/internal/gltf"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/model3d"
+	"Go_for_unity/internal/storage"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// modelExtensions 主模型文件的后缀，同一压缩包中有多个候选时按此顺序优先
+var modelExtensions = []string{".gltf", ".glb", ".fbx", ".obj"}
+
+// windowsAbsPath 贴图引用中常见的 Windows 绝对路径，例如 C:/Users/xx/tex.jpg
+var windowsAbsPath = regexp.MustCompile(`^[A-Za-z]:/`)
+
+func modelExtRank(name string) int {
+	ext := strings.ToLower(path.Ext(name))
+	for i, e := range modelExtensions {
+		if e == ext {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsGLTF 判断文件是否为 glTF/GLB 模型
+func IsGLTF(name string) bool {
+	return modelExtRank(name) == 0 || modelExtRank(name) == 1
+}
+
+// findPrimaryModel 在模型压缩包中查找主模型文件
+//  1. 与压缩包同名的模型文件，例如 House.zip 中的 House.fbx
+//  2. 压缩包中只有一个模型文件时直接使用
+//  3. 否则取目录层级最浅的模型文件，同一层有多个时要求按第 1 条命名
+func findPrimaryModel(backend storage.Backend, dir, baseName string) (string, error) {
+	var candidates []string
+	err := backend.Walk(dir, func(obj storage.ObjectInfo) error {
+		if modelExtRank(obj.Key) >= 0 {
+			candidates = append(candidates, obj.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", inputErrorf("压缩包中没有模型文件 (%s)", strings.Join(modelExtensions, "/"))
+	}
+
+	// 同名文件优先，其次按层级、后缀优先级排序
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if ma, mb := modelBaseMatches(a, baseName), modelBaseMatches(b, baseName); ma != mb {
+			return ma
+		}
+		if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+			return da < db
+		}
+		return modelExtRank(a) < modelExtRank(b)
+	})
+	best := candidates[0]
+	if modelBaseMatches(best, baseName) || len(candidates) == 1 {
+		return best, nil
+	}
+	next := candidates[1]
+	if strings.Count(next, "/") == strings.Count(best, "/") {
+		return "", inputErrorf("压缩包中有多个模型文件，请将主模型文件命名为 %s%s 等与压缩包同名的文件", baseName, path.Ext(best))
+	}
+	return best, nil
+}
+
+func modelBaseMatches(key, baseName string) bool {
+	base := path.Base(key)
+	return strings.EqualFold(strings.TrimSuffix(base, path.Ext(base)), baseName)
+}
+
+// extractModelMeta 按格式解析模型并检查贴图引用，root 为模型所在压缩包 (或单文件) 的根目录
+// 不支持的格式返回 nil
+func extractModelMeta(backend storage.Backend, key, root string) (*model.ModelMeta, error) {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".gltf", ".glb":
+		return extractGLTFMeta(backend, key, root)
+	case ".obj":
+		return extractOBJMeta(backend, key, root)
+	case ".fbx":
+		return extractFBXMeta(backend, key, root)
+	}
+	return nil, nil
+}
+
+// extractGLTFMeta 解析 glTF/GLB 模型，检查 buffer 和外部文件引用并统计网格信息
+func extractGLTFMeta(backend storage.Backend, key, root string) (*model.ModelMeta, error) {
+	r, err := backend.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("读取模型文件失败: %w", err)
+	}
+	defer r.Close()
+
+	f, err := gltf.Read(r)
+	if err != nil {
+		return nil, &InputError{Msg: err.Error()}
+	}
+	stats, err := gltf.Inspect(f, storageResolver(backend, storage.Dir(key), root))
+	if err != nil {
+		return nil, &InputError{Msg: err.Error()}
+	}
+
+	meta := &model.ModelMeta{
+		Format:         stats.Format,
+		Generator:      stats.Generator,
+		MeshCount:      stats.MeshCount,
+		PrimitiveCount: stats.PrimitiveCount,
+		VertexCount:    stats.VertexCount,
+		TriangleCount:  stats.TriangleCount,
+		MaterialCount:  stats.MaterialCount,
+		TextureCount:   stats.TextureCount,
+		UpAxis:         stats.UpAxis,
+		Extensions:     stats.ExtensionsUsed,
+	}
+	if stats.HasBounds {
+		meta.BoundsMin, meta.BoundsMax = &stats.BoundsMin, &stats.BoundsMax
+	}
+	for _, uri := range stats.Images {
+		meta.Textures = append(meta.Textures, relativeKey(root, resolveURI(storage.Dir(key), uri)))
+	}
+	return meta, nil
+}
+
+// extractOBJMeta 统计 OBJ 的顶点和三角形，检查 mtllib 以及材质库中引用的贴图
+func extractOBJMeta(backend storage.Backend, key, root string) (*model.ModelMeta, error) {
+	r, err := backend.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("读取模型文件失败: %w", err)
+	}
+	stats, err := model3d.ReadOBJ(r)
+	r.Close()
+	if err != nil {
+		return nil, &InputError{Msg: err.Error()}
+	}
+
+	meta := &model.ModelMeta{
+		Format:        "obj",
+		MeshCount:     1,
+		VertexCount:   stats.VertexCount,
+		TriangleCount: stats.TriangleCount,
+		UpAxis:        "Y",
+	}
+	if stats.HasBounds {
+		meta.BoundsMin, meta.BoundsMax = &stats.BoundsMin, &stats.BoundsMax
+	}
+
+	pkg, err := newModelPackage(backend, root)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, lib := range stats.MaterialLibs {
+		mtlKey, ok := pkg.resolve(storage.Dir(key), lib, meta)
+		if !ok {
+			return nil, inputErrorf("材质库 %s 不存在", lib)
+		}
+		r, err := backend.Open(mtlKey)
+		if err != nil {
+			return nil, fmt.Errorf("读取材质库失败: %w", err)
+		}
+		mtl, err := model3d.ReadMTL(r)
+		r.Close()
+		if err != nil {
+			return nil, &InputError{Msg: err.Error()}
+		}
+		meta.MaterialCount += mtl.MaterialCount
+		for _, tex := range mtl.Textures {
+			if texKey, ok := pkg.resolve(storage.Dir(mtlKey), tex, meta); ok {
+				meta.Textures = append(meta.Textures, relativeKey(root, texKey))
+			} else {
+				missing = append(missing, tex)
+			}
+		}
+	}
+	meta.TextureCount = len(meta.Textures)
+	if len(missing) > 0 {
+		return nil, inputErrorf("压缩包中缺少贴图: %s", strings.Join(missing, ", "))
+	}
+	return meta, nil
+}
+
+// extractFBXMeta 扫描 FBX 引用的贴图，贴图可能已内嵌在 FBX 中，缺失时只记录警告
+func extractFBXMeta(backend storage.Backend, key, root string) (*model.ModelMeta, error) {
+	r, err := backend.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("读取模型文件失败: %w", err)
+	}
+	refs, err := model3d.FBXTextureRefs(r)
+	r.Close()
+	if err != nil {
+		return nil, fmt.Errorf("读取模型文件失败: %w", err)
+	}
+
+	meta := &model.ModelMeta{Format: "fbx", UpAxis: "Y"}
+	pkg, err := newModelPackage(backend, root)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if texKey, ok := pkg.resolve(storage.Dir(key), ref, meta); ok {
+			meta.Textures = append(meta.Textures, relativeKey(root, texKey))
+		} else {
+			meta.MissingTextures = append(meta.MissingTextures, ref)
+		}
+	}
+	meta.TextureCount = len(meta.Textures)
+	if len(meta.MissingTextures) > 0 {
+		meta.Warnings = append(meta.Warnings, fmt.Sprintf("压缩包中没有 %d 个贴图文件，如未内嵌在 FBX 中将无法显示", len(meta.MissingTextures)))
+	}
+	return meta, nil
+}
+
+// modelPackage 模型压缩包中的文件列表，用于查找贴图
+type modelPackage struct {
+	keys   map[string]bool
+	byName map[string]string // 小写文件名 -> key，同名文件取第一个
+}
+
+func newModelPackage(backend storage.Backend, root string) (*modelPackage, error) {
+	keys, err := listKeys(backend, root)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &modelPackage{keys: keys, byName: make(map[string]string)}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		name := strings.ToLower(path.Base(key))
+		if _, ok := pkg.byName[name]; !ok {
+			pkg.byName[name] = key
+		}
+	}
+	return pkg, nil
+}
+
+// resolve 按相对路径查找引用的文件，找不到时 (包括导出时写入的绝对路径) 按文件名在整个压缩包中匹配
+func (p *modelPackage) resolve(dir, ref string, meta *model.ModelMeta) (string, bool) {
+	ref = strings.ReplaceAll(strings.TrimSpace(ref), "\\", "/")
+	if ref == "" {
+		return "", false
+	}
+	if !strings.HasPrefix(ref, "/") && !windowsAbsPath.MatchString(ref) {
+		if key := path.Join(dir, ref); p.keys[key] {
+			return key, true
+		}
+	}
+	if key, ok := p.byName[strings.ToLower(path.Base(ref))]; ok {
+		meta.Warnings = append(meta.Warnings, fmt.Sprintf("%s 按文件名匹配到 %s", ref, path.Base(key)))
+		return key, true
+	}
+	return "", false
+}
+
+// storageResolver 在存储中按模型所在目录解析 glTF 的相对 uri，不允许引用 root 之外的文件
+func storageResolver(backend storage.Backend, dir, root string) gltf.Resolver {
+	return func(uri string) (int64, error) {
+		if strings.Contains(uri, "://") {
+			return 0, fmt.Errorf("不支持引用外部地址: %s", uri)
+		}
+		key := resolveURI(dir, uri)
+		if root != "" && !strings.HasPrefix(key, root+"/") {
+			return 0, fmt.Errorf("引用的文件超出模型目录: %s", uri)
+		}
+		info, err := backend.Stat(key)
+		if err != nil {
+			return 0, err
+		}
+		return info.Size, nil
+	}
+}
+
+// resolveURI 把 glTF 中 URL 编码的相对 uri 解析为存储 key
+func resolveURI(dir, uri string) string {
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
+	return path.Join(dir, uri)
+}
+
+// relativeKey 返回 key 相对 root 的路径
+func relativeKey(root, key string) string {
+	return strings.TrimPrefix(key, root+"/")
+}
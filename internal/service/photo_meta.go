@@ -0,0 +1,59 @@
+package service
+
+import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/imaging"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
+// extractPhotoMeta 读取 jpg 照片的 EXIF，返回拍摄信息以及照片位置 (WGS84 经纬度，范围退化为一个点)
+// 没有 EXIF 的图片 (例如 png) 返回 nil；EXIF 损坏只记录为警告，不影响上传
+func extractPhotoMeta(backend storage.Backend, key string) (*model.PhotoMeta, model.BBox, string, error) {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg":
+	default:
+		return nil, model.BBox{}, "", nil
+	}
+
+	r, err := backend.Open(key)
+	if err != nil {
+		return nil, model.BBox{}, "", err
+	}
+	defer r.Close()
+
+	exif, err := imaging.ReadEXIF(r)
+	if errors.Is(err, imaging.ErrNoEXIF) {
+		return nil, model.BBox{}, "", nil
+	}
+	if err != nil {
+		return &model.PhotoMeta{Warnings: []string{"EXIF 解析失败: " + err.Error()}}, model.BBox{}, "", nil
+	}
+
+	// 没有时区信息的拍摄时间按服务器所在时区解释
+	meta := &model.PhotoMeta{
+		HasGPS:      exif.HasGPS,
+		Altitude:    exif.Altitude,
+		Heading:     exif.Heading,
+		HeadingRef:  exif.HeadingRef,
+		CapturedAt:  exif.CaptureTime(time.Local),
+		Orientation: exif.Orientation,
+		Camera:      strings.TrimSpace(exif.Make + " " + exif.Model),
+	}
+	if !exif.HasGPS {
+		meta.Warnings = append(meta.Warnings, "照片没有 GPS 位置信息")
+		return meta, model.BBox{}, "", nil
+	}
+	if exif.HeadingRef == "M" {
+		meta.Warnings = append(meta.Warnings, "拍摄方向以磁北为基准")
+	}
+	meta.Lat, meta.Lon = exif.Lat, exif.Lon
+
+	// EXIF 中的 GPS 坐标为 WGS84，照片位置写入 BBox 后可以参与范围查询
+	bbox := model.BBox{MinX: exif.Lon, MinY: exif.Lat, MaxX: exif.Lon, MaxY: exif.Lat, Valid: true}
+	return meta, bbox, geo.CRSWGS84, nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ArchiveMode 上传文件的保存方式
+type ArchiveMode int
+
+const (
+	ArchiveNever  ArchiveMode = iota // 单个文件，原样保存
+	ArchiveAlways                    // 总是 zip 压缩包，解压后保存整个目录
+	ArchiveZipExt                    // 扩展名为 .zip 时解压，否则作为单个文件保存
+)
+
+// ProcessEnv 处理器解析元数据时可以使用的依赖
+type ProcessEnv struct {
+	Backend storage.Backend
+	Thumbs  *ThumbnailService
+}
+
+// LocateFunc 在解压后的内容目录 dir 中查找 DataPath 应该指向的文件 (索引文件或主文件)
+// fileName 为上传时的原始文件名
+type LocateFunc func(backend storage.Backend, dir, fileName string) (string, error)
+
+// ExtractFunc 校验文件内容并把元数据填充到 file，返回 InputError 时上传被拒绝
+type ExtractFunc func(env ProcessEnv, file *model.DataFile) error
+
+// Exporter 描述处理器在岛屿导出 JSON 中的输出
+type Exporter struct {
+	Section string                                                             // 导出 JSON 中的字段名，例如 vectors
+	Entry   func(ctx ExportContext, file *model.DataFile) (interface{}, error) // 生成一个条目，返回 nil 时跳过该文件
+}
+
+// Processor 一种数据类型 (DataType) 的处理方式：接受的扩展名、解压方式、索引文件查找、元数据解析和导出
+// 内置类型见 builtinProcessors，新类型可以通过 RegisterProcessor 或配置文件的 data_types 节点添加
+type Processor struct {
+	Type       string      // DataType，例如 shp
+	Extensions []string    // 接受的上传文件扩展名 (小写，带点)，为空时不限制
+	Archive    ArchiveMode // 上传文件的保存方式
+	Locate     LocateFunc  // 压缩包解压后查找 DataPath，单个文件保存时不会调用
+	Extract    ExtractFunc // 校验并解析元数据，可为 nil
+	Export     *Exporter   // 导出方式，为 nil 时不出现在导出 JSON 中
+	ConvertsTo []string    // 修改文件类型时允许改成的类型 (内容格式兼容，重新解析即可)
+	MIMETypes  []string    // 接受的内容类型 (按文件头和结构判断，见 SniffMIME)，支持 text/* 通配，为空时不检查
+}
+
+// Accepts 判断上传的文件名是否符合该类型接受的扩展名
+func (p *Processor) Accepts(fileName string) bool {
+	if len(p.Extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, e := range p.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// IsArchive 判断上传的文件是否需要解压
+func (p *Processor) IsArchive(fileName string) bool {
+	switch p.Archive {
+	case ArchiveAlways:
+		return true
+	case ArchiveZipExt:
+		return strings.EqualFold(filepath.Ext(fileName), ".zip")
+	}
+	return false
+}
+
+// CanConvertTo 判断该类型的文件能否改为 dataType 类型
+func (p *Processor) CanConvertTo(dataType string) bool {
+	for _, t := range p.ConvertsTo {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// processorRegistry 按类型保存处理器，并记住注册顺序 (导出 JSON 中各字段的顺序与之一致)
+type processorRegistry struct {
+	mu     sync.RWMutex
+	order  []*Processor
+	byType map[string]*Processor
+}
+
+var processors = &processorRegistry{byType: make(map[string]*Processor)}
+
+func init() {
+	for _, p := range builtinProcessors() {
+		if err := RegisterProcessor(p); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterProcessor 注册一种数据类型，类型已存在时返回错误
+// 应在服务启动、开始处理请求之前调用
+func RegisterProcessor(p *Processor) error {
+	if p.Type == "" {
+		return fmt.Errorf("数据类型名称不能为空")
+	}
+	if p.Archive != ArchiveNever && p.Locate == nil {
+		return fmt.Errorf("数据类型 %s 需要解压，但没有提供 Locate", p.Type)
+	}
+	if p.Export != nil && (p.Export.Section == "" || p.Export.Entry == nil || reservedExportKeys[p.Export.Section]) {
+		return fmt.Errorf("数据类型 %s 的导出字段 %q 无效", p.Type, p.Export.Section)
+	}
+	for i, ext := range p.Extensions {
+		p.Extensions[i] = strings.ToLower(ext)
+	}
+	p.MIMETypes = normalizeMIMETypes(p.MIMETypes)
+
+	processors.mu.Lock()
+	defer processors.mu.Unlock()
+	if _, ok := processors.byType[p.Type]; ok {
+		return fmt.Errorf("数据类型 %s 已注册", p.Type)
+	}
+	processors.byType[p.Type] = p
+	processors.order = append(processors.order, p)
+	return nil
+}
+
+// SetProcessorMIMETypes 修改数据类型接受的内容类型，为空时不检查；应在服务启动时调用
+func SetProcessorMIMETypes(dataType string, types []string) error {
+	processors.mu.Lock()
+	defer processors.mu.Unlock()
+	p, ok := processors.byType[dataType]
+	if !ok {
+		return fmt.Errorf("数据类型 %s 不存在", dataType)
+	}
+	p.MIMETypes = normalizeMIMETypes(types)
+	return nil
+}
+
+// LookupProcessor 查询数据类型的处理器
+func LookupProcessor(dataType string) (*Processor, bool) {
+	processors.mu.RLock()
+	defer processors.mu.RUnlock()
+	p, ok := processors.byType[dataType]
+	return p, ok
+}
+
+// Processors 按注册顺序返回所有处理器
+func Processors() []*Processor {
+	processors.mu.RLock()
+	defer processors.mu.RUnlock()
+	return append([]*Processor(nil), processors.order...)
+}
+
+// DataTypes 按注册顺序返回所有支持的数据类型
+func DataTypes() []string {
+	var types []string
+	for _, p := range Processors() {
+		types = append(types, p.Type)
+	}
+	return types
+}
+
+// IsSupportedDataType 判断文件类型是否受支持
+func IsSupportedDataType(dataType string) bool {
+	_, ok := LookupProcessor(dataType)
+	return ok
+}
+
+// ExtractsArchive 判断该类型的上传内容是否总是解压后的目录 (例如 shp、tif)
+func ExtractsArchive(dataType string) bool {
+	p, ok := LookupProcessor(dataType)
+	return ok && p.Archive == ArchiveAlways
+}
+
+// ValidateUpload 检查上传的文件类型和文件名，不符合时返回 InputError
+func ValidateUpload(dataType, fileName string) error {
+	p, ok := LookupProcessor(dataType)
+	if !ok {
+		return inputErrorf("不支持的文件类型: %s", dataType)
+	}
+	if !p.Accepts(fileName) {
+		return inputErrorf("%s 类型只接受 %s 文件: %s", dataType, strings.Join(p.Extensions, "/"), fileName)
+	}
+	return nil
+}
+
+// ProcessorConfig 配置文件 data_types 节点中声明的数据类型，不需要写代码即可接收和导出新类型的文件
+//
+//	data_types:
+//	  - type: pointcloud
+//	    extensions: [".zip"]
+//	    archive: always          # never / always / zip
+//	    index: "{name}.json"     # 压缩包中的索引文件，".las" 形式表示按后缀查找
+//	    export: pointClouds      # 导出 JSON 中的字段名，为空时不导出
+//	    mime_types: [application/zip] # 接受的内容类型，为空时不检查
+type ProcessorConfig struct {
+	Type       string   `mapstructure:"type"`
+	Extensions []string `mapstructure:"extensions"`
+	Archive    string   `mapstructure:"archive"`
+	Index      string   `mapstructure:"index"`
+	Export     string   `mapstructure:"export"`
+	MIMETypes  []string `mapstructure:"mime_types"`
+}
+
+// NewConfigProcessor 根据配置创建处理器：不解析元数据，导出时输出名称和文件 URL
+func NewConfigProcessor(cfg ProcessorConfig) (*Processor, error) {
+	p := &Processor{Type: strings.TrimSpace(cfg.Type), Extensions: cfg.Extensions, MIMETypes: cfg.MIMETypes}
+	switch strings.ToLower(cfg.Archive) {
+	case "", "never":
+		p.Archive = ArchiveNever
+	case "always":
+		p.Archive = ArchiveAlways
+	case "zip":
+		p.Archive = ArchiveZipExt
+	default:
+		return nil, fmt.Errorf("数据类型 %s 的 archive 应为 never、always 或 zip: %s", p.Type, cfg.Archive)
+	}
+	if p.Archive != ArchiveNever {
+		if cfg.Index == "" {
+			return nil, fmt.Errorf("数据类型 %s 需要解压，必须配置 index", p.Type)
+		}
+		p.Locate = locateIndex(cfg.Index)
+	}
+	if cfg.Export != "" {
+		p.Export = &Exporter{Section: cfg.Export, Entry: exportFileEntry(true)}
+	}
+	return p, nil
+}
+
+// locateIndex 按配置查找索引文件：以 "." 开头时查找第一个该后缀的文件，否则为相对路径，{name} 替换为压缩包名 (不含扩展名)
+func locateIndex(index string) LocateFunc {
+	return func(backend storage.Backend, dir, fileName string) (string, error) {
+		if strings.HasPrefix(index, ".") {
+			found, err := findFileByExt(backend, dir, strings.ToLower(index))
+			if err != nil {
+				return "", inputErrorf("在压缩包中未找到 %s 文件", index)
+			}
+			return found, nil
+		}
+		baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		key := storage.Join(dir, strings.ReplaceAll(index, "{name}", baseName))
+		if _, err := backend.Stat(key); err != nil {
+			return "", inputErrorf("在压缩包中未找到索引文件 %s", strings.TrimPrefix(key, dir+"/"))
+		}
+		return key, nil
+	}
+}
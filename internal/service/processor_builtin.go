@@ -0,0 +1,172 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// builtinProcessors 内置的数据类型，注册顺序即导出 JSON 中各字段的顺序
+func builtinProcessors() []*Processor {
+	return []*Processor{
+		{
+			Type:       "shp",
+			Extensions: []string{".zip"},
+			Archive:    ArchiveAlways,
+			Locate:     locateShapefile,
+			Extract:    extractShapefile,
+			Export:     &Exporter{Section: "vectors", Entry: exportVector},
+			MIMETypes:  []string{"application/zip"},
+		},
+		{
+			Type:       "tif",
+			Extensions: []string{".zip"},
+			Archive:    ArchiveAlways,
+			Locate:     locateRasterIndex,
+			Extract:    extractRasterIndex,
+			Export:     &Exporter{Section: "rasters", Entry: exportRaster},
+			MIMETypes:  []string{"application/zip"},
+		},
+		{
+			Type:       "models",
+			Extensions: append(append([]string(nil), modelExtensions...), ".zip"),
+			Archive:    ArchiveZipExt,
+			Locate:     locatePrimaryModel,
+			Extract:    extractModel,
+			Export:     &Exporter{Section: "models", Entry: exportModel},
+			MIMETypes:  []string{"application/zip", "model/gltf-binary", "model/gltf+json", "model/obj", "application/vnd.autodesk.fbx"},
+		},
+		{
+			Type:       "jpg",
+			Extensions: []string{".jpg", ".jpeg", ".png", ".gif"},
+			Extract:    extractPhoto,
+			Export:     &Exporter{Section: "pictures", Entry: exportPicture},
+			MIMETypes:  []string{"image/jpeg", "image/png", "image/gif"},
+		},
+		{
+			Type:       "txt",
+			Export:     &Exporter{Section: "txtFilePath", Entry: exportFileEntry(false)},
+			ConvertsTo: []string{"weather", "mapping"},
+			MIMETypes:  []string{"text/*", "application/json", "application/xml"},
+		},
+		{
+			Type:       "weather",
+			Extensions: []string{".json", ".csv", ".txt"},
+			Extract:    extractWeather,
+			Export:     &Exporter{Section: "weatherFilePath", Entry: exportWeather},
+			ConvertsTo: []string{"txt", "mapping"},
+			MIMETypes:  []string{"application/json", "text/csv", "text/plain"},
+		},
+		{
+			Type:       "mapping",
+			Extensions: []string{".csv", ".txt"},
+			Extract:    extractMapping,
+			Export:     &Exporter{Section: "csvFilePath", Entry: exportMapping},
+			ConvertsTo: []string{"txt", "weather"},
+			MIMETypes:  []string{"text/csv", "text/plain"},
+		},
+	}
+}
+
+// --- 索引文件查找 ---
+
+// locateShapefile 在压缩包中查找 .shp 文件
+func locateShapefile(backend storage.Backend, dir, fileName string) (string, error) {
+	foundPath, err := findFileByExt(backend, dir, ".shp")
+	if err != nil {
+		return "", inputErrorf("在压缩包中未找到 .shp 文件")
+	}
+	return foundPath, nil
+}
+
+// locateRasterIndex 获取 zip 文件的基本名称 (例如 "MyTiles" from "MyTiles.zip")，查找索引文件 (.xml 或 .json)
+func locateRasterIndex(backend storage.Backend, dir, fileName string) (string, error) {
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	foundPath, err := findRasterIndexFile(backend, dir, baseName)
+	if err != nil {
+		return "", &InputError{Msg: err.Error()}
+	}
+	return foundPath, nil
+}
+
+// locatePrimaryModel 模型压缩包：按命名约定查找主模型文件
+func locatePrimaryModel(backend storage.Backend, dir, fileName string) (string, error) {
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	return findPrimaryModel(backend, dir, baseName)
+}
+
+// --- 元数据解析 ---
+
+func extractShapefile(env ProcessEnv, file *model.DataFile) error {
+	meta, bbox, err := extractShapefileMeta(env.Backend, file.DataPath)
+	if err != nil {
+		return err
+	}
+	file.Shapefile = meta
+	file.BBox = bbox
+	file.CRS = CRSLabel(meta.EPSG, meta.CRSName)
+	return nil
+}
+
+func extractRasterIndex(env ProcessEnv, file *model.DataFile) error {
+	meta, bbox, crs, err := extractRasterIndexMeta(env.Backend, file.DataPath)
+	if err != nil {
+		return err
+	}
+	file.Raster = meta
+	file.BBox = bbox
+	file.CRS = crs
+	return nil
+}
+
+func extractModel(env ProcessEnv, file *model.DataFile) error {
+	// 贴图等外部引用只能在模型所在的内容目录内解析；旧版按岛屿目录存储的文件以模型所在目录为界
+	root := storage.Dir(file.DataPath)
+	if file.BlobHash != "" {
+		root = blobDir(file.BlobHash)
+	}
+	meta, err := extractModelMeta(env.Backend, file.DataPath, root)
+	if err != nil {
+		return err
+	}
+	file.Mesh = meta
+	return nil
+}
+
+func extractPhoto(env ProcessEnv, file *model.DataFile) error {
+	meta, bbox, crs, err := extractPhotoMeta(env.Backend, file.DataPath)
+	if err != nil {
+		return err
+	}
+	file.Photo = meta
+	file.BBox = bbox
+	file.CRS = crs
+	// 缩略图生成失败不影响上传，请求缩略图时会再次尝试
+	if env.Thumbs != nil && IsThumbnailSource(file.DataPath) {
+		if err := env.Thumbs.Ensure(file.DataPath); err != nil {
+			log.Printf("生成缩略图失败 %s: %v", file.DataPath, err)
+		}
+	}
+	return nil
+}
+
+func extractWeather(env ProcessEnv, file *model.DataFile) error {
+	meta, steps, err := extractWeatherMeta(env.Backend, file.DataPath)
+	if err != nil {
+		return err
+	}
+	file.Weather = meta
+	file.WeatherSteps = steps
+	return nil
+}
+
+func extractMapping(env ProcessEnv, file *model.DataFile) error {
+	meta, err := extractMappingMeta(env.Backend, file.DataPath)
+	if err != nil {
+		return err
+	}
+	file.Mapping = meta
+	return nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	maxMissingListed  = 20 // RasterIndexMeta.Missing 中最多记录的缺失瓦片数量
+	maxTilesetNesting = 8  // 外部 tileset 的最大嵌套层数，防止循环引用
+)
+
+// extractRasterIndexMeta 解析 tif 上传的索引文件 (3D Tiles 的 .json 或瓦片的 .xml)，并检查引用的瓦片是否都在压缩包中
+// 索引无法解析、根瓦片或全部瓦片缺失视为非法上传；部分瓦片缺失只记录为警告
+func extractRasterIndexMeta(backend storage.Backend, indexKey string) (*model.RasterIndexMeta, model.BBox, string, error) {
+	keys, err := listKeys(backend, storage.Dir(indexKey))
+	if err != nil {
+		return nil, model.BBox{}, "", err
+	}
+	if strings.EqualFold(path.Ext(indexKey), ".json") {
+		return checkTileset(backend, indexKey, keys)
+	}
+	return checkRasterXML(backend, indexKey, keys)
+}
+
+// tilesetChecker 遍历 tileset 及其引用的外部 tileset，统计瓦片并记录缺失项
+type tilesetChecker struct {
+	backend     storage.Backend
+	keys        map[string]bool
+	meta        *model.RasterIndexMeta
+	visited     map[string]bool
+	refs        int  // 引用的瓦片内容总数 (包括缺失的)
+	rootMissing bool // 顶层 tileset 的根瓦片内容缺失
+}
+
+func checkTileset(backend storage.Backend, indexKey string, keys map[string]bool) (*model.RasterIndexMeta, model.BBox, string, error) {
+	c := &tilesetChecker{
+		backend: backend,
+		keys:    keys,
+		meta:    &model.RasterIndexMeta{Format: "3dtiles"},
+		visited: map[string]bool{indexKey: true},
+	}
+	ts, err := c.check(indexKey, 0, 0)
+	if err != nil {
+		return nil, model.BBox{}, "", err
+	}
+	c.meta.Version = ts.Asset.Version
+	if err := c.finish(); err != nil {
+		return nil, model.BBox{}, "", err
+	}
+
+	// 只有 region 类型的包围体可以直接换算为经纬度范围
+	var bbox model.BBox
+	var crs string
+	if minLon, minLat, maxLon, maxLat, ok := ts.Root.BoundingVolume.RegionDegrees(); ok {
+		bbox = model.BBox{MinX: minLon, MinY: minLat, MaxX: maxLon, MaxY: maxLat, Valid: true}
+		crs = "EPSG:4326"
+	}
+	return c.meta, bbox, crs, nil
+}
+
+// check 解析 key 指向的 tileset，depth 为其根瓦片在整棵瓦片树中的深度，nesting 为外部 tileset 的嵌套层数
+func (c *tilesetChecker) check(key string, depth, nesting int) (*geo.Tileset, error) {
+	r, err := c.backend.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("读取索引文件失败: %w", err)
+	}
+	defer r.Close()
+	ts, err := geo.ParseTileset(r)
+	if err != nil {
+		return nil, inputErrorf("%s: %v", path.Base(key), err)
+	}
+
+	err = ts.Walk(func(tile *geo.Tile, tilePath string, d int) error {
+		if depth+d+1 > c.meta.Levels {
+			c.meta.Levels = depth + d + 1
+		}
+		for _, ref := range tile.ContentRefs() {
+			target, ok := resolveTileRef(key, ref)
+			if !ok {
+				c.warn(fmt.Sprintf("瓦片引用了外部地址，未做检查: %s", ref))
+				continue
+			}
+			c.refs++
+			if !c.keys[target] {
+				c.missing(target)
+				if nesting == 0 && d == 0 {
+					c.rootMissing = true
+				}
+				continue
+			}
+			if !strings.EqualFold(path.Ext(target), ".json") {
+				c.meta.TileCount++
+				continue
+			}
+
+			// 内容是外部 tileset，继续向下检查
+			c.refs--
+			if c.visited[target] {
+				continue
+			}
+			c.visited[target] = true
+			if nesting+1 >= maxTilesetNesting {
+				c.warn(fmt.Sprintf("外部 tileset 嵌套超过 %d 层，未继续检查: %s", maxTilesetNesting, ref))
+				continue
+			}
+			if _, err := c.check(target, depth+d+1, nesting+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (c *tilesetChecker) missing(key string) {
+	c.meta.MissingCount++
+	if len(c.meta.Missing) < maxMissingListed {
+		c.meta.Missing = append(c.meta.Missing, key)
+	}
+}
+
+func (c *tilesetChecker) warn(msg string) {
+	c.meta.Warnings = append(c.meta.Warnings, msg)
+}
+
+// finish 根据缺失情况决定是报错还是记录警告
+func (c *tilesetChecker) finish() error {
+	if c.meta.MissingCount == 0 {
+		return nil
+	}
+	if c.rootMissing || c.meta.MissingCount == c.refs {
+		return inputErrorf("压缩包中缺失 %d 个瓦片文件，例如: %s", c.meta.MissingCount, strings.Join(c.meta.Missing, ", "))
+	}
+	c.warn(fmt.Sprintf("压缩包中缺失 %d/%d 个瓦片文件", c.meta.MissingCount, c.refs))
+	return nil
+}
+
+// resolveTileRef 把 tileset 中的相对地址解析为存储 key，外部地址返回 false
+func resolveTileRef(tilesetKey, ref string) (string, bool) {
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if strings.Contains(ref, "://") || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "/") {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	return path.Join(storage.Dir(tilesetKey), ref), true
+}
+
+// checkRasterXML 解析 XML 索引；TMS TileMap 会检查每个级别的瓦片目录
+func checkRasterXML(backend storage.Backend, indexKey string, keys map[string]bool) (*model.RasterIndexMeta, model.BBox, string, error) {
+	r, err := backend.Open(indexKey)
+	if err != nil {
+		return nil, model.BBox{}, "", fmt.Errorf("读取索引文件失败: %w", err)
+	}
+	defer r.Close()
+	index, err := geo.ParseRasterIndexXML(r)
+	if err != nil {
+		return nil, model.BBox{}, "", inputErrorf("%s: %v", path.Base(indexKey), err)
+	}
+
+	meta := &model.RasterIndexMeta{Format: "xml"}
+	switch {
+	case index.TileMap != nil:
+		tm := index.TileMap
+		meta.Format = "tms"
+		meta.Version = tm.Version
+		meta.Levels = len(tm.TileSets.TileSets)
+
+		// 瓦片位于 <href>/<x>/<y>.<extension>，按级别统计
+		ext := "." + strings.ToLower(tm.TileFormat.Extension)
+		for _, set := range tm.TileSets.TileSets {
+			href := set.Href
+			if strings.Contains(href, "://") {
+				href = path.Base(href)
+			}
+			prefix := path.Join(storage.Dir(indexKey), href) + "/"
+			count := 0
+			for key := range keys {
+				if strings.HasPrefix(key, prefix) && strings.HasSuffix(strings.ToLower(key), ext) {
+					count++
+				}
+			}
+			if count == 0 {
+				meta.MissingCount++
+				meta.Missing = append(meta.Missing, strings.TrimSuffix(prefix, "/"))
+			}
+			meta.TileCount += count
+		}
+		if meta.TileCount == 0 {
+			return nil, model.BBox{}, "", inputErrorf("压缩包中没有任何 %s 瓦片", ext)
+		}
+		if meta.MissingCount > 0 {
+			meta.Warnings = append(meta.Warnings, fmt.Sprintf("%d/%d 个级别没有瓦片", meta.MissingCount, meta.Levels))
+		}
+		bb := tm.BoundingBox
+		bbox := model.BBox{MinX: bb.MinX, MinY: bb.MinY, MaxX: bb.MaxX, MaxY: bb.MaxY, Valid: true}
+		return meta, bbox, strings.TrimSpace(tm.SRS), nil
+
+	case index.Model != nil:
+		meta.Version = index.Model.Version
+		return meta, model.BBox{}, index.Model.SRS, nil
+
+	default:
+		meta.Warnings = append(meta.Warnings, fmt.Sprintf("无法识别的索引格式 <%s>，只校验了 XML 格式", index.Root))
+		return meta, model.BBox{}, "", nil
+	}
+}
+
+// listKeys 列出目录下的所有对象 key
+func listKeys(backend storage.Backend, dir string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	err := backend.Walk(dir, func(obj storage.ObjectInfo) error {
+		keys[obj.Key] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出解压文件失败: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 对账结果中记录失效的原因
+const (
+	ReasonIslandMissing  = "island_missing"  // 所属岛屿不存在 (例如岛屿已被删除)
+	ReasonContentMissing = "content_missing" // 记录指向的文件在存储中不存在
+)
+
+// OrphanFile uploads/ 下没有任何记录引用的文件
+type OrphanFile struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// DanglingRow 失效的数据库记录
+// data_files 和 history_trails 的记录修复时删除；isles 的记录只清空丢失的岛屿图片路径
+type DanglingRow struct {
+	Table  string `json:"table"`
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// StaleBlob 没有被任何 DataFile 引用的内容记录，修复时连同内容一起删除
+type StaleBlob struct {
+	Hash     string `json:"hash"`
+	Dir      string `json:"dir"`
+	Size     int64  `json:"size"` // 内容目录在存储中的字节数
+	RefCount int    `json:"ref_count"`
+}
+
+// RefCountFix 内容记录的引用数与实际引用它的 DataFile 数量不一致
+type RefCountFix struct {
+	Hash     string `json:"hash"`
+	RefCount int    `json:"ref_count"`
+	Actual   int    `json:"actual"`
+}
+
+// ReconcileTotals 对账的数量和大小统计
+type ReconcileTotals struct {
+	ScannedFiles   int   `json:"scanned_files"`
+	ScannedBytes   int64 `json:"scanned_bytes"`
+	OrphanFiles    int   `json:"orphan_files"`
+	OrphanBytes    int64 `json:"orphan_bytes"`
+	RecentFiles    int   `json:"recent_files"` // 未被引用但修改时间在 min_age 之内 (可能是正在进行的上传)，不视为孤立文件
+	DanglingRows   int   `json:"dangling_rows"`
+	StaleBlobs     int   `json:"stale_blobs"`
+	StaleBlobBytes int64 `json:"stale_blob_bytes"`
+	RefCountFixes  int   `json:"ref_count_fixes"`
+}
+
+// ReconcileReport 对账结果
+type ReconcileReport struct {
+	DryRun        bool            `json:"dry_run"`
+	Fingerprint   string          `json:"fingerprint"` // 结果摘要，修复时必须提供，保证修复的正是预览看到的内容
+	Totals        ReconcileTotals `json:"totals"`
+	OrphanFiles   []OrphanFile    `json:"orphan_files"`
+	DanglingRows  []DanglingRow   `json:"dangling_rows"`
+	StaleBlobs    []StaleBlob     `json:"stale_blobs"`
+	RefCountFixes []RefCountFix   `json:"ref_count_fixes"`
+	Errors        []string        `json:"errors,omitempty"` // 修复时失败的项目，其余项目照常修复
+}
+
+// ReconcileOptions 对账参数
+type ReconcileOptions struct {
+	MinAge time.Duration // 修改时间在此之内的文件和内容记录不处理，避免误删正在上传的内容
+}
+
+// reconcilePlan 一次扫描的结果以及修复时需要的记录
+type reconcilePlan struct {
+	report  *ReconcileReport
+	files   []model.DataFile
+	trails  []uint
+	islands []model.Island
+}
+
+// Reconciler 比对 uploads/ 下的文件与 isles、data_files、history_trails、blobs 表
+// 找出没有记录引用的文件、指向不存在内容或岛屿的记录，以及引用数不正确的内容记录
+// 修复时不加锁，应在没有上传和删除操作时执行
+type Reconciler struct {
+	isStore    *store.IslandStore
+	dfStore    *store.DataFileStore
+	trailStore *store.HistoryTrailStore
+	blobs      *store.BlobStore
+	backend    storage.Backend
+}
+
+func NewReconciler(isStore *store.IslandStore, dfStore *store.DataFileStore, trailStore *store.HistoryTrailStore, blobs *store.BlobStore, backend storage.Backend) *Reconciler {
+	return &Reconciler{isStore: isStore, dfStore: dfStore, trailStore: trailStore, blobs: blobs, backend: backend}
+}
+
+// Scan 只扫描不修改 (dry-run)
+func (r *Reconciler) Scan(opts ReconcileOptions) (*ReconcileReport, error) {
+	plan, err := r.scan(opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan.report, nil
+}
+
+// Repair 重新扫描，结果与预览时的 fingerprint 一致才执行修复，否则返回 ErrReconcileChanged
+func (r *Reconciler) Repair(opts ReconcileOptions, fingerprint string) (*ReconcileReport, error) {
+	plan, err := r.scan(opts)
+	if err != nil {
+		return nil, err
+	}
+	report := plan.report
+	if report.Fingerprint != fingerprint {
+		return nil, ErrReconcileChanged
+	}
+	report.DryRun = false
+	fail := func(format string, args ...interface{}) {
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+	}
+
+	// --- 1. 先修复数据库记录 ---
+	for i := range plan.files {
+		file := &plan.files[i]
+		if err := r.dfStore.RemoveVersion(file); err != nil {
+			fail("删除文件记录 %d 失败: %v", file.ID, err)
+		}
+	}
+	for _, id := range plan.trails {
+		if err := r.trailStore.Delete(id); err != nil {
+			fail("删除轨迹记录 %d 失败: %v", id, err)
+		}
+	}
+	for i := range plan.islands {
+		island := &plan.islands[i]
+		island.IslePicPath = ""
+		if err := r.isStore.Update(island); err != nil {
+			fail("清空岛屿 %d 的图片路径失败: %v", island.ID, err)
+		}
+	}
+	for _, fix := range report.RefCountFixes {
+		if err := r.blobs.SetRefCount(fix.Hash, fix.Actual); err != nil {
+			fail("修正内容 %s 的引用数失败: %v", fix.Hash, err)
+		}
+	}
+
+	// --- 2. 再删除不再被引用的内容和文件 ---
+	for _, blob := range report.StaleBlobs {
+		if err := r.backend.RemoveAll(blob.Dir); err != nil {
+			fail("删除内容目录 %s 失败: %v", blob.Dir, err)
+			continue
+		}
+		if err := r.blobs.Delete(blob.Hash); err != nil {
+			fail("删除内容记录 %s 失败: %v", blob.Hash, err)
+		}
+	}
+	for _, f := range report.OrphanFiles {
+		if err := r.backend.Remove(f.Key); err != nil {
+			fail("删除文件 %s 失败: %v", f.Key, err)
+		}
+	}
+	return report, nil
+}
+
+func (r *Reconciler) scan(opts ReconcileOptions) (*reconcilePlan, error) {
+	plan := &reconcilePlan{report: &ReconcileReport{
+		DryRun:        true,
+		OrphanFiles:   []OrphanFile{},
+		DanglingRows:  []DanglingRow{},
+		StaleBlobs:    []StaleBlob{},
+		RefCountFixes: []RefCountFix{},
+	}}
+	report := plan.report
+	cutoff := time.Now().Add(-opts.MinAge)
+
+	keys := make(map[string]bool)     // 被记录引用的文件
+	prefixes := make(map[string]bool) // 被记录引用的目录 (压缩包解压出的文件夹)
+	exists := func(p string) bool {
+		_, err := r.backend.Stat(p)
+		return err == nil
+	}
+
+	// --- 1. 岛屿图片 ---
+	islands, err := r.isStore.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("查询岛屿失败: %w", err)
+	}
+	islandByID := make(map[uint]*model.Island, len(islands))
+	islandByName := make(map[string]bool, len(islands))
+	for i := range islands {
+		island := &islands[i]
+		islandByID[island.ID] = island
+		islandByName[island.IsleName] = true
+		if island.IslePicPath == "" {
+			continue
+		}
+		if !exists(island.IslePicPath) {
+			report.DanglingRows = append(report.DanglingRows, DanglingRow{Table: "isles", ID: island.ID, Name: island.IsleName, Path: island.IslePicPath, Reason: ReasonContentMissing})
+			plan.islands = append(plan.islands, *island)
+			continue
+		}
+		keys[storage.Key(island.IslePicPath)] = true
+	}
+
+	// --- 2. 数据文件 (包括历史版本) ---
+	files, err := r.dfStore.ListAllVersions()
+	if err != nil {
+		return nil, fmt.Errorf("查询文件记录失败: %w", err)
+	}
+	refs := make(map[string]int) // 内容哈希 -> 实际引用数
+	for _, file := range files {
+		island, ok := islandByID[file.IsleID]
+		reason := ""
+		switch {
+		case !ok:
+			reason = ReasonIslandMissing
+		case !exists(file.DataPath):
+			reason = ReasonContentMissing
+		}
+		if reason != "" {
+			report.DanglingRows = append(report.DanglingRows, DanglingRow{Table: "data_files", ID: file.ID, Name: file.DataName, Path: file.DataPath, Reason: reason})
+			plan.files = append(plan.files, file)
+			continue
+		}
+		if file.BlobHash != "" {
+			refs[file.BlobHash]++
+			continue
+		}
+		key := storage.Key(file.DataPath)
+		if unit := legacyUnit(&file, storage.Join("uploads", island.BelongTo, island.IsleName, file.DataType)); unit != key {
+			prefixes[unit] = true
+		} else {
+			keys[key] = true
+		}
+	}
+
+	// --- 3. 历史轨迹 ---
+	trails, err := r.trailStore.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("查询轨迹记录失败: %w", err)
+	}
+	for _, trail := range trails {
+		reason := ""
+		switch {
+		case !islandByName[trail.IsleName]:
+			reason = ReasonIslandMissing
+		case !exists(trail.TrailPath):
+			reason = ReasonContentMissing
+		}
+		if reason != "" {
+			report.DanglingRows = append(report.DanglingRows, DanglingRow{Table: "history_trails", ID: trail.ID, Name: trail.TrailName, Path: trail.TrailPath, Reason: reason})
+			plan.trails = append(plan.trails, trail.ID)
+			continue
+		}
+		keys[storage.Key(trail.TrailPath)] = true
+	}
+
+	// --- 4. 内容记录：引用数按剩余的 DataFile 重新计算 ---
+	blobs, err := r.blobs.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("查询内容记录失败: %w", err)
+	}
+	knownBlobs := make(map[string]bool, len(blobs))
+	stale := make(map[string]int) // 内容目录 -> StaleBlobs 下标
+	for _, blob := range blobs {
+		dir := storage.Key(blob.Dir)
+		knownBlobs[dir] = true
+		actual := refs[blob.Hash]
+		delete(refs, blob.Hash)
+		switch {
+		case actual == 0 && blob.UpdatedAt.Before(cutoff):
+			stale[dir] = len(report.StaleBlobs)
+			report.StaleBlobs = append(report.StaleBlobs, StaleBlob{Hash: blob.Hash, Dir: dir, RefCount: blob.RefCount})
+		case actual > 0 && actual != blob.RefCount:
+			report.RefCountFixes = append(report.RefCountFixes, RefCountFix{Hash: blob.Hash, RefCount: blob.RefCount, Actual: actual})
+		}
+	}
+	// 被 DataFile 引用但缺少内容记录的目录保留，避免删除仍在使用的文件
+	for hash := range refs {
+		prefixes[blobDir(hash)] = true
+	}
+
+	// --- 5. 遍历 uploads/ ---
+	err = r.backend.Walk("uploads", func(info storage.ObjectInfo) error {
+		// 临时目录、缩略图等以 "." 开头的路径由各自的服务清理
+		if storage.IsHidden(info.Key) {
+			return nil
+		}
+		report.Totals.ScannedFiles++
+		report.Totals.ScannedBytes += info.Size
+		if dir, ok := blobDirOf(info.Key); ok && knownBlobs[dir] {
+			if i, ok := stale[dir]; ok {
+				report.StaleBlobs[i].Size += info.Size
+			}
+			return nil
+		}
+		if keys[info.Key] || underPrefix(info.Key, prefixes) {
+			return nil
+		}
+		if !info.ModTime.Before(cutoff) {
+			report.Totals.RecentFiles++
+			return nil
+		}
+		report.OrphanFiles = append(report.OrphanFiles, OrphanFile{Key: info.Key, Size: info.Size, ModTime: info.ModTime})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历 uploads 失败: %w", err)
+	}
+
+	// --- 6. 汇总 ---
+	sort.Slice(report.OrphanFiles, func(i, j int) bool { return report.OrphanFiles[i].Key < report.OrphanFiles[j].Key })
+	sort.SliceStable(report.DanglingRows, func(i, j int) bool {
+		a, b := report.DanglingRows[i], report.DanglingRows[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.ID < b.ID
+	})
+	sort.Slice(report.StaleBlobs, func(i, j int) bool { return report.StaleBlobs[i].Hash < report.StaleBlobs[j].Hash })
+	sort.Slice(report.RefCountFixes, func(i, j int) bool { return report.RefCountFixes[i].Hash < report.RefCountFixes[j].Hash })
+	t := &report.Totals
+	t.OrphanFiles = len(report.OrphanFiles)
+	for _, f := range report.OrphanFiles {
+		t.OrphanBytes += f.Size
+	}
+	t.DanglingRows = len(report.DanglingRows)
+	t.StaleBlobs = len(report.StaleBlobs)
+	for _, b := range report.StaleBlobs {
+		t.StaleBlobBytes += b.Size
+	}
+	t.RefCountFixes = len(report.RefCountFixes)
+	report.Fingerprint = reconcileFingerprint(report)
+	return plan, nil
+}
+
+// --- Helper Functions ---
+
+// blobDirOf 返回 key 所在的内容目录 uploads/blobs/<前两位>/<hash>
+func blobDirOf(key string) (string, bool) {
+	rest := strings.TrimPrefix(key, blobRoot+"/")
+	if rest == key {
+		return "", false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 3 {
+		return "", false
+	}
+	return storage.Join(blobRoot, parts[0], parts[1]), true
+}
+
+// underPrefix 判断 key 是否位于 prefixes 中的某个目录下
+func underPrefix(key string, prefixes map[string]bool) bool {
+	for dir := storage.Dir(key); dir != "" && dir != "."; dir = storage.Dir(dir) {
+		if prefixes[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileFingerprint 对需要修复的项目计算摘要，大小和修改时间不参与计算
+func reconcileFingerprint(report *ReconcileReport) string {
+	h := sha256.New()
+	for _, f := range report.OrphanFiles {
+		fmt.Fprintf(h, "file:%s\n", f.Key)
+	}
+	for _, row := range report.DanglingRows {
+		fmt.Fprintf(h, "row:%s:%d:%s\n", row.Table, row.ID, row.Reason)
+	}
+	for _, b := range report.StaleBlobs {
+		fmt.Fprintf(h, "blob:%s\n", b.Hash)
+	}
+	for _, fix := range report.RefCountFixes {
+		fmt.Fprintf(h, "ref:%s:%d\n", fix.Hash, fix.Actual)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
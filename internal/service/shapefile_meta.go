@@ -0,0 +1,143 @@
+package service
+
+import (
+	"Go_for_unity/internal/geo"
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// maxSidecarSize .prj/.cpg 这类小文件的读取上限
+const maxSidecarSize = 64 << 10
+
+// extractShapefileMeta 读取 .shp 文件头以及同名的 .shx/.dbf/.prj/.cpg，解析出元数据
+// .shp 文件头损坏视为非法上传；其余附属文件缺失时对应字段留空
+func extractShapefileMeta(backend storage.Backend, shpKey string) (*model.ShapefileMeta, model.BBox, error) {
+	header, err := readShpHeader(backend, shpKey)
+	if err != nil {
+		return nil, model.BBox{}, &InputError{Msg: err.Error()}
+	}
+	meta := &model.ShapefileMeta{
+		GeometryType: geo.ShapeTypeName(header.ShapeType),
+		Fields:       []model.AttributeField{},
+	}
+	bbox := model.BBox{MinX: header.MinX, MinY: header.MinY, MaxX: header.MaxX, MaxY: header.MaxY, Valid: true}
+
+	// 1. 坐标系 (.prj)
+	if wkt, err := readSidecar(backend, shpKey, ".prj"); err == nil {
+		crs := geo.ParsePRJ(wkt)
+		meta.CRSName = crs.Name
+		meta.EPSG = crs.EPSG
+		meta.Geographic = crs.Geographic
+		meta.WKT = crs.WKT
+	}
+
+	// 2. 属性表 (.dbf)，编码优先取 .cpg
+	cpg, _ := readSidecar(backend, shpKey, ".cpg")
+	meta.Encoding = strings.TrimSpace(cpg)
+	if dbf, err := readDBFHeader(backend, shpKey, cpg); err == nil {
+		meta.FeatureCount = dbf.RecordCount
+		for _, f := range dbf.Fields {
+			meta.Fields = append(meta.Fields, model.AttributeField{
+				Name: f.Name, Type: f.Type, Length: f.Length, Decimals: f.Decimals,
+			})
+		}
+		if meta.Encoding == "" && dbf.LanguageID == geo.DBFLanguageGBK {
+			meta.Encoding = "GBK"
+		}
+	} else if !errors.Is(err, storage.ErrNotExist) {
+		return nil, model.BBox{}, &InputError{Msg: err.Error()}
+	}
+
+	// 3. 没有 .dbf 时用 .shx 或逐条扫描 .shp 统计要素数量
+	if meta.FeatureCount == 0 {
+		count, err := countFeatures(backend, shpKey)
+		if err != nil {
+			return nil, model.BBox{}, &InputError{Msg: err.Error()}
+		}
+		meta.FeatureCount = count
+	}
+	return meta, bbox, nil
+}
+
+// CRSLabel 生成 DataFile.CRS 中保存的坐标系标识
+func CRSLabel(epsg int, name string) string {
+	if epsg > 0 {
+		return fmt.Sprintf("EPSG:%d", epsg)
+	}
+	return name
+}
+
+func readShpHeader(backend storage.Backend, key string) (geo.ShpHeader, error) {
+	r, err := backend.Open(key)
+	if err != nil {
+		return geo.ShpHeader{}, err
+	}
+	defer r.Close()
+	return geo.ReadShpHeader(r)
+}
+
+func readDBFHeader(backend storage.Backend, shpKey, cpg string) (geo.DBFHeader, error) {
+	key, err := findSidecar(backend, shpKey, ".dbf")
+	if err != nil {
+		return geo.DBFHeader{}, err
+	}
+	r, err := backend.Open(key)
+	if err != nil {
+		return geo.DBFHeader{}, err
+	}
+	defer r.Close()
+
+	var decode geo.Decoder
+	if cpg != "" {
+		decode = geo.DecoderFor(cpg, 0)
+	}
+	return geo.ReadDBFHeader(r, decode)
+}
+
+func countFeatures(backend storage.Backend, shpKey string) (int, error) {
+	if shxKey, err := findSidecar(backend, shpKey, ".shx"); err == nil {
+		if header, err := readShpHeader(backend, shxKey); err == nil {
+			return geo.ShxRecordCount(header), nil
+		}
+	}
+	r, err := backend.Open(shpKey)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	if _, err := geo.ReadShpHeader(r); err != nil {
+		return 0, err
+	}
+	return geo.CountShpRecords(r)
+}
+
+// readSidecar 读取与 .shp 同名的小文件 (例如 .prj、.cpg)
+func readSidecar(backend storage.Backend, shpKey, ext string) (string, error) {
+	key, err := findSidecar(backend, shpKey, ext)
+	if err != nil {
+		return "", err
+	}
+	r, err := backend.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(io.LimitReader(r, maxSidecarSize))
+	return string(b), err
+}
+
+// findSidecar 查找与 .shp 同名的附属文件，兼容大小写不同的扩展名
+func findSidecar(backend storage.Backend, shpKey, ext string) (string, error) {
+	base := strings.TrimSuffix(shpKey, path.Ext(shpKey))
+	for _, candidate := range []string{base + strings.ToLower(ext), base + strings.ToUpper(ext)} {
+		if _, err := backend.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", storage.ErrNotExist
+}
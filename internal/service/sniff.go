@@ -0,0 +1,255 @@
+package service
+
+import (
+	"Go_for_unity/internal/imaging"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen 判断文件类型时读取的文件头长度，与 http.DetectContentType 一致
+const sniffLen = 512
+
+// DefaultIslandPictureMIMETypes 岛屿图片默认接受的内容类型，可通过 upload.island_mime_types 配置
+var DefaultIslandPictureMIMETypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// islandPictureMIMETypes 当前生效的岛屿图片内容类型
+var islandPictureMIMETypes = DefaultIslandPictureMIMETypes
+
+// SetIslandPictureMIMETypes 修改岛屿图片接受的内容类型，为空时不检查；应在服务启动时调用
+func SetIslandPictureMIMETypes(types []string) {
+	islandPictureMIMETypes = normalizeMIMETypes(types)
+}
+
+// extensionMIME 这些扩展名的文件按扩展名解析，内容必须与扩展名一致
+var extensionMIME = map[string]string{
+	".zip":  "application/zip",
+	".glb":  "model/gltf-binary",
+	".gltf": "model/gltf+json",
+	".json": "application/json",
+}
+
+// SniffMIME 根据文件头和内容结构判断文件的 MIME 类型 (不带参数)
+// ZIP 会检查目录结构，JSON、XML 会完整解析一遍；扩展名声明为 JSON、glTF 或 XML 而内容无法解析时返回错误
+func SniffMIME(fileName string, r io.ReaderAt, size int64) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	// --- 1. 二进制格式的文件头 ---
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		if _, err := zip.NewReader(r, size); err != nil {
+			return "", fmt.Errorf("ZIP 压缩包已损坏: %v", err)
+		}
+		return "application/zip", nil
+	case bytes.HasPrefix(head, []byte("glTF")):
+		return "model/gltf-binary", nil
+	case bytes.HasPrefix(head, []byte("Kaydara FBX Binary")):
+		return "application/vnd.autodesk.fbx", nil
+	}
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !strings.HasPrefix(detected, "text/") {
+		return detected, nil
+	}
+
+	// --- 2. 文本格式：按内容结构细分 ---
+	body := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	switch {
+	case len(body) > 0 && (body[0] == '{' || body[0] == '['):
+		if err := parseJSON(io.NewSectionReader(r, 0, size)); err != nil {
+			if ext == ".json" || ext == ".gltf" {
+				return "", fmt.Errorf("JSON 格式错误: %v", err)
+			}
+			break
+		}
+		if ext == ".gltf" {
+			return "model/gltf+json", nil
+		}
+		return "application/json", nil
+	case len(body) > 0 && body[0] == '<':
+		if err := parseXML(io.NewSectionReader(r, 0, size)); err != nil {
+			if ext == ".xml" {
+				return "", fmt.Errorf("XML 格式错误: %v", err)
+			}
+			break
+		}
+		return "application/xml", nil
+	case ext == ".fbx" && bytes.HasPrefix(body, []byte("; FBX")):
+		return "application/vnd.autodesk.fbx", nil
+	}
+	switch ext {
+	case ".json", ".gltf":
+		return "", fmt.Errorf("内容不是 JSON")
+	case ".csv":
+		return "text/csv", nil
+	case ".obj":
+		return "model/obj", nil
+	}
+	return "text/plain", nil
+}
+
+// CheckContent 检查上传内容是否是数据类型接受的 MIME 类型，图片还要检查像素数，不符合时返回 InputError
+func CheckContent(dataType, fileName string, r io.ReaderAt, size int64) error {
+	p, ok := LookupProcessor(dataType)
+	if !ok {
+		return inputErrorf("不支持的文件类型: %s", dataType)
+	}
+	detected, err := checkMIME(dataType+" 类型", p.MIMETypes, fileName, r, size)
+	if err != nil {
+		return err
+	}
+	if detected == "" {
+		// 没有配置 MIME 类型时不做类型检查，但图片仍然要检查尺寸
+		if detected, err = SniffMIME(fileName, r, size); err != nil {
+			return nil
+		}
+	}
+	if strings.HasPrefix(detected, "image/") {
+		return checkImageSize(fileName, r, size)
+	}
+	return nil
+}
+
+// CheckContentFile 与 CheckContent 相同，检查本地文件
+func CheckContentFile(dataType, localPath, fileName string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return CheckContent(dataType, fileName, f, info.Size())
+}
+
+// CheckIslandPicture 检查岛屿图片的内容类型和尺寸
+func CheckIslandPicture(fileName string, r io.ReaderAt, size int64) error {
+	if _, err := checkMIME("岛屿图片", islandPictureMIMETypes, fileName, r, size); err != nil {
+		return err
+	}
+	return checkImageSize(fileName, r, size)
+}
+
+// --- Helper Functions ---
+
+// checkMIME 检查内容类型并返回识别出的 MIME 类型，allowed 为空时不检查，返回空字符串
+func checkMIME(label string, allowed []string, fileName string, r io.ReaderAt, size int64) (string, error) {
+	if len(allowed) == 0 {
+		return "", nil
+	}
+	detected, err := SniffMIME(fileName, r, size)
+	if err != nil {
+		return "", inputErrorf("%s: %v", fileName, err)
+	}
+	if want, ok := extensionMIME[strings.ToLower(filepath.Ext(fileName))]; ok && detected != want {
+		return "", inputErrorf("%s 的扩展名与内容不符: 内容为 %s", fileName, detected)
+	}
+	if !matchMIME(allowed, detected) {
+		return "", inputErrorf("%s 的内容为 %s，%s只接受 %s", fileName, detected, label, strings.Join(allowed, ", "))
+	}
+	return detected, nil
+}
+
+// checkImageSize 解码前检查图片的像素数，超过 thumbnails.max_pixels 时拒绝上传
+// 没有解码器的图片格式 (例如通过配置额外允许的类型) 无法读取尺寸，也不会生成缩略图，不做检查
+func checkImageSize(fileName string, r io.ReaderAt, size int64) error {
+	if maxImagePixels <= 0 {
+		return nil
+	}
+	err := imaging.CheckSize(io.NewSectionReader(r, 0, size), maxImagePixels)
+	if errors.Is(err, image.ErrFormat) {
+		return nil
+	}
+	if err != nil {
+		return inputErrorf("%s: %v", fileName, err)
+	}
+	return nil
+}
+
+// matchMIME 判断 mimeType 是否在 allowed 中，支持 text/* 这样的通配
+func matchMIME(allowed []string, mimeType string) bool {
+	for _, a := range allowed {
+		if a == mimeType || a == "*/*" {
+			return true
+		}
+		if prefix := strings.TrimSuffix(a, "*"); prefix != a && strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMIMETypes 统一为小写并去掉参数 (例如 "; charset=utf-8")
+func normalizeMIMETypes(types []string) []string {
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		if i := strings.IndexByte(t, ';'); i >= 0 {
+			t = t[:i]
+		}
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// parseJSON 完整读取一遍 JSON，只检查语法
+func parseJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if depth > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// parseXML 完整读取一遍 XML，只检查语法，支持 GBK 等非 UTF-8 编码声明
+func parseXML(r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/store"
+	"math"
+	"sort"
+)
+
+const (
+	earthRadius      = 6371008.8 // 地球平均半径 (米)
+	maxSearchRadius  = math.Pi * earthRadius
+	nearestStartSize = 10000.0 // 最近岛屿查询的初始搜索半径 (米)
+)
+
+// GeographicCRS 以经纬度度数表示坐标的坐标系，它们的范围可以直接与岛屿中心点比较
+// CGCS2000 与 WGS84 的差异在厘米级，这里视为相同
+var GeographicCRS = []string{"EPSG:4326", "EPSG:4490", "EPSG:4979"}
+
+// IslandDistance 带距离的岛屿查询结果
+type IslandDistance struct {
+	model.Island
+	Distance float64 `json:"distance"` // 到查询点的大圆距离 (米)
+}
+
+// SpatialService 岛屿中心点和数据范围的空间查询
+// MySQL 8 上通过空间索引 (R-tree) 按外包矩形取候选，不支持时岛屿按 1° 网格编号查询 (见 store.GridCell)；
+// 半径和最近岛屿查询再在内存中按大圆距离精确计算
+type SpatialService struct {
+	isStore *store.IslandStore
+	dfStore *store.DataFileStore
+}
+
+func NewSpatialService(isStore *store.IslandStore, dfStore *store.DataFileStore) *SpatialService {
+	return &SpatialService{isStore: isStore, dfStore: dfStore}
+}
+
+// IslandsInBBox 查询中心点在经纬度范围内的岛屿
+func (s *SpatialService) IslandsInBBox(bbox [4]float64, limit int) ([]model.Island, error) {
+	return s.isStore.FindInBBox(bbox[0], bbox[1], bbox[2], bbox[3], limit)
+}
+
+// LayersInBBox 查询范围与给定矩形相交的数据图层
+// crs 为空时按经纬度查询，匹配所有地理坐标系的图层
+func (s *SpatialService) LayersInBBox(bbox [4]float64, crs, dataType string, limit int) ([]model.DataFile, error) {
+	crsList := GeographicCRS
+	if crs != "" && !isGeographicCRS(crs) {
+		crsList = []string{crs}
+	}
+	return s.dfStore.FindIntersecting(bbox[0], bbox[1], bbox[2], bbox[3], crsList, dataType, limit)
+}
+
+// IslandsWithin 查询距离 (lat, lon) 不超过 radius 米的岛屿，按距离从近到远排序
+func (s *SpatialService) IslandsWithin(lat, lon, radius float64, limit int) ([]IslandDistance, error) {
+	if err := checkLatLon(lat, lon); err != nil {
+		return nil, err
+	}
+	if radius <= 0 {
+		return nil, inputErrorf("radius 必须大于 0")
+	}
+	candidates, err := s.candidates(lat, lon, radius)
+	if err != nil {
+		return nil, err
+	}
+	var result []IslandDistance
+	for _, c := range candidates {
+		if c.Distance <= radius {
+			result = append(result, c)
+		}
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// NearestIsland 查询距离 (lat, lon) 最近的岛屿，没有任何岛屿时返回 nil
+// 搜索半径从 10km 开始逐步扩大，候选中最近的一个落在半径内时即为全局最近
+func (s *SpatialService) NearestIsland(lat, lon float64) (*IslandDistance, error) {
+	if err := checkLatLon(lat, lon); err != nil {
+		return nil, err
+	}
+	for radius := nearestStartSize; ; radius *= 4 {
+		candidates, err := s.candidates(lat, lon, radius)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) > 0 && (candidates[0].Distance <= radius || radius >= maxSearchRadius) {
+			return &candidates[0], nil
+		}
+		if radius >= maxSearchRadius {
+			return nil, nil
+		}
+	}
+}
+
+// candidates 查询覆盖半径 radius 圆的经纬度矩形内的岛屿，按距离排序
+func (s *SpatialService) candidates(lat, lon, radius float64) ([]IslandDistance, error) {
+	var islands []model.Island
+	for _, box := range radiusBoxes(lat, lon, radius) {
+		found, err := s.isStore.FindInBBox(box[0], box[1], box[2], box[3], -1)
+		if err != nil {
+			return nil, err
+		}
+		islands = append(islands, found...)
+	}
+
+	result := make([]IslandDistance, 0, len(islands))
+	for _, island := range islands {
+		result = append(result, IslandDistance{
+			Island:   island,
+			Distance: haversine(lat, lon, island.CenterY, island.CenterX),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Distance < result[j].Distance })
+	return result, nil
+}
+
+// radiusBoxes 计算覆盖圆的经纬度矩形，跨越 ±180° 经线时拆成两个
+func radiusBoxes(lat, lon, radius float64) [][4]float64 {
+	dLat := radius / earthRadius * 180 / math.Pi
+	minLat, maxLat := math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+
+	// 圆覆盖到极点或半径超过半个地球时经度取全部
+	if minLat <= -90 || maxLat >= 90 || radius >= maxSearchRadius {
+		return [][4]float64{{-180, minLat, 180, maxLat}}
+	}
+	ratio := math.Sin(radius/earthRadius) / math.Cos(lat*math.Pi/180)
+	if ratio >= 1 {
+		return [][4]float64{{-180, minLat, 180, maxLat}}
+	}
+	dLon := math.Asin(ratio) * 180 / math.Pi
+	minLon, maxLon := lon-dLon, lon+dLon
+	switch {
+	case minLon < -180:
+		return [][4]float64{{minLon + 360, minLat, 180, maxLat}, {-180, minLat, maxLon, maxLat}}
+	case maxLon > 180:
+		return [][4]float64{{minLon, minLat, 180, maxLat}, {-180, minLat, maxLon - 360, maxLat}}
+	}
+	return [][4]float64{{minLon, minLat, maxLon, maxLat}}
+}
+
+// haversine 计算两点间的大圆距离 (米)
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func checkLatLon(lat, lon float64) error {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return inputErrorf("经纬度超出范围: lat=%v, lon=%v", lat, lon)
+	}
+	return nil
+}
+
+func isGeographicCRS(crs string) bool {
+	for _, c := range GeographicCRS {
+		if c == crs {
+			return true
+		}
+	}
+	return false
+}
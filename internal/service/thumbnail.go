@@ -0,0 +1,162 @@
+package service
+
+import (
+	"Go_for_unity/internal/imaging"
+	"Go_for_unity/internal/storage"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// thumbDirName 缩略图保存在原图同目录下的隐藏子目录中，不会经由 /uploads 直接对外提供
+const thumbDirName = ".thumbs"
+
+// DefaultThumbnailSizes 默认生成的缩略图尺寸 (长边像素)
+var DefaultThumbnailSizes = []int{128, 256, 512}
+
+// DefaultMaxImagePixels 默认允许解码的图片像素数上限 (宽×高)，可通过 thumbnails.max_pixels 配置
+const DefaultMaxImagePixels = 100_000_000
+
+// maxImagePixels 当前生效的像素数上限
+var maxImagePixels int64 = DefaultMaxImagePixels
+
+// SetMaxImagePixels 修改图片像素数上限，小于等于 0 时不限制；应在服务启动时调用
+func SetMaxImagePixels(n int64) {
+	maxImagePixels = n
+}
+
+// ThumbnailService 为岛屿图片和 jpg 数据文件生成多种尺寸的缩略图
+// 缩略图比原图旧 (原图被替换) 时会重新生成
+type ThumbnailService struct {
+	backend storage.Backend
+	sizes   []int      // 从小到大排列
+	locks   keyedMutex // 同一原图只生成一次
+}
+
+func NewThumbnailService(backend storage.Backend, sizes []int) *ThumbnailService {
+	var valid []int
+	for _, s := range sizes {
+		if s > 0 {
+			valid = append(valid, s)
+		}
+	}
+	if len(valid) == 0 {
+		valid = DefaultThumbnailSizes
+	}
+	sort.Ints(valid)
+	return &ThumbnailService{backend: backend, sizes: valid}
+}
+
+// IsThumbnailSource 判断文件是否可以生成缩略图
+func IsThumbnailSource(key string) bool {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// ThumbKey 返回原图对应尺寸的缩略图 key，例如 uploads/u/岛/.thumbs/pic.256.jpg
+func ThumbKey(srcKey string, size int) string {
+	base := path.Base(srcKey)
+	name := fmt.Sprintf("%s.%d.jpg", strings.TrimSuffix(base, path.Ext(base)), size)
+	return storage.Join(storage.Dir(srcKey), thumbDirName, name)
+}
+
+// Sizes 返回可用的缩略图尺寸
+func (s *ThumbnailService) Sizes() []int {
+	return s.sizes
+}
+
+// pickSize 选择不小于请求尺寸的最小缩略图，请求超过最大尺寸时返回最大的一个
+func (s *ThumbnailService) pickSize(size int) int {
+	for _, v := range s.sizes {
+		if v >= size {
+			return v
+		}
+	}
+	return s.sizes[len(s.sizes)-1]
+}
+
+// Get 返回原图指定尺寸的缩略图 key，缩略图不存在或已过期时先生成
+func (s *ThumbnailService) Get(srcKey string, size int) (string, error) {
+	if err := s.Ensure(srcKey); err != nil {
+		return "", err
+	}
+	return ThumbKey(srcKey, s.pickSize(size)), nil
+}
+
+// Ensure 检查所有尺寸的缩略图是否存在且比原图新，否则重新生成
+func (s *ThumbnailService) Ensure(srcKey string) error {
+	unlock := s.locks.lock(srcKey)
+	defer unlock()
+
+	src, err := s.backend.Stat(srcKey)
+	if err != nil {
+		return err
+	}
+	for _, size := range s.sizes {
+		thumb, err := s.backend.Stat(ThumbKey(srcKey, size))
+		if err != nil || thumb.ModTime.Before(src.ModTime) {
+			return s.generate(srcKey)
+		}
+	}
+	return nil
+}
+
+// Regenerate 原图上传或替换后调用，在后台重新生成缩略图
+func (s *ThumbnailService) Regenerate(srcKey string) {
+	go func() {
+		unlock := s.locks.lock(srcKey)
+		defer unlock()
+		if err := s.generate(srcKey); err != nil {
+			log.Printf("生成缩略图失败 %s: %v", srcKey, err)
+		}
+	}()
+}
+
+// Remove 删除原图的所有缩略图
+func (s *ThumbnailService) Remove(srcKey string) {
+	for _, size := range s.sizes {
+		if err := s.backend.Remove(ThumbKey(srcKey, size)); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			log.Printf("删除缩略图失败 %s: %v", ThumbKey(srcKey, size), err)
+		}
+	}
+}
+
+// generate 解码原图一次，生成所有尺寸，调用方需持有 srcKey 的锁
+// 照片带有 EXIF 方向时，缩略图按拍摄方向旋转
+func (s *ThumbnailService) generate(srcKey string) error {
+	orientation := 0
+	if r, err := s.backend.Open(srcKey); err == nil {
+		if exif, err := imaging.ReadEXIF(r); err == nil {
+			orientation = exif.Orientation
+		}
+		r.Close()
+	}
+
+	r, err := s.backend.Open(srcKey)
+	if err != nil {
+		return err
+	}
+	img, err := imaging.Decode(r, maxImagePixels)
+	r.Close()
+	if err != nil {
+		return inputErrorf("无法解码图片 %s: %v", path.Base(srcKey), err)
+	}
+
+	for _, size := range s.sizes {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, imaging.Orient(imaging.Fit(img, size), orientation)); err != nil {
+			return err
+		}
+		if err := s.backend.Put(ThumbKey(srcKey, size), &buf, int64(buf.Len())); err != nil {
+			return fmt.Errorf("保存缩略图失败: %w", err)
+		}
+	}
+	return nil
+}
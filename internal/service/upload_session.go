@@ -0,0 +1,401 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/store"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultChunkSize 客户端未指定分片大小时使用的默认值
+const DefaultChunkSize int64 = 8 << 20 // 8 MB
+
+// MaxChunkSize 单个分片允许的最大字节数
+const MaxChunkSize int64 = 256 << 20 // 256 MB
+
+var (
+	// ErrSessionNotFound 上传会话不存在或已被清理
+	ErrSessionNotFound = errors.New("上传会话不存在或已过期")
+	// ErrSessionClosed 上传会话已经完成或正在处理，不能再写入分片
+	ErrSessionClosed = errors.New("上传会话已完成或正在处理中")
+)
+
+// sessionRoot 分片文件的存放目录: <临时目录>/.sessions/<会话ID>/<分片序号>.part，由 SetStagingDir 设置
+var sessionRoot = filepath.Join("uploads", ".sessions")
+
+// CreateSessionRequest 创建上传会话的请求参数
+type CreateSessionRequest struct {
+	IsleID    uint    `json:"isle_id" binding:"required"`
+	DataType  string  `json:"data_type" binding:"required"`
+	Height    float64 `json:"height"`
+	FileName  string  `json:"file_name" binding:"required"`
+	TotalSize int64   `json:"total_size" binding:"required"`
+	ChunkSize int64   `json:"chunk_size"`
+}
+
+// ChunkStatus 描述会话中各分片的接收情况，客户端据此决定从哪里续传
+type ChunkStatus struct {
+	ReceivedChunks []int `json:"received_chunks"`
+	MissingChunks  []int `json:"missing_chunks"`
+	ReceivedBytes  int64 `json:"received_bytes"`
+}
+
+// UploadSessionService 实现可断点续传的分片上传协议：
+// 创建会话 -> PUT 各个分片 -> 查询已完成的分片 -> 完成并交给 Ingestor 处理
+type UploadSessionService struct {
+	store    *store.UploadSessionStore
+	isStore  *store.IslandStore
+	ingestor *Ingestor
+	jobs     *JobRunner
+	ttl      time.Duration // 会话在最后一次活动后保留的时长
+	locks    keyedMutex    // 按会话加锁：分片落盘 (改名) 与 complete/abort 的状态切换互斥
+}
+
+func NewUploadSessionService(store *store.UploadSessionStore, isStore *store.IslandStore, ingestor *Ingestor, jobs *JobRunner, ttl time.Duration) *UploadSessionService {
+	return &UploadSessionService{store: store, isStore: isStore, ingestor: ingestor, jobs: jobs, ttl: ttl}
+}
+
+// Create 创建一个新的上传会话
+func (s *UploadSessionService) Create(req CreateSessionRequest) (*model.UploadSession, error) {
+	if err := ValidateUpload(req.DataType, filepath.Base(req.FileName)); err != nil {
+		return nil, err
+	}
+	if req.TotalSize <= 0 {
+		return nil, inputErrorf("total_size 必须大于 0")
+	}
+	if _, err := s.isStore.GetByID(req.IsleID); err != nil {
+		return nil, ErrIslandNotFound
+	}
+
+	chunkSize := req.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > MaxChunkSize {
+		return nil, inputErrorf("chunk_size 不能超过 %d 字节", MaxChunkSize)
+	}
+
+	session := model.UploadSession{
+		ID:          newID(),
+		IsleID:      req.IsleID,
+		DataType:    req.DataType,
+		Height:      req.Height,
+		FileName:    filepath.Base(req.FileName),
+		TotalSize:   req.TotalSize,
+		ChunkSize:   chunkSize,
+		TotalChunks: int((req.TotalSize + chunkSize - 1) / chunkSize),
+		Status:      model.UploadStatusUploading,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	if err := os.MkdirAll(sessionDir(session.ID), 0755); err != nil {
+		return nil, fmt.Errorf("创建分片目录失败: %w", err)
+	}
+	if err := s.store.Create(&session); err != nil {
+		os.RemoveAll(sessionDir(session.ID))
+		return nil, fmt.Errorf("创建上传会话失败: %w", err)
+	}
+	return &session, nil
+}
+
+// Get 查询会话及其分片接收情况
+func (s *UploadSessionService) Get(id string) (*model.UploadSession, *ChunkStatus, error) {
+	session, err := s.store.GetByID(id)
+	if err != nil {
+		return nil, nil, ErrSessionNotFound
+	}
+	status := &ChunkStatus{ReceivedChunks: []int{}, MissingChunks: []int{}}
+	if session.Status == model.UploadStatusCompleted {
+		// 已完成的会话分片已被合并删除，视为全部收到
+		for i := 0; i < session.TotalChunks; i++ {
+			status.ReceivedChunks = append(status.ReceivedChunks, i)
+		}
+		status.ReceivedBytes = session.TotalSize
+		return session, status, nil
+	}
+	for i := 0; i < session.TotalChunks; i++ {
+		if info, err := os.Stat(chunkPath(id, i)); err == nil && info.Size() == expectedChunkSize(session, i) {
+			status.ReceivedChunks = append(status.ReceivedChunks, i)
+			status.ReceivedBytes += info.Size()
+		} else {
+			status.MissingChunks = append(status.MissingChunks, i)
+		}
+	}
+	return session, status, nil
+}
+
+// WriteChunk 写入序号为 index 的分片，重复写入同一分片会覆盖之前的内容
+// 每个请求写入各自的临时文件，同一分片的并发重试互不干扰，最后完整写入的一个生效
+func (s *UploadSessionService) WriteChunk(id string, index int, r io.Reader) error {
+	session, err := s.store.GetByID(id)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	if session.Status != model.UploadStatusUploading {
+		return ErrSessionClosed
+	}
+	if index < 0 || index >= session.TotalChunks {
+		return inputErrorf("分片序号超出范围: %d (共 %d 片)", index, session.TotalChunks)
+	}
+
+	expected := expectedChunkSize(session, index)
+	finalPath := chunkPath(id, index)
+
+	out, err := os.CreateTemp(sessionDir(id), filepath.Base(finalPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建分片文件失败: %w", err)
+	}
+	tmpPath := out.Name()
+	// 多读一个字节，用来判断客户端是否发送了超长的分片
+	n, err := io.Copy(out, io.LimitReader(r, expected+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入分片失败: %w", err)
+	}
+	if n != expected {
+		os.Remove(tmpPath)
+		return inputErrorf("分片 %d 大小不正确: 期望 %d 字节, 实际收到 %d 字节", index, expected, n)
+	}
+	// 写完整后再改名，保证磁盘上的 .part 文件一定是完整的
+	// 改名前在会话锁内重新检查状态，避免分片在 complete 合并期间落盘
+	unlock := s.locks.lock(id)
+	defer unlock()
+	if session, err := s.store.GetByID(id); err != nil || session.Status != model.UploadStatusUploading {
+		os.Remove(tmpPath)
+		return ErrSessionClosed
+	}
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存分片失败: %w", err)
+	}
+
+	if err := s.store.Touch(id, time.Now().Add(s.ttl)); err != nil {
+		log.Printf("延长上传会话 %s 过期时间失败: %v", id, err)
+	}
+	return nil
+}
+
+// Complete 校验分片是否齐全，然后提交后台任务：合并分片并交给 Ingestor 按类型处理
+// (shp/tif 解压、models 等直接保存)，立即返回任务快照
+func (s *UploadSessionService) Complete(id string) (*model.Job, error) {
+	session, err := s.startCompleting(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := IngestRequest{
+		IsleID:   session.IsleID,
+		DataType: session.DataType,
+		Height:   session.Height,
+		FileName: session.FileName,
+	}
+	job, err := s.jobs.Submit(UploadJob(req), func(p *JobProgress) (*model.DataFile, error) {
+		dataFile, err := s.complete(session, req, p)
+		if err != nil {
+			// 处理失败时回退状态，客户端可以补传分片后重试
+			if _, revertErr := s.store.TransitionStatus(id, model.UploadStatusCompleting, model.UploadStatusUploading); revertErr != nil {
+				log.Printf("回退上传会话 %s 状态失败: %v", id, revertErr)
+			}
+			return nil, err
+		}
+		if err := s.store.MarkCompleted(id, dataFile.ID, time.Now().Add(s.ttl)); err != nil {
+			log.Printf("标记上传会话 %s 完成失败: %v", id, err)
+		}
+		os.RemoveAll(sessionDir(id))
+		return dataFile, nil
+	})
+	if err != nil {
+		s.store.TransitionStatus(id, model.UploadStatusCompleting, model.UploadStatusUploading)
+		return nil, err
+	}
+	if err := s.store.SetJobID(id, job.ID); err != nil {
+		log.Printf("记录上传会话 %s 的任务 ID 失败: %v", id, err)
+	}
+	return job, nil
+}
+
+// startCompleting 在会话锁内校验分片是否齐全并切换到 completing 状态
+// 切换之后 WriteChunk 不会再改名分片，合并期间分片保持不变
+func (s *UploadSessionService) startCompleting(id string) (*model.UploadSession, error) {
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	session, status, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != model.UploadStatusUploading {
+		return nil, ErrSessionClosed
+	}
+	if len(status.MissingChunks) > 0 {
+		return nil, inputErrorf("仍有 %d 个分片未上传: %v", len(status.MissingChunks), status.MissingChunks)
+	}
+
+	ok, err := s.store.TransitionStatus(id, model.UploadStatusUploading, model.UploadStatusCompleting)
+	if err != nil {
+		return nil, fmt.Errorf("更新会话状态失败: %w", err)
+	}
+	if !ok {
+		return nil, ErrSessionClosed
+	}
+	return session, nil
+}
+
+// complete 在后台任务中合并分片并处理文件
+func (s *UploadSessionService) complete(session *model.UploadSession, req IngestRequest, p *JobProgress) (*model.DataFile, error) {
+	p.Stage(model.JobStatusExtracting)
+	stagingDir, err := NewStagingDir("chunked-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+
+	assembledPath := filepath.Join(stagingDir, session.FileName)
+	if err := assembleChunks(session, assembledPath); err != nil {
+		os.RemoveAll(stagingDir)
+		return nil, fmt.Errorf("合并分片失败: %w", err)
+	}
+
+	req.SrcPath = assembledPath
+	return s.ingestor.Task(req, stagingDir)(p)
+}
+
+// RecoverInterrupted 服务重启后，把停留在 completing 状态的会话恢复为 uploading，
+// 它们对应的后台任务已经随进程退出而中断，客户端可以重新调用 complete
+func (s *UploadSessionService) RecoverInterrupted() {
+	n, err := s.store.ResetStatus(model.UploadStatusCompleting, model.UploadStatusUploading)
+	if err != nil {
+		log.Printf("恢复被中断的上传会话失败: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("已恢复 %d 个被中断的上传会话", n)
+	}
+}
+
+// Abort 取消上传会话并删除已上传的分片
+func (s *UploadSessionService) Abort(id string) error {
+	unlock := s.locks.lock(id)
+	defer unlock()
+	session, err := s.store.GetByID(id)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	if session.Status == model.UploadStatusCompleting {
+		return ErrSessionClosed
+	}
+	os.RemoveAll(sessionDir(id))
+	return s.store.Delete(id)
+}
+
+// StartJanitor 启动后台协程，定期清理过期的上传会话
+func (s *UploadSessionService) StartJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.CleanupExpired()
+		}
+	}()
+}
+
+// CleanupExpired 删除所有过期会话的分片和记录
+func (s *UploadSessionService) CleanupExpired() {
+	sessions, err := s.store.ListExpired(time.Now())
+	if err != nil {
+		log.Printf("查询过期上传会话失败: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		removed, err := s.removeIfExpired(session.ID)
+		if err != nil {
+			log.Printf("删除过期上传会话 %s 失败: %v", session.ID, err)
+			continue
+		}
+		if removed {
+			log.Printf("已清理过期上传会话: %s (%s)", session.ID, session.FileName)
+		}
+	}
+}
+
+// removeIfExpired 在会话锁内重新读取会话，仍然过期且不在合并中时才删除分片和记录
+// 列出过期会话之后，分片写入可能已经延长了过期时间，complete 也可能已经开始合并
+func (s *UploadSessionService) removeIfExpired(id string) (bool, error) {
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	session, err := s.store.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if session.Status == model.UploadStatusCompleting || !session.ExpiresAt.Before(time.Now()) {
+		return false, nil
+	}
+	os.RemoveAll(sessionDir(id))
+	if err := s.store.Delete(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// --- Helper Functions ---
+
+// assembleChunks 按顺序把所有分片拼接成完整文件，每个分片读完立即关闭
+func assembleChunks(session *model.UploadSession, dst string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < session.TotalChunks; i++ {
+		if err := appendFile(out, chunkPath(session.ID, i)); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	return out.Close()
+}
+
+func appendFile(out io.Writer, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// expectedChunkSize 计算第 index 个分片应有的大小，最后一片可能不足 ChunkSize
+func expectedChunkSize(session *model.UploadSession, index int) int64 {
+	if index == session.TotalChunks-1 {
+		return session.TotalSize - int64(index)*session.ChunkSize
+	}
+	return session.ChunkSize
+}
+
+func sessionDir(id string) string {
+	return filepath.Join(sessionRoot, id)
+}
+
+func chunkPath(id string, index int) string {
+	return filepath.Join(sessionDir(id), fmt.Sprintf("%06d.part", index))
+}
+
+// newID 生成一个随机的 32 位十六进制 ID
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
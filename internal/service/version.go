@@ -0,0 +1,175 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/store"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// versionIgnoredFields 比较版本差异时忽略的字段，它们每个版本都不同，不反映内容变化
+var versionIgnoredFields = map[string]bool{
+	"ID":        true,
+	"CreatedAt": true,
+	"UpdatedAt": true,
+	"DeletedAt": true,
+	"LineageID": true,
+	"Version":   true,
+	"Active":    true,
+}
+
+// FieldChange 两个版本之间一个字段的变化，字段名与接口返回的 JSON 一致，嵌套字段用 . 连接 (例如 Shapefile.featureCount)
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// VersionDiff 两个版本之间的元数据差异
+type VersionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// VersionService 数据文件的版本历史、差异比较和回滚
+type VersionService struct {
+	dfStore *store.DataFileStore
+}
+
+func NewVersionService(dfStore *store.DataFileStore) *VersionService {
+	return &VersionService{dfStore: dfStore}
+}
+
+// List 返回 id 所在版本链的全部版本，按版本号升序
+func (s *VersionService) List(id uint) ([]model.DataFile, error) {
+	file, err := s.dfStore.GetByID(id)
+	if err != nil {
+		return nil, ErrDataFileNotFound
+	}
+	return s.dfStore.ListVersions(file.LineageID)
+}
+
+// Diff 比较 id 所在版本链中两个版本的元数据
+// to 为 0 时取生效版本，from 为 0 时取 to 的上一个版本
+func (s *VersionService) Diff(id uint, from, to int) (*VersionDiff, error) {
+	file, err := s.dfStore.GetByID(id)
+	if err != nil {
+		return nil, ErrDataFileNotFound
+	}
+	versions, err := s.dfStore.ListVersions(file.LineageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 确定要比较的两个版本
+	if to == 0 {
+		for _, v := range versions {
+			if v.Active {
+				to = v.Version
+			}
+		}
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	fromFile, toFile := findVersion(versions, from), findVersion(versions, to)
+	if fromFile == nil || toFile == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	// 2. 展开为字段路径后逐一比较
+	a, err := flattenVersion(fromFile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := flattenVersion(toFile)
+	if err != nil {
+		return nil, err
+	}
+	diff := &VersionDiff{From: from, To: to, Changes: []FieldChange{}}
+	for field := range unionKeys(a, b) {
+		if !reflect.DeepEqual(a[field], b[field]) {
+			diff.Changes = append(diff.Changes, FieldChange{Field: field, From: a[field], To: b[field]})
+		}
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Field < diff.Changes[j].Field })
+	return diff, nil
+}
+
+// Rollback 把 id 所在版本链的生效版本切换为 version，列表和导出随之使用该版本
+// 回滚不删除任何版本，之后再次上传时版本号仍在最大值上递增
+func (s *VersionService) Rollback(id uint, version int) (*model.DataFile, error) {
+	file, err := s.dfStore.GetByID(id)
+	if err != nil {
+		return nil, ErrDataFileNotFound
+	}
+	target, err := s.dfStore.GetVersion(file.LineageID, version)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	if target.Active {
+		return target, nil
+	}
+	if err := s.dfStore.Activate(file.LineageID, target.ID); err != nil {
+		return nil, err
+	}
+	target.Active = true
+	return target, nil
+}
+
+// --- Helper Functions ---
+
+// findVersion 在版本列表中查找指定版本号
+func findVersion(versions []model.DataFile, version int) *model.DataFile {
+	for i := range versions {
+		if versions[i].Version == version {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+// flattenVersion 把文件记录按 JSON 序列化结果展开为 "字段路径 -> 值"，对象逐层展开，数组作为整体比较
+func flattenVersion(file *model.DataFile) (map[string]interface{}, error) {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for field := range versionIgnoredFields {
+		delete(doc, field)
+	}
+	out := make(map[string]interface{})
+	flattenInto(out, "", doc)
+	return out, nil
+}
+
+func flattenInto(out map[string]interface{}, prefix string, obj map[string]interface{}) {
+	for k, v := range obj {
+		field := k
+		if prefix != "" {
+			field = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenInto(out, field, nested)
+			continue
+		}
+		out[field] = v
+	}
+}
+
+func unionKeys(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
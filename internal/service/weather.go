@@ -0,0 +1,182 @@
+package service
+
+import (
+	"Go_for_unity/internal/model"
+	"Go_for_unity/internal/storage"
+	"Go_for_unity/internal/store"
+	"Go_for_unity/internal/weather"
+	"errors"
+	"path"
+	"time"
+)
+
+const (
+	DefaultWeatherWindowLimit = 500  // 时间段查询默认返回的时间步数量
+	MaxWeatherWindowLimit     = 5000 // 时间段查询单次最多返回的时间步数量
+)
+
+// extractWeatherMeta 按 weather 包定义的格式解析气象文件，返回变量信息和全部时间步
+// 格式错误视为非法上传；没有时区的时间按服务器所在时区解释
+func extractWeatherMeta(backend storage.Backend, key string) (*model.WeatherMeta, []model.WeatherStep, error) {
+	r, err := backend.Open(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	series, err := weather.Parse(r, path.Base(key), time.Local)
+	if errors.Is(err, weather.ErrSchema) {
+		return nil, nil, &InputError{Msg: err.Error()}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := &model.WeatherMeta{
+		Format:          series.Format,
+		StepCount:       len(series.Steps),
+		Start:           series.Start(),
+		End:             series.End(),
+		IntervalSeconds: series.Interval().Seconds(),
+	}
+	for _, v := range series.Variables {
+		meta.Variables = append(meta.Variables, model.WeatherVariable{Name: v.Name, Unit: v.Unit, Interp: v.Interp})
+	}
+
+	steps := make([]model.WeatherStep, len(series.Steps))
+	for i, s := range series.Steps {
+		values := make(map[string]*float64, len(series.Variables))
+		for j, v := range series.Variables {
+			values[v.Name] = s.Values[j]
+		}
+		steps[i] = model.WeatherStep{Time: s.Time, Values: values}
+	}
+	return meta, steps, nil
+}
+
+// WeatherWindow 时间段查询的结果，Next 不为空表示结果被截断，下一页从 Next 开始查询
+type WeatherWindow struct {
+	Variables []model.WeatherVariable `json:"variables"`
+	Steps     []model.WeatherStep     `json:"steps"`
+	Next      *time.Time              `json:"next"`
+}
+
+// WeatherSample 某一时刻的插值结果
+type WeatherSample struct {
+	Time   time.Time           `json:"time"`
+	Exact  bool                `json:"exact"` // 是否正好落在时间步上 (无需插值)
+	Values map[string]*float64 `json:"values"`
+}
+
+// WeatherService 查询已索引的气象时间序列，Unity 按需拉取时间段或插值结果进行回放
+type WeatherService struct {
+	steps *store.WeatherStepStore
+}
+
+func NewWeatherService(steps *store.WeatherStepStore) *WeatherService {
+	return &WeatherService{steps: steps}
+}
+
+// Window 返回 [from, to] 内的时间步，vars 为空时返回全部变量
+func (s *WeatherService) Window(file *model.DataFile, from, to time.Time, vars []string, limit int) (*WeatherWindow, error) {
+	selected, err := selectWeatherVars(file, vars)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, inputErrorf("结束时间不能早于开始时间")
+	}
+	if limit <= 0 {
+		limit = DefaultWeatherWindowLimit
+	}
+	if limit > MaxWeatherWindowLimit {
+		limit = MaxWeatherWindowLimit
+	}
+
+	// 多查一条用于判断是否还有下一页
+	steps, err := s.steps.Window(file.ID, from, to, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	result := &WeatherWindow{Variables: selected, Steps: steps}
+	if len(steps) > limit {
+		next := steps[limit].Time
+		result.Next = &next
+		result.Steps = steps[:limit]
+	}
+	for i := range result.Steps {
+		result.Steps[i].Values = pickValues(result.Steps[i].Values, selected)
+	}
+	return result, nil
+}
+
+// At 返回 t 时刻各变量的值，t 不在时间步上时按变量的插值方式计算，超出文件时间范围时返回 InputError
+func (s *WeatherService) At(file *model.DataFile, t time.Time, vars []string) (*WeatherSample, error) {
+	selected, err := selectWeatherVars(file, vars)
+	if err != nil {
+		return nil, err
+	}
+	before, after, err := s.steps.Around(file.ID, t)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil || after == nil {
+		meta := file.Weather
+		return nil, inputErrorf("时间 %s 超出气象数据范围 %s ~ %s",
+			t.Format(time.RFC3339), meta.Start.Format(time.RFC3339), meta.End.Format(time.RFC3339))
+	}
+
+	sample := &WeatherSample{Time: t, Exact: before.ID == after.ID}
+	if sample.Exact {
+		sample.Values = pickValues(before.Values, selected)
+		return sample, nil
+	}
+
+	vars0 := make([]weather.Variable, len(selected))
+	v0 := make([]*float64, len(selected))
+	v1 := make([]*float64, len(selected))
+	for i, v := range selected {
+		vars0[i] = weather.Variable{Name: v.Name, Interp: v.Interp}
+		v0[i], v1[i] = before.Values[v.Name], after.Values[v.Name]
+	}
+	values := weather.Interpolate(vars0, before.Time, v0, after.Time, v1, t)
+	sample.Values = make(map[string]*float64, len(selected))
+	for i, v := range selected {
+		sample.Values[v.Name] = values[i]
+	}
+	return sample, nil
+}
+
+// selectWeatherVars 校验文件类型并返回要查询的变量，未知变量返回 InputError
+func selectWeatherVars(file *model.DataFile, names []string) ([]model.WeatherVariable, error) {
+	if file.DataType != "weather" || file.Weather == nil {
+		return nil, inputErrorf("该文件不是已解析的气象文件")
+	}
+	if len(names) == 0 {
+		return file.Weather.Variables, nil
+	}
+	selected := make([]model.WeatherVariable, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, v := range file.Weather.Variables {
+			if v.Name == name {
+				selected = append(selected, v)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, inputErrorf("气象文件中没有变量 %s", name)
+		}
+	}
+	return selected, nil
+}
+
+// pickValues 只保留选中的变量
+func pickValues(values map[string]*float64, selected []model.WeatherVariable) map[string]*float64 {
+	out := make(map[string]*float64, len(selected))
+	for _, v := range selected {
+		out[v.Name] = values[v.Name]
+	}
+	return out
+}
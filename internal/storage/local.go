@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// LocalConfig 本地磁盘驱动配置
+type LocalConfig struct {
+	Root string // 根目录，key "uploads/a.jpg" 对应 <Root>/uploads/a.jpg，默认为当前目录
+}
+
+// Local 把对象保存在本地磁盘上，保持与旧版 ./uploads 目录完全相同的布局
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) *Local {
+	if root == "" {
+		root = "."
+	}
+	return &Local{root: root}
+}
+
+// path 把 key 转换为本地路径，Key 已经去掉了 ".."，不会越出根目录
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(Key(key)))
+}
+
+func (l *Local) Put(key string, r io.Reader, size int64) error {
+	dst := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".put-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (l *Local) Import(localPath, key string) error {
+	dst := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	if err := os.Rename(localPath, dst); err == nil {
+		return nil
+	}
+	// 跨文件系统时无法 rename，退化为逐个复制
+	if err := copyTree(localPath, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(localPath)
+}
+
+func (l *Local) Open(key string) (io.ReadCloser, error) {
+	f, err := os.Open(l.path(key))
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		f.Close()
+		return nil, ErrNotExist
+	}
+	return f, nil
+}
+
+func (l *Local) Stat(key string) (ObjectInfo, error) {
+	info, err := os.Stat(l.path(key))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if info.IsDir() {
+		return ObjectInfo{}, ErrNotExist
+	}
+	return ObjectInfo{Key: Key(key), Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (l *Local) Remove(key string) error {
+	if err := os.Remove(l.path(key)); err != nil && !isNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) RemoveAll(key string) error {
+	if Key(key) == "" {
+		return errRemoveRoot
+	}
+	return os.RemoveAll(l.path(key))
+}
+
+func (l *Local) Walk(dir string, fn func(ObjectInfo) error) error {
+	root := l.path(dir)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if isNotExist(err) && p == root {
+				return SkipAll // 目录不存在视为空
+			}
+			return err
+		}
+		if d.IsDir() || p == root {
+			return nil // 只遍历 dir 下的对象，dir 本身是文件时视为空
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: Key(filepath.ToSlash(rel)), Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) Serve(w http.ResponseWriter, r *http.Request, key string) {
+	f, err := os.Open(l.path(key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	// ServeContent 会处理 Range、If-Modified-Since 等请求头
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// copyTree 复制文件或目录
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(p, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
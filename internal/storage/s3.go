@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// S3Config S3 兼容存储 (AWS S3、MinIO 等) 的配置
+type S3Config struct {
+	Endpoint      string        // 例如 127.0.0.1:9000 或 s3.amazonaws.com
+	Region        string        // 例如 us-east-1
+	Bucket        string        // 存储桶名称
+	AccessKey     string        // 访问密钥
+	SecretKey     string        // 私有密钥
+	UseSSL        bool          // 是否使用 https
+	ServeMode     string        // redirect: 302 跳转到预签名 URL；proxy: 由服务端转发内容
+	PresignExpiry time.Duration // 预签名 URL 的有效期
+}
+
+// S3 使用 path-style 访问 (http://endpoint/bucket/key) 的 S3 兼容驱动，方便对接本地 MinIO
+type S3 struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 存储需要配置 endpoint 和 bucket")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.ServeMode == "" {
+		cfg.ServeMode = "redirect"
+	}
+	if cfg.PresignExpiry <= 0 {
+		cfg.PresignExpiry = time.Hour
+	}
+	return &S3{cfg: cfg, client: &http.Client{}}, nil
+}
+
+// EnsureBucket 检查存储桶是否存在，不存在则创建
+func (s *S3) EnsureBucket() error {
+	resp, err := s.do(http.MethodHead, "", nil, nil, -1, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("检查存储桶失败: %s", resp.Status)
+	}
+
+	resp, err = s.do(http.MethodPut, "", nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, "创建存储桶")
+}
+
+func (s *S3) Put(key string, r io.Reader, size int64) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType(key))
+	resp, err := s.do(http.MethodPut, Key(key), nil, r, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, "上传对象 "+key)
+}
+
+func (s *S3) Import(localPath, key string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if err := s.putFile(localPath, Key(key)); err != nil {
+			return err
+		}
+		return os.Remove(localPath)
+	}
+
+	// 目录：逐个上传其中的文件，保持相对路径
+	err = filepath.WalkDir(localPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, p)
+		if err != nil {
+			return err
+		}
+		return s.putFile(p, Join(key, filepath.ToSlash(rel)))
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(localPath)
+}
+
+func (s *S3) putFile(localPath, key string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return s.Put(key, f, info.Size())
+}
+
+func (s *S3) Open(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, Key(key), nil, nil, -1, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, "读取对象 "+key); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Stat(key string) (ObjectInfo, error) {
+	resp, err := s.do(http.MethodHead, Key(key), nil, nil, -1, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp, "查询对象 "+key); err != nil {
+		return ObjectInfo{}, err
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return ObjectInfo{Key: Key(key), Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+func (s *S3) Remove(key string) error {
+	resp, err := s.do(http.MethodDelete, Key(key), nil, nil, -1, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkResponse(resp, "删除对象 "+key)
+}
+
+func (s *S3) RemoveAll(key string) error {
+	if Key(key) == "" {
+		return errRemoveRoot
+	}
+	// 先收集再删除，避免边遍历边删除影响分页
+	var keys []string
+	if err := s.Walk(key, func(obj ObjectInfo) error {
+		keys = append(keys, obj.Key)
+		return nil
+	}); err != nil {
+		return err
+	}
+	keys = append(keys, Key(key))
+	for _, k := range keys {
+		if err := s.Remove(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listResult ListObjectsV2 的响应结构
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3) Walk(dir string, fn func(ObjectInfo) error) error {
+	prefix := Key(dir) + "/"
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", query, nil, -1, nil)
+		if err != nil {
+			return err
+		}
+		var result listResult
+		if err := checkResponse(resp, "列出对象 "+prefix); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("解析对象列表失败: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			err := fn(ObjectInfo{Key: obj.Key, Size: obj.Size, ModTime: obj.LastModified})
+			if err == SkipAll {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3) Serve(w http.ResponseWriter, r *http.Request, key string) {
+	if s.cfg.ServeMode == "redirect" {
+		http.Redirect(w, r, s.PresignedURL(key, s.cfg.PresignExpiry), http.StatusFound)
+		return
+	}
+
+	// proxy 模式：转发 Range 等请求头，由服务端读取后返回
+	header := http.Header{}
+	for _, h := range []string{"Range", "If-None-Match", "If-Modified-Since"} {
+		if v := r.Header.Get(h); v != "" {
+			header.Set(h, v)
+		}
+	}
+	resp, err := s.do(http.MethodGet, Key(key), nil, nil, -1, header)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	for _, h := range []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"} {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// PresignedURL 生成对象的预签名下载地址，expires <= 0 时使用配置的有效期
+func (s *S3) PresignedURL(key string, expires time.Duration) string {
+	if expires <= 0 {
+		expires = s.cfg.PresignExpiry
+	}
+	return s.presign(s.objectURL(Key(key), nil), expires, time.Now())
+}
+
+// do 发送签名后的请求，key 为空时请求的是存储桶本身
+func (s *S3) do(method, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	s.signRequest(req, time.Now())
+	return s.client.Do(req)
+}
+
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+	scheme := "http"
+	if s.cfg.UseSSL {
+		scheme = "https"
+	}
+	p := "/" + s.cfg.Bucket
+	if key != "" {
+		p += "/" + key
+	}
+	u := &url.URL{Scheme: scheme, Host: s.cfg.Endpoint, Path: p, RawPath: uriEncode(p, false)}
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return u
+}
+
+// checkResponse 把非 2xx 响应转换为错误，404 转换为 ErrNotExist
+func checkResponse(resp *http.Response, action string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", action, ErrNotExist)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s失败: %s %s", action, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// contentType 根据扩展名推断 Content-Type
+func contentType(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotExist 对象不存在，与 fs.ErrNotExist 相同，可以直接用 errors.Is 判断
+var ErrNotExist = fs.ErrNotExist
+
+// errRemoveRoot 防止因为路径为空而误删整个存储
+var errRemoveRoot = errors.New("拒绝删除存储根目录")
+
+// SkipAll 在 Walk 回调中返回它可以提前结束遍历
+var SkipAll = fs.SkipAll
+
+func init() {
+	// 标准库的 MIME 表中没有 GeoJSON，下载时需要正确的 Content-Type
+	mime.AddExtensionType(".geojson", "application/geo+json")
+}
+
+// ObjectInfo 描述存储中的一个对象
+type ObjectInfo struct {
+	Key     string    // 对象的 key，使用 "/" 分隔，例如 uploads/user/岛屿/pic.jpg
+	Size    int64     // 字节数
+	ModTime time.Time // 最后修改时间
+}
+
+// Backend 存储后端接口
+// key 统一使用 "/" 分隔的相对路径 (例如 uploads/blobs/ab/<hash>/a.shp)，与数据库中保存的路径一致
+type Backend interface {
+	// Put 从 r 读取 size 个字节写入 key，已存在时覆盖
+	Put(key string, r io.Reader, size int64) error
+	// Import 把本地文件或目录移动到 key 下，目录会保持内部结构，成功后本地源会被删除
+	Import(localPath, key string) error
+	// Open 打开对象用于读取
+	Open(key string) (io.ReadCloser, error)
+	// Stat 查询对象信息，不存在时返回 ErrNotExist
+	Stat(key string) (ObjectInfo, error)
+	// Remove 删除单个对象，对象不存在时不报错
+	Remove(key string) error
+	// RemoveAll 删除 key 本身以及 key/ 下的所有对象，类似 os.RemoveAll
+	RemoveAll(key string) error
+	// Walk 遍历 dir/ 下的所有对象，回调返回 SkipAll 时提前结束
+	Walk(dir string, fn func(ObjectInfo) error) error
+	// Serve 把对象作为 HTTP 响应返回：本地直接输出文件，S3 重定向到预签名 URL 或由服务端代理
+	Serve(w http.ResponseWriter, r *http.Request, key string)
+}
+
+// Config 存储后端配置，对应配置文件中的 storage 节点
+type Config struct {
+	Driver string // local 或 s3
+	Local  LocalConfig
+	S3     S3Config
+}
+
+// New 根据配置创建存储后端
+func New(cfg Config) (Backend, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.Local.Root), nil
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("不支持的存储驱动: %s", cfg.Driver)
+	}
+}
+
+// Key 把数据库中保存的路径规范化为存储 key
+// 兼容 Windows 下保存的反斜杠路径，例如 uploads\user\岛屿\pic.jpg
+func Key(p string) string {
+	p = strings.ReplaceAll(p, "\\", "/")
+	p = path.Clean("/" + p)
+	return strings.TrimPrefix(p, "/")
+}
+
+// Join 拼接 key
+func Join(elem ...string) string {
+	return Key(path.Join(elem...))
+}
+
+// Dir 返回 key 的父级，等价于 path.Dir
+func Dir(key string) string {
+	return path.Dir(Key(key))
+}
+
+// Exists 判断 key 对应的对象或以 key 为前缀的 "目录" 是否存在
+func Exists(b Backend, key string) bool {
+	if _, err := b.Stat(key); err == nil {
+		return true
+	}
+	found := false
+	b.Walk(key, func(ObjectInfo) error {
+		found = true
+		return SkipAll
+	})
+	return found
+}
+
+// Copy 把 src 对象或 src/ 下的所有对象复制到 dst，目录会保持内部结构
+func Copy(b Backend, src, dst string) error {
+	if info, err := b.Stat(src); err == nil {
+		return copyObject(b, info, dst)
+	}
+	prefix := Key(src) + "/"
+	return b.Walk(src, func(obj ObjectInfo) error {
+		return copyObject(b, obj, Join(dst, strings.TrimPrefix(obj.Key, prefix)))
+	})
+}
+
+// Move 把 src 移动到 dst：先复制再删除源，适用于所有后端
+func Move(b Backend, src, dst string) error {
+	if err := Copy(b, src, dst); err != nil {
+		b.RemoveAll(dst)
+		return err
+	}
+	return b.RemoveAll(src)
+}
+
+func copyObject(b Backend, obj ObjectInfo, dst string) error {
+	r, err := b.Open(obj.Key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return b.Put(dst, r, obj.Size)
+}
+
+// IsHidden 判断 key 是否包含以 "." 开头的路径段 (例如 uploads/.staging)，这类对象不对外提供访问
+func IsHidden(key string) bool {
+	for _, seg := range strings.Split(Key(key), "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotExist 判断错误是否表示对象不存在
+func isNotExist(err error) bool {
+	return errors.Is(err, ErrNotExist)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"Go_for_unity/internal/model"
+	"gorm.io/gorm"
+)
+
+type BlobStore struct {
+	db *gorm.DB
+}
+
+func NewBlobStore(db *gorm.DB) *BlobStore {
+	return &BlobStore{db: db}
+}
+
+// Create 创建一条内容记录
+func (s *BlobStore) Create(blob *model.Blob) error {
+	return s.db.Create(blob).Error
+}
+
+// GetByHash 根据哈希查询内容记录
+func (s *BlobStore) GetByHash(hash string) (*model.Blob, error) {
+	var blob model.Blob
+	err := s.db.Where("hash = ?", hash).First(&blob).Error
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// IncRef 引用数加一
+func (s *BlobStore) IncRef(hash string) error {
+	return s.db.Model(&model.Blob{}).Where("hash = ?", hash).
+		Update("ref_count", gorm.Expr("ref_count + 1")).Error
+}
+
+// DecRef 引用数减一，返回减少后的引用数
+func (s *BlobStore) DecRef(hash string) (int, error) {
+	var blob model.Blob
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Blob{}).Where("hash = ? AND ref_count > 0", hash).
+			Update("ref_count", gorm.Expr("ref_count - 1")).Error; err != nil {
+			return err
+		}
+		return tx.Where("hash = ?", hash).First(&blob).Error
+	})
+	return blob.RefCount, err
+}
+
+// Delete 删除内容记录
+func (s *BlobStore) Delete(hash string) error {
+	return s.db.Where("hash = ?", hash).Delete(&model.Blob{}).Error
+}
+
+// ListAll 获取所有内容记录 (用于存储对账)
+func (s *BlobStore) ListAll() ([]model.Blob, error) {
+	var blobs []model.Blob
+	err := s.db.Find(&blobs).Error
+	return blobs, err
+}
+
+// SetRefCount 把引用数修正为实际引用该内容的 DataFile 数量
+func (s *BlobStore) SetRefCount(hash string, refCount int) error {
+	return s.db.Model(&model.Blob{}).Where("hash = ?", hash).Update("ref_count", refCount).Error
+}
@@ -2,11 +2,14 @@ package store
 
 import (
 	"Go_for_unity/internal/model"
+	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DataFileStore struct {
-	db *gorm.DB
+	db      *gorm.DB
+	spatial bool // 是否已建立数据范围的空间索引 (见 EnableSpatialIndex)
 }
 
 // DataFileCountResult 用于接收聚合查询结果的临时结构
@@ -20,22 +23,195 @@ func NewDataFileStore(db *gorm.DB) *DataFileStore {
 	return &DataFileStore{db: db}
 }
 
-// Create 创建一条文件记录
+// weatherStepBatchSize 写入气象时间步时每批插入的行数，避免单条 SQL 的占位符超过上限
+const weatherStepBatchSize = 1000
+
+// Create 创建一条文件记录，气象文件的时间步在同一事务中分批写入
 func (s *DataFileStore) Create(file *model.DataFile) error {
-	return s.db.Create(file).Error
+	return s.db.Session(&gorm.Session{CreateBatchSize: weatherStepBatchSize}).Create(file).Error
+}
+
+// CreateVersion 创建文件记录并接入版本链
+// 岛屿下已有类型和名称相同的生效文件时，新记录成为该版本链的下一个版本并取代原生效版本；否则开始新的版本链
+// 返回被取代的原生效版本 ID，开始新版本链时为 0 (用于 UndoVersion)
+func (s *DataFileStore) CreateVersion(file *model.DataFile) (uint, error) {
+	var previousID uint
+	err := s.db.Session(&gorm.Session{CreateBatchSize: weatherStepBatchSize}).Transaction(func(tx *gorm.DB) error {
+		// 1. 查找当前生效的同名文件
+		var current model.DataFile
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("isle_id = ? AND data_type = ? AND data_name = ? AND active = ?", file.IsleID, file.DataType, file.DataName, true).
+			Order("id DESC").
+			Take(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 2. 没有同名文件：作为第一个版本创建，版本链 ID 即自身 ID
+			file.Version = 1
+			file.Active = true
+			if err := tx.Create(file).Error; err != nil {
+				return err
+			}
+			file.LineageID = file.ID
+			return tx.Model(&model.DataFile{}).Where("id = ?", file.ID).Update("lineage_id", file.ID).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// 3. 已有同名文件：版本号取版本链中的最大值加一，原生效版本失效
+		var maxVersion int
+		if err := tx.Model(&model.DataFile{}).Where("lineage_id = ?", current.LineageID).
+			Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.DataFile{}).Where("lineage_id = ?", current.LineageID).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+		// 显示样式属于版本链，新版本沿用
+		if file.Style == nil {
+			file.Style = current.Style
+		}
+		file.LineageID = current.LineageID
+		file.Version = maxVersion + 1
+		file.Active = true
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		previousID = current.ID
+		return nil
+	})
+	return previousID, err
+}
+
+// BackfillLineage 为引入版本链之前创建的记录补齐版本链 ID，每条旧记录各自成为一个版本链
+func (s *DataFileStore) BackfillLineage() error {
+	return s.db.Model(&model.DataFile{}).Where("lineage_id = 0 OR lineage_id IS NULL").
+		Update("lineage_id", gorm.Expr("id")).Error
+}
+
+// EnableSpatialIndex 为数据范围建立空间索引 (MySQL 8 的 SPATIAL INDEX)，失败时范围查询继续使用 B-tree 联合索引
+// 没有范围的文件生成一个位于原点的点，查询时由 bbox_valid 排除
+func (s *DataFileStore) EnableSpatialIndex() error {
+	expr := "IF(bbox_valid, ST_MakeEnvelope(POINT(bbox_min_x, bbox_min_y), POINT(bbox_max_x, bbox_max_y)), POINT(0, 0))"
+	err := addSpatialIndex(s.db, &model.DataFile{}, "data_files", "footprint", "GEOMETRY", expr, "idx_data_files_footprint")
+	s.spatial = err == nil
+	return err
+}
+
+// ListVersions 按版本号顺序查询版本链中的所有版本
+func (s *DataFileStore) ListVersions(lineageID uint) ([]model.DataFile, error) {
+	var files []model.DataFile
+	err := s.db.Where("lineage_id = ?", lineageID).Order("version").Find(&files).Error
+	return files, err
+}
+
+// GetVersion 查询版本链中的指定版本
+func (s *DataFileStore) GetVersion(lineageID uint, version int) (*model.DataFile, error) {
+	var file model.DataFile
+	err := s.db.Where("lineage_id = ? AND version = ?", lineageID, version).Take(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Activate 把版本链中的指定记录设为生效版本，其余版本失效
+func (s *DataFileStore) Activate(lineageID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.DataFile{}).Where("lineage_id = ? AND id <> ?", lineageID, id).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.DataFile{}).Where("lineage_id = ? AND id = ?", lineageID, id).
+			Update("active", true).Error
+	})
+}
+
+// GetActive 查询版本链当前的生效版本
+func (s *DataFileStore) GetActive(lineageID uint) (*model.DataFile, error) {
+	var file model.DataFile
+	err := s.db.Where("lineage_id = ? AND active = ?", lineageID, true).Order("id DESC").Take(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// FindActive 查询岛屿下类型和名称相同的生效文件
+func (s *DataFileStore) FindActive(isleID uint, dataType, dataName string) (*model.DataFile, error) {
+	var file model.DataFile
+	err := s.db.Where("isle_id = ? AND data_type = ? AND data_name = ? AND active = ?", isleID, dataType, dataName, true).
+		Order("id DESC").Take(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// SaveVersions 在一个事务中保存版本链中被修改的记录
+// replaceSteps 为 true 时按 WeatherSteps 重建气象时间步 (类型改变后元数据重新解析)，dropJoins 为 true 时删除涉及这些版本链的 CSV 关联
+func (s *DataFileStore) SaveVersions(files []model.DataFile, replaceSteps, dropJoins bool) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, len(files))
+		lineages := make([]uint, len(files))
+		for i := range files {
+			ids[i] = files[i].ID
+			lineages[i] = files[i].LineageID
+			if err := tx.Omit(clause.Associations).Save(&files[i]).Error; err != nil {
+				return err
+			}
+		}
+		if dropJoins {
+			if err := tx.Where("mapping_lineage_id IN ? OR shp_lineage_id IN ?", lineages, lineages).Delete(&model.MappingJoin{}).Error; err != nil {
+				return err
+			}
+		}
+		if !replaceSteps {
+			return nil
+		}
+		if err := tx.Where("data_file_id IN ?", ids).Delete(&model.WeatherStep{}).Error; err != nil {
+			return err
+		}
+		for i := range files {
+			steps := files[i].WeatherSteps
+			if len(steps) == 0 {
+				continue
+			}
+			for j := range steps {
+				steps[j].DataFileID = files[i].ID
+			}
+			if err := tx.CreateInBatches(steps, weatherStepBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateStyles 修改版本链的显示样式，styles 的键为版本链 ID，链中所有版本一起修改
+func (s *DataFileStore) UpdateStyles(styles map[uint]*model.LayerStyle) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for lineageID, style := range styles {
+			if err := tx.Model(&model.DataFile{}).Where("lineage_id = ?", lineageID).
+				Select("Style").Updates(&model.DataFile{Style: style}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// GetByIsleID 分页查询某个岛屿下的所有文件
+// GetByIsleID 分页查询某个岛屿下的所有文件 (只返回生效版本)
 // 返回: 文件列表, 总记录数, 错误
 func (s *DataFileStore) GetByIsleID(isleID uint, page, pageSize int) ([]model.DataFile, int64, error) {
 	var files []model.DataFile
 	var total int64
 
 	// 计算总数
-	s.db.Model(&model.DataFile{}).Where("isle_id = ?", isleID).Count(&total)
+	s.db.Model(&model.DataFile{}).Where("isle_id = ? AND active = ?", isleID, true).Count(&total)
 
 	// 分页查询
-	err := s.db.Where("isle_id = ?", isleID).
+	err := s.db.Where("isle_id = ? AND active = ?", isleID, true).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Find(&files).Error
@@ -58,25 +234,173 @@ func (s *DataFileStore) UpdateHeight(id uint, height float64) error {
 	return s.db.Model(&model.DataFile{}).Where("id = ?", id).Update("height", height).Error
 }
 
-// Delete 根据 ID 删除文件记录,硬删除
+// SetSHA256 记录文件内容的 SHA-256，不修改 updated_at
+func (s *DataFileStore) SetSHA256(id uint, sum string) error {
+	return s.db.Model(&model.DataFile{}).Where("id = ?", id).UpdateColumn("sha256", sum).Error
+}
+
+// Delete 根据 ID 删除文件记录,硬删除，同时删除气象文件的时间步和涉及该文件的 CSV 关联
 func (s *DataFileStore) Delete(id uint) error {
-	return s.db.Unscoped().Delete(&model.DataFile{}, id).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return deleteFiles(tx, []uint{id})
+	})
+}
+
+// DeleteLineage 删除版本链中的所有版本
+func (s *DataFileStore) DeleteLineage(lineageID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&model.DataFile{}).Where("lineage_id = ?", lineageID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return deleteFiles(tx, ids)
+	})
+}
+
+// RemoveVersion 删除版本链中的一个版本；删除的是生效版本时，剩余版本中版本号最大的一个重新生效
+func (s *DataFileStore) RemoveVersion(file *model.DataFile) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteFiles(tx, []uint{file.ID}); err != nil {
+			return err
+		}
+		if !file.Active {
+			return nil
+		}
+		var previous model.DataFile
+		err := tx.Where("lineage_id = ?", file.LineageID).Order("version DESC").Take(&previous).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.DataFile{}).Where("id = ?", previous.ID).Update("active", true).Error
+	})
+}
+
+// VersionUndo 描述一次需要撤销的 CreateVersion
+type VersionUndo struct {
+	File       *model.DataFile
+	PreviousID uint // CreateVersion 的返回值
+}
+
+// UndoVersions 在一个事务中倒序撤销多次 CreateVersion，任意一条失败时全部不生效
+// 每条撤销删除新建的记录，并让 PreviousID 重新成为生效版本；与 RemoveVersion 不同，恢复的是创建之前生效的版本，
+// 而不是剩余版本中版本号最大的一个。新记录已经被其他版本取代 (不再生效) 时只删除记录，不改变生效版本
+func (s *DataFileStore) UndoVersions(undos []VersionUndo) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i := len(undos) - 1; i >= 0; i-- {
+			if err := undoVersion(tx, undos[i].File, undos[i].PreviousID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func undoVersion(tx *gorm.DB, file *model.DataFile, previousID uint) error {
+	var current model.DataFile
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, active").Take(&current, file.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := deleteFiles(tx, []uint{file.ID}); err != nil {
+		return err
+	}
+	if !current.Active || previousID == 0 {
+		return nil
+	}
+	if err := tx.Model(&model.DataFile{}).Where("lineage_id = ? AND id <> ?", file.LineageID, previousID).
+		Update("active", false).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.DataFile{}).Where("lineage_id = ? AND id = ?", file.LineageID, previousID).
+		Update("active", true).Error
+}
+
+// deleteFiles 在事务中硬删除文件记录及其气象时间步；版本链中的版本全部删除后，涉及该版本链的 CSV 关联一并删除
+func deleteFiles(tx *gorm.DB, ids []uint) error {
+	var lineages []uint
+	if err := tx.Model(&model.DataFile{}).Where("id IN ?", ids).Distinct().Pluck("lineage_id", &lineages).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("data_file_id IN ?", ids).Delete(&model.WeatherStep{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Delete(&model.DataFile{}, ids).Error; err != nil {
+		return err
+	}
+	if len(lineages) == 0 {
+		return nil
+	}
+	var remaining []uint
+	if err := tx.Model(&model.DataFile{}).Where("lineage_id IN ?", lineages).Distinct().Pluck("lineage_id", &remaining).Error; err != nil {
+		return err
+	}
+	alive := make(map[uint]bool, len(remaining))
+	for _, id := range remaining {
+		alive[id] = true
+	}
+	var gone []uint
+	for _, id := range lineages {
+		if !alive[id] {
+			gone = append(gone, id)
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+	return tx.Where("mapping_lineage_id IN ? OR shp_lineage_id IN ?", gone, gone).Delete(&model.MappingJoin{}).Error
 }
 
-// GetAllByIsleID 查询某个岛屿下的所有文件（不分页）
+// GetAllByIsleID 查询某个岛屿下的所有文件（不分页，只返回生效版本）
 func (s *DataFileStore) GetAllByIsleID(isleID uint) ([]model.DataFile, error) {
 	var files []model.DataFile
-	err := s.db.Where("isle_id = ?", isleID).Find(&files).Error
+	err := s.db.Where("isle_id = ? AND active = ?", isleID, true).Find(&files).Error
+	return files, err
+}
+
+// ListAllVersions 查询所有文件记录，包括未生效的历史版本 (用于存储对账)
+func (s *DataFileStore) ListAllVersions() ([]model.DataFile, error) {
+	var files []model.DataFile
+	err := s.db.Select("id, isle_id, data_type, data_name, data_path, blob_hash, lineage_id, version, active").
+		Order("id").Find(&files).Error
 	return files, err
 }
 
 // GetGlobalCounts 获取全局的文件统计信息
 func (s *DataFileStore) GetGlobalCounts() ([]DataFileCountResult, error) {
 	var results []DataFileCountResult
-	// SQL: SELECT isle_id, data_type, count(*) as total FROM data_files WHERE deleted_at IS NULL GROUP BY isle_id, data_type
+	// SQL: SELECT isle_id, data_type, count(*) as total FROM data_files WHERE active = true AND deleted_at IS NULL GROUP BY isle_id, data_type
 	err := s.db.Model(&model.DataFile{}).
+		Where("active = ?", true).
 		Select("isle_id, data_type, count(*) as total").
 		Group("isle_id, data_type").
 		Scan(&results).Error
 	return results, err
 }
+
+// FindIntersecting 查询范围与给定矩形相交的数据文件 (只返回生效版本)
+// crsList 限定坐标系 (不同坐标系的范围无法比较)，dataType 为空时不过滤类型
+// 有空间索引时通过 footprint 列的 R-tree 取候选，否则使用 (bbox_min_y, bbox_min_x) 联合索引，再按范围精确过滤
+func (s *DataFileStore) FindIntersecting(minX, minY, maxX, maxY float64, crsList []string, dataType string, limit int) ([]model.DataFile, error) {
+	var files []model.DataFile
+	query := s.db
+	if s.spatial {
+		query = query.Where("MBRIntersects(footprint, ST_MakeEnvelope(POINT(?, ?), POINT(?, ?)))", minX, minY, maxX, maxY)
+	}
+	query = query.Where("bbox_valid = ? AND active = ?", true, true).
+		Where("bbox_min_y <= ? AND bbox_min_x <= ? AND bbox_max_y >= ? AND bbox_max_x >= ?", maxY, maxX, minY, minX).
+		Where("crs IN ?", crsList)
+	if dataType != "" {
+		query = query.Where("data_type = ?", dataType)
+	}
+	err := query.Limit(limit).Find(&files).Error
+	return files, err
+}
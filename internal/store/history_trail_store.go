@@ -68,6 +68,13 @@ func (s *HistoryTrailStore) Delete(id uint) error {
 	return s.db.Unscoped().Delete(&model.HistoryTrail{}, id).Error
 }
 
+// ListAll 获取所有历史轨迹记录 (不分页，用于存储对账)
+func (s *HistoryTrailStore) ListAll() ([]model.HistoryTrail, error) {
+	var trails []model.HistoryTrail
+	err := s.db.Find(&trails).Error
+	return trails, err
+}
+
 // GetGlobalCounts 获取全局的轨迹统计信息
 func (s *HistoryTrailStore) GetGlobalCounts() ([]TrailCountResult, error) {
 	var results []TrailCountResult
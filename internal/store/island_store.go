@@ -7,7 +7,8 @@ import (
 
 // IslandStore 定义了所有与岛屿相关的数据库操作
 type IslandStore struct {
-	db *gorm.DB
+	db      *gorm.DB
+	spatial bool // 是否已建立中心点的空间索引 (见 EnableSpatialIndex)
 }
 
 // NewIslandStore 创建一个新的 IslandStore
@@ -17,6 +18,7 @@ func NewIslandStore(db *gorm.DB) *IslandStore {
 
 // Create 创建一个新的岛屿记录
 func (s *IslandStore) Create(island *model.Island) error {
+	island.GridCell = GridCell(island.CenterX, island.CenterY)
 	return s.db.Create(island).Error
 }
 
@@ -75,6 +77,7 @@ func (s *IslandStore) GetByID(id uint) (*model.Island, error) {
 func (s *IslandStore) Update(island *model.Island) error {
 	// 使用 Save 会更新所有字段，即使是零值
 	// 如果只想更新非零值字段，可以使用 Updates
+	island.GridCell = GridCell(island.CenterX, island.CenterY)
 	return s.db.Save(island).Error
 }
 
@@ -91,3 +94,57 @@ func (s *IslandStore) GetAll() ([]model.Island, error) {
 	err := s.db.Select("id, isle_name, isle_desc, belong_to, created_at, updated_at").Find(&islands).Error
 	return islands, err
 }
+
+// ListAll 获取所有岛屿的完整记录 (不分页，用于存储对账)
+func (s *IslandStore) ListAll() ([]model.Island, error) {
+	var islands []model.Island
+	err := s.db.Find(&islands).Error
+	return islands, err
+}
+
+// FindInBBox 查询中心点落在经纬度范围内的岛屿
+// 有空间索引时通过 location 列的 R-tree 取候选，否则按网格编号区间查询，再用中心点坐标精确过滤
+func (s *IslandStore) FindInBBox(minX, minY, maxX, maxY float64, limit int) ([]model.Island, error) {
+	var islands []model.Island
+	query := s.db
+	if s.spatial {
+		query = query.Where("MBRIntersects(location, ST_MakeEnvelope(POINT(?, ?), POINT(?, ?)))", minX, minY, maxX, maxY)
+	} else {
+		cond, args := gridCondition("grid_cell", minX, minY, maxX, maxY)
+		query = query.Where(cond, args...)
+	}
+	err := query.Where("center_y BETWEEN ? AND ? AND center_x BETWEEN ? AND ?", minY, maxY, minX, maxX).
+		Limit(limit).
+		Find(&islands).Error
+	return islands, err
+}
+
+// BackfillGridCells 为引入网格编号之前创建的岛屿计算网格编号，并删除原来的 (center_y, center_x) 联合索引
+func (s *IslandStore) BackfillGridCells() error {
+	if m := s.db.Migrator(); m.HasIndex(&model.Island{}, "idx_isles_center") {
+		if err := m.DropIndex(&model.Island{}, "idx_isles_center"); err != nil {
+			return err
+		}
+	}
+	var islands []model.Island
+	if err := s.db.Select("id, center_x, center_y").Where("grid_cell = ?", -1).Find(&islands).Error; err != nil {
+		return err
+	}
+	for _, island := range islands {
+		cell := GridCell(island.CenterX, island.CenterY)
+		if cell < 0 {
+			continue // 中心点不是经纬度，不参与空间查询
+		}
+		if err := s.db.Model(&model.Island{}).Where("id = ?", island.ID).UpdateColumn("grid_cell", cell).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnableSpatialIndex 为岛屿中心点建立空间索引 (MySQL 8 的 SPATIAL INDEX)，失败时空间查询继续使用网格编号
+func (s *IslandStore) EnableSpatialIndex() error {
+	err := addSpatialIndex(s.db, &model.Island{}, "isles", "location", "POINT", "POINT(center_x, center_y)", "idx_isles_location")
+	s.spatial = err == nil
+	return err
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"Go_for_unity/internal/model"
+	"gorm.io/gorm"
+)
+
+type JobStore struct {
+	db *gorm.DB
+}
+
+func NewJobStore(db *gorm.DB) *JobStore {
+	return &JobStore{db: db}
+}
+
+// Create 创建一条任务记录
+func (s *JobStore) Create(job *model.Job) error {
+	return s.db.Create(job).Error
+}
+
+// GetByID 根据 ID 查询任务
+func (s *JobStore) GetByID(id string) (*model.Job, error) {
+	var job model.Job
+	err := s.db.Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Update 保存任务的最新状态
+func (s *JobStore) Update(job *model.Job) error {
+	return s.db.Save(job).Error
+}
+
+// FailUnfinished 把所有未结束的任务标记为失败，用于服务重启后清理被中断的任务
+func (s *JobStore) FailUnfinished(reason string) (int64, error) {
+	result := s.db.Model(&model.Job{}).
+		Where("status NOT IN ?", []string{model.JobStatusReady, model.JobStatusFailed}).
+		Updates(map[string]interface{}{"status": model.JobStatusFailed, "error": reason})
+	return result.RowsAffected, result.Error
+}
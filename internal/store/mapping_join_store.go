@@ -0,0 +1,55 @@
+package store
+
+import (
+	"Go_for_unity/internal/model"
+	"gorm.io/gorm"
+)
+
+type MappingJoinStore struct {
+	db *gorm.DB
+}
+
+func NewMappingJoinStore(db *gorm.DB) *MappingJoinStore {
+	return &MappingJoinStore{db: db}
+}
+
+// Create 创建一条关联记录
+func (s *MappingJoinStore) Create(join *model.MappingJoin) error {
+	return s.db.Create(join).Error
+}
+
+// GetByID 根据 ID 查询关联
+func (s *MappingJoinStore) GetByID(id uint) (*model.MappingJoin, error) {
+	var join model.MappingJoin
+	err := s.db.First(&join, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &join, nil
+}
+
+// BackfillLineage 为按文件 ID 保存的旧关联补齐两个版本链 ID
+func (s *MappingJoinStore) BackfillLineage() error {
+	if err := s.db.Model(&model.MappingJoin{}).Where("mapping_lineage_id = 0 OR mapping_lineage_id IS NULL").
+		Update("mapping_lineage_id", gorm.Expr("COALESCE((SELECT lineage_id FROM data_files WHERE data_files.id = mapping_joins.mapping_file_id), 0)")).Error; err != nil {
+		return err
+	}
+	return s.db.Model(&model.MappingJoin{}).Where("shp_lineage_id = 0 OR shp_lineage_id IS NULL").
+		Update("shp_lineage_id", gorm.Expr("COALESCE((SELECT lineage_id FROM data_files WHERE data_files.id = mapping_joins.shp_file_id), 0)")).Error
+}
+
+// ListByIsleID 查询岛屿下的关联，lineageID 不为 0 时只返回涉及该版本链 (CSV 或 shp) 的关联
+func (s *MappingJoinStore) ListByIsleID(isleID, lineageID uint) ([]model.MappingJoin, error) {
+	var joins []model.MappingJoin
+	query := s.db.Where("isle_id = ?", isleID)
+	if lineageID != 0 {
+		query = query.Where("mapping_lineage_id = ? OR shp_lineage_id = ?", lineageID, lineageID)
+	}
+	err := query.Order("id").Find(&joins).Error
+	return joins, err
+}
+
+// Delete 删除一条关联
+func (s *MappingJoinStore) Delete(id uint) error {
+	return s.db.Delete(&model.MappingJoin{}, id).Error
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"math"
+	"strings"
+)
+
+// 空间索引
+// MySQL 8 上为岛屿中心点和数据范围各增加一个由坐标生成的几何列并建立 SPATIAL INDEX (R-tree)，
+// 查询先用 MBRIntersects 通过索引取出候选，再用原始坐标精确过滤。
+// 几何列使用 SRID 0 (平面坐标)：查询条件是经纬度矩形或投影坐标矩形，平面 MBR 与之完全一致；
+// 使用 SRID 4326 时矩形的边会变成大圆弧，并且超出经纬度范围的旧数据会让生成列写入失败。
+// 不支持的数据库 (例如本地测试用的旧版 MySQL) 上岛屿查询退化为按 1° 网格编号的 B-tree 索引。
+
+// errSpatialUnsupported 当前数据库不是 MySQL，不尝试创建空间索引
+var errSpatialUnsupported = errors.New("当前数据库不支持空间索引")
+
+// gridSize 网格的边长 (度)
+const gridSize = 1.0
+
+const (
+	gridCols = int(360 / gridSize)
+	gridRows = int(180 / gridSize)
+)
+
+// GridCell 经纬度所在网格的编号：按纬度行、经度列从南到北、从西到东编号，超出经纬度范围时为 -1
+// 同一纬度行中的网格编号连续，矩形查询可以按行转换为若干个编号区间
+func GridCell(lon, lat float64) int {
+	if math.IsNaN(lon) || math.IsNaN(lat) || lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return -1
+	}
+	return gridRow(lat)*gridCols + gridCol(lon)
+}
+
+func gridRow(lat float64) int {
+	return min(int(math.Floor((lat+90)/gridSize)), gridRows-1)
+}
+
+func gridCol(lon float64) int {
+	return min(int(math.Floor((lon+180)/gridSize)), gridCols-1)
+}
+
+// gridCondition 生成 "网格编号落在矩形覆盖的网格中" 的查询条件，每个纬度行一个区间，经度覆盖全部时合并为一个区间
+func gridCondition(column string, minX, minY, maxX, maxY float64) (string, []interface{}) {
+	minX, maxX = math.Max(minX, -180), math.Min(maxX, 180)
+	minY, maxY = math.Max(minY, -90), math.Min(maxY, 90)
+	if minX > maxX || minY > maxY {
+		return "1 = 0", nil
+	}
+	row0, row1 := gridRow(minY), gridRow(maxY)
+	col0, col1 := gridCol(minX), gridCol(maxX)
+	if col0 == 0 && col1 == gridCols-1 {
+		return column + " BETWEEN ? AND ?", []interface{}{row0 * gridCols, row1*gridCols + gridCols - 1}
+	}
+	conds := make([]string, 0, row1-row0+1)
+	args := make([]interface{}, 0, 2*(row1-row0+1))
+	for row := row0; row <= row1; row++ {
+		conds = append(conds, column+" BETWEEN ? AND ?")
+		args = append(args, row*gridCols+col0, row*gridCols+col1)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// addSpatialIndex 在 table 上增加由 expr 生成的几何列 column 并建立空间索引，已存在时跳过
+func addSpatialIndex(db *gorm.DB, model interface{}, table, column, geomType, expr, index string) error {
+	if db.Dialector.Name() != "mysql" {
+		return errSpatialUnsupported
+	}
+	m := db.Migrator()
+	if !m.HasColumn(model, column) {
+		sql := "ALTER TABLE " + table + " ADD COLUMN " + column + " " + geomType +
+			" SRID 0 GENERATED ALWAYS AS (" + expr + ") STORED NOT NULL"
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	if !m.HasIndex(model, index) {
+		if err := db.Exec("ALTER TABLE " + table + " ADD SPATIAL INDEX " + index + " (" + column + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
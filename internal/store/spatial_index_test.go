@@ -0,0 +1,49 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGridCell(t *testing.T) {
+	cases := []struct {
+		lon, lat float64
+		want     int
+	}{
+		{-180, -90, 0},
+		{-179.5, -89.5, 0},
+		{-179, -90, 1},
+		{0, 0, 90*360 + 180},
+		{116.4, 39.9, 129*360 + 296},
+		{180, 90, 179*360 + 359}, // 东经 180° 和北纬 90° 归入最后一列、最后一行
+		{181, 0, -1},
+		{0, -91, -1},
+	}
+	for _, c := range cases {
+		if got := GridCell(c.lon, c.lat); got != c.want {
+			t.Errorf("GridCell(%v, %v) = %d, 期望 %d", c.lon, c.lat, got, c.want)
+		}
+	}
+}
+
+func TestGridCondition(t *testing.T) {
+	cases := []struct {
+		name                   string
+		minX, minY, maxX, maxY float64
+		cond                   string
+		args                   []interface{}
+	}{
+		{"单个网格", 116.1, 39.2, 116.9, 39.8, "(c BETWEEN ? AND ?)", []interface{}{129*360 + 296, 129*360 + 296}},
+		{"每个纬度行一个区间", 116.5, 39.5, 118.5, 40.5, "(c BETWEEN ? AND ? OR c BETWEEN ? AND ?)",
+			[]interface{}{129*360 + 296, 129*360 + 298, 130*360 + 296, 130*360 + 298}},
+		{"经度覆盖全部时合并为一个区间", -180, -1, 180, 1, "c BETWEEN ? AND ?", []interface{}{89 * 360, 91*360 + 359}},
+		{"超出范围的部分被截断", -200, 89.5, -179.5, 100, "(c BETWEEN ? AND ?)", []interface{}{179 * 360, 179 * 360}},
+		{"范围为空", 10, 95, 20, 99, "1 = 0", nil},
+	}
+	for _, c := range cases {
+		cond, args := gridCondition("c", c.minX, c.minY, c.maxX, c.maxY)
+		if cond != c.cond || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%s: gridCondition = %q %v, 期望 %q %v", c.name, cond, args, c.cond, c.args)
+		}
+	}
+}
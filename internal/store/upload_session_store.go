@@ -0,0 +1,77 @@
+package store
+
+import (
+	"Go_for_unity/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type UploadSessionStore struct {
+	db *gorm.DB
+}
+
+func NewUploadSessionStore(db *gorm.DB) *UploadSessionStore {
+	return &UploadSessionStore{db: db}
+}
+
+// Create 创建一个上传会话
+func (s *UploadSessionStore) Create(session *model.UploadSession) error {
+	return s.db.Create(session).Error
+}
+
+// GetByID 根据 ID 查询上传会话
+func (s *UploadSessionStore) GetByID(id string) (*model.UploadSession, error) {
+	var session model.UploadSession
+	err := s.db.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch 延长会话的过期时间
+func (s *UploadSessionStore) Touch(id string, expiresAt time.Time) error {
+	return s.db.Model(&model.UploadSession{}).Where("id = ?", id).Update("expires_at", expiresAt).Error
+}
+
+// TransitionStatus 仅当当前状态为 from 时才把状态改为 to，用于防止并发重复完成
+// 返回值表示是否修改成功
+func (s *UploadSessionStore) TransitionStatus(id, from, to string) (bool, error) {
+	result := s.db.Model(&model.UploadSession{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
+
+// SetJobID 记录会话对应的后台处理任务
+func (s *UploadSessionStore) SetJobID(id, jobID string) error {
+	return s.db.Model(&model.UploadSession{}).Where("id = ?", id).Update("job_id", jobID).Error
+}
+
+// ResetStatus 把所有状态为 from 的会话改为 to，返回受影响的行数
+func (s *UploadSessionStore) ResetStatus(from, to string) (int64, error) {
+	result := s.db.Model(&model.UploadSession{}).Where("status = ?", from).Update("status", to)
+	return result.RowsAffected, result.Error
+}
+
+// MarkCompleted 标记会话完成并记录生成的 DataFile，会话记录保留到 expiresAt 以便客户端查询结果
+func (s *UploadSessionStore) MarkCompleted(id string, dataFileID uint, expiresAt time.Time) error {
+	return s.db.Model(&model.UploadSession{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       model.UploadStatusCompleted,
+			"data_file_id": dataFileID,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// ListExpired 查询所有已过期的会话 (正在合并处理中的会话除外)
+func (s *UploadSessionStore) ListExpired(now time.Time) ([]model.UploadSession, error) {
+	var sessions []model.UploadSession
+	err := s.db.Where("expires_at < ? AND status <> ?", now, model.UploadStatusCompleting).Find(&sessions).Error
+	return sessions, err
+}
+
+// Delete 删除会话记录
+func (s *UploadSessionStore) Delete(id string) error {
+	return s.db.Where("id = ?", id).Delete(&model.UploadSession{}).Error
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"Go_for_unity/internal/model"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WeatherStepStore struct {
+	db *gorm.DB
+}
+
+func NewWeatherStepStore(db *gorm.DB) *WeatherStepStore {
+	return &WeatherStepStore{db: db}
+}
+
+// Window 按时间顺序查询 [from, to] 内的时间步，最多返回 limit 条
+func (s *WeatherStepStore) Window(fileID uint, from, to time.Time, limit int) ([]model.WeatherStep, error) {
+	var steps []model.WeatherStep
+	err := s.db.Where("data_file_id = ? AND time >= ? AND time <= ?", fileID, from, to).
+		Order("time").
+		Limit(limit).
+		Find(&steps).Error
+	return steps, err
+}
+
+// Around 查询 t 时刻之前 (含) 的最后一个时间步和之后 (含) 的第一个时间步，不存在时对应结果为 nil
+func (s *WeatherStepStore) Around(fileID uint, t time.Time) (*model.WeatherStep, *model.WeatherStep, error) {
+	before, err := s.first(s.db.Where("data_file_id = ? AND time <= ?", fileID, t).Order("time DESC"))
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := s.first(s.db.Where("data_file_id = ? AND time >= ?", fileID, t).Order("time"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+func (s *WeatherStepStore) first(query *gorm.DB) (*model.WeatherStep, error) {
+	var step model.WeatherStep
+	err := query.Take(&step).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &step, nil
+}
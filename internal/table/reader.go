@@ -0,0 +1,187 @@
+// Package table 读取映射表等 CSV 文件：识别编码 (UTF-8 / GBK) 和分隔符，推断列类型
+package table
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// 支持的文件编码
+const (
+	EncodingUTF8 = "UTF-8"
+	EncodingGBK  = "GBK" // 按 GB18030 解码，兼容 GBK 和 GB2312
+)
+
+// MaxColumns 表头允许的最大列数
+const MaxColumns = 1000
+
+// ErrFormat 文件不是合法的 CSV (表头缺失、重复列名、行字段数不一致等)
+var ErrFormat = errors.New("CSV 格式错误")
+
+// formatErrorf 构造带有 ErrFormat 的错误
+func formatErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrFormat, fmt.Sprintf(format, args...))
+}
+
+// DetectEncoding 扫描整个文件判断编码：带 BOM 或全部字节是合法 UTF-8 时为 UTF-8，否则为 GBK
+func DetectEncoding(r io.Reader) (string, error) {
+	buf := make([]byte, 64<<10)
+	var carry []byte // 上一块末尾被截断的多字节字符
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := append(carry, buf[:n]...)
+			// 末尾最多 3 个字节可能是被截断的字符，留到下一块再检查
+			end := len(chunk)
+			for i := 1; i <= 3 && i <= len(chunk); i++ {
+				if utf8.RuneStart(chunk[len(chunk)-i]) {
+					if !utf8.FullRune(chunk[len(chunk)-i:]) {
+						end = len(chunk) - i
+					}
+					break
+				}
+			}
+			if !utf8.Valid(chunk[:end]) {
+				return EncodingGBK, nil
+			}
+			carry = append([]byte(nil), chunk[end:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(carry) > 0 {
+		return EncodingGBK, nil
+	}
+	return EncodingUTF8, nil
+}
+
+// decode 按编码包装数据流，UTF-8 时去掉 BOM
+func decode(r io.Reader, encoding string) (*bufio.Reader, error) {
+	switch encoding {
+	case EncodingUTF8:
+		br := bufio.NewReader(r)
+		if b, err := br.Peek(3); err == nil && bytes.Equal(b, []byte{0xef, 0xbb, 0xbf}) {
+			br.Discard(3)
+		}
+		return br, nil
+	case EncodingGBK:
+		return bufio.NewReader(transform.NewReader(r, simplifiedchinese.GB18030.NewDecoder())), nil
+	}
+	return nil, fmt.Errorf("不支持的编码 %s", encoding)
+}
+
+// delimiters 候选的分隔符
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// sniffDelimiter 以表头行中出现次数最多的候选字符作为分隔符，都没有出现时为逗号
+func sniffDelimiter(br *bufio.Reader) rune {
+	b, _ := br.Peek(br.Size())
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[:i]
+	}
+	best, bestCount := ',', 0
+	for _, d := range delimiters {
+		if n := bytes.Count(b, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// Reader 按行读取 CSV，表头在创建时读取并校验
+type Reader struct {
+	Header    []string
+	Encoding  string
+	Delimiter rune
+
+	cr *csv.Reader
+}
+
+// NewReader 按指定编码读取 CSV，分隔符根据表头行自动判断
+func NewReader(r io.Reader, encoding string) (*Reader, error) {
+	br, err := decode(r, encoding)
+	if err != nil {
+		return nil, err
+	}
+	delimiter := sniffDelimiter(br)
+	cr := csv.NewReader(br)
+	cr.Comma = delimiter
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, formatErrorf("文件为空")
+	}
+	if err != nil {
+		return nil, formatErrorf("读取表头失败: %v", describeParseError(err))
+	}
+	if len(header) > MaxColumns {
+		return nil, formatErrorf("列数 %d 超过上限 %d", len(header), MaxColumns)
+	}
+	names := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, h := range header {
+		name := strings.TrimSpace(h)
+		if name == "" {
+			return nil, formatErrorf("第 %d 列的列名为空", i+1)
+		}
+		if seen[name] {
+			return nil, formatErrorf("列名 %s 重复", name)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return &Reader{Header: names, Encoding: encoding, Delimiter: delimiter, cr: cr}, nil
+}
+
+// Next 读取下一行，文件结束时返回 io.EOF；返回的切片在下次调用时会被复用
+// 行的格式错误 (字段数与表头不一致、引号不匹配) 以 *RowError 返回，调用方可以跳过该行继续读取
+func (r *Reader) Next() ([]string, error) {
+	record, err := r.cr.Read()
+	if err == nil || err == io.EOF {
+		return record, err
+	}
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return nil, &RowError{Line: pe.StartLine, Msg: describeParseError(err)}
+	}
+	return nil, err
+}
+
+// RowError 某一行的格式错误
+type RowError struct {
+	Line int // 行号 (从 1 开始，包含表头)
+	Msg  string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("第 %d 行: %s", e.Line, e.Msg)
+}
+
+// describeParseError 把 encoding/csv 的错误转换为中文说明
+func describeParseError(err error) string {
+	var pe *csv.ParseError
+	if !errors.As(err, &pe) {
+		return err.Error()
+	}
+	switch {
+	case errors.Is(pe.Err, csv.ErrFieldCount):
+		return "字段数与表头不一致"
+	case errors.Is(pe.Err, csv.ErrQuote), errors.Is(pe.Err, csv.ErrBareQuote):
+		return "引号不匹配"
+	}
+	return pe.Err.Error()
+}
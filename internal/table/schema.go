@@ -0,0 +1,223 @@
+package table
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 列类型，按从严格到宽松的顺序推断
+const (
+	TypeInteger  = "integer"
+	TypeNumber   = "number"
+	TypeBoolean  = "boolean"
+	TypeDatetime = "datetime"
+	TypeString   = "string"
+)
+
+// MaxRowErrors 校验时最多收集的行错误数量，超过后停止读取
+const MaxRowErrors = 20
+
+// Column 一列的推断结果
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool     // 是否存在空值
+	Min      *float64 // 数值列的最小值
+	Max      *float64 // 数值列的最大值
+}
+
+// Schema 整个文件的检查结果
+type Schema struct {
+	Encoding  string
+	Delimiter rune
+	Columns   []Column
+	RowCount  int
+	RowErrors []*RowError // 格式错误的行，最多 MaxRowErrors 条
+}
+
+// Inspect 读取整个文件：校验每一行的格式并推断列类型
+// 行格式错误不会中断检查，收集到 MaxRowErrors 条后停止
+func Inspect(r io.Reader, encoding string) (*Schema, error) {
+	tr, err := NewReader(r, encoding)
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{Encoding: encoding, Delimiter: tr.Delimiter}
+	inferrers := make([]inferrer, len(tr.Header))
+	for i := range inferrers {
+		inferrers[i] = newInferrer()
+	}
+
+	for {
+		record, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			schema.RowErrors = append(schema.RowErrors, rowErr)
+			if len(schema.RowErrors) >= MaxRowErrors {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		schema.RowCount++
+		for i, cell := range record {
+			inferrers[i].add(cell)
+		}
+	}
+
+	for i, name := range tr.Header {
+		schema.Columns = append(schema.Columns, inferrers[i].column(name))
+	}
+	return schema, nil
+}
+
+// inferrer 逐个值排除不可能的类型
+type inferrer struct {
+	integer, number, boolean, datetime bool
+	seen, nullable                     bool
+	min, max                           float64
+}
+
+func newInferrer() inferrer {
+	return inferrer{integer: true, number: true, boolean: true, datetime: true}
+}
+
+func (f *inferrer) add(cell string) {
+	cell = strings.TrimSpace(cell)
+	if IsNull(cell) {
+		f.nullable = true
+		return
+	}
+	// 带前导零的编码 (例如 00123) 按字符串处理，避免转换为数字后丢失前导零
+	if hasLeadingZero(cell) {
+		f.integer, f.number = false, false
+	}
+	if f.integer {
+		if _, err := strconv.ParseInt(cell, 10, 64); err != nil {
+			f.integer = false
+		}
+	}
+	if f.number {
+		if v, err := strconv.ParseFloat(cell, 64); err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			f.number = false
+		} else if !f.seen {
+			f.min, f.max = v, v
+		} else {
+			f.min, f.max = min(f.min, v), max(f.max, v)
+		}
+	}
+	if f.boolean {
+		if _, ok := ParseBool(cell); !ok {
+			f.boolean = false
+		}
+	}
+	if f.datetime {
+		if _, ok := ParseDatetime(cell); !ok {
+			f.datetime = false
+		}
+	}
+	f.seen = true
+}
+
+func (f *inferrer) column(name string) Column {
+	col := Column{Name: name, Nullable: f.nullable, Type: TypeString}
+	switch {
+	case !f.seen:
+		// 整列为空，无法推断
+	case f.integer:
+		col.Type = TypeInteger
+	case f.number:
+		col.Type = TypeNumber
+	case f.boolean:
+		col.Type = TypeBoolean
+	case f.datetime:
+		col.Type = TypeDatetime
+	}
+	if f.seen && f.number {
+		lo, hi := f.min, f.max
+		col.Min, col.Max = &lo, &hi
+	}
+	return col
+}
+
+func hasLeadingZero(cell string) bool {
+	cell = strings.TrimLeft(cell, "+-")
+	return len(cell) > 1 && cell[0] == '0' && cell[1] != '.'
+}
+
+// IsNull 判断单元格是否表示空值
+func IsNull(cell string) bool {
+	switch strings.ToLower(cell) {
+	case "", "null", "na", "n/a", "nan":
+		return true
+	}
+	return false
+}
+
+// ParseBool 解析布尔值，0/1 会被推断为整数，因此不在这里识别
+func ParseBool(cell string) (bool, bool) {
+	switch strings.ToLower(cell) {
+	case "true", "yes", "y", "是":
+		return true, true
+	case "false", "no", "n", "否":
+		return false, true
+	}
+	return false, false
+}
+
+// datetimeLayouts 识别的日期时间格式
+var datetimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"2006/1/2",
+}
+
+// ParseDatetime 解析日期时间，没有时区时按服务器所在时区解释
+func ParseDatetime(cell string) (time.Time, bool) {
+	for _, layout := range datetimeLayouts {
+		if t, err := time.ParseInLocation(layout, cell, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Convert 按列类型转换单元格，空值返回 nil，无法转换时返回原字符串
+func Convert(cell, typ string) interface{} {
+	cell = strings.TrimSpace(cell)
+	if IsNull(cell) {
+		return nil
+	}
+	switch typ {
+	case TypeInteger:
+		if v, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return v
+		}
+	case TypeNumber:
+		if v, err := strconv.ParseFloat(cell, 64); err == nil {
+			return v
+		}
+	case TypeBoolean:
+		if v, ok := ParseBool(cell); ok {
+			return v
+		}
+	case TypeDatetime:
+		if v, ok := ParseDatetime(cell); ok {
+			return v
+		}
+	}
+	return cell
+}
@@ -0,0 +1,66 @@
+package weather
+
+import (
+	"math"
+	"time"
+)
+
+// Interpolate 在前后两个时间步之间按各变量的插值方式计算 t 时刻的值
+// t 必须在 [t0, t1] 内；任一侧缺测时：step 变量取前一时刻的值，其余变量为 nil
+func Interpolate(vars []Variable, t0 time.Time, v0 []*float64, t1 time.Time, v1 []*float64, t time.Time) []*float64 {
+	f := 0.0
+	if span := t1.Sub(t0); span > 0 {
+		f = float64(t.Sub(t0)) / float64(span)
+	}
+
+	out := make([]*float64, len(vars))
+	for i, v := range vars {
+		a, b := valueAt(v0, i), valueAt(v1, i)
+		// 正好落在时间步上时直接取该时刻的值
+		if f == 0 {
+			out[i] = a
+			continue
+		}
+		if f == 1 {
+			out[i] = b
+			continue
+		}
+		if v.Interp == InterpStep {
+			out[i] = a
+			continue
+		}
+		if a == nil || b == nil {
+			continue
+		}
+		var r float64
+		if v.Interp == InterpAngle {
+			r = lerpAngle(*a, *b, f)
+		} else {
+			r = *a + (*b-*a)*f
+		}
+		out[i] = &r
+	}
+	return out
+}
+
+func valueAt(values []*float64, i int) *float64 {
+	if i < len(values) {
+		return values[i]
+	}
+	return nil
+}
+
+// lerpAngle 沿较小的夹角插值角度，结果在 [0, 360)
+func lerpAngle(a, b, f float64) float64 {
+	d := math.Mod(b-a, 360)
+	if d > 180 {
+		d -= 360
+	} else if d < -180 {
+		d += 360
+	}
+	r := math.Mod(a+d*f, 360)
+	if r < 0 {
+		r += 360
+	}
+	return r
+}
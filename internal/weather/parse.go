@@ -0,0 +1,192 @@
+package weather
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse 按文件名后缀解析气象文件，后缀无法判断格式时根据内容的第一个字符判断
+// 返回的错误中，格式问题包装了 ErrSchema
+func Parse(r io.Reader, name string, loc *time.Location) (*Series, error) {
+	br := bufio.NewReader(r)
+	skipBOM(br)
+
+	var series *Series
+	var err error
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		series, err = parseJSON(br, loc)
+	case ".csv", ".txt":
+		series, err = parseCSV(br, loc)
+	default:
+		if looksLikeJSON(br) {
+			series, err = parseJSON(br, loc)
+		} else {
+			series, err = parseCSV(br, loc)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := series.validate(); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// skipBOM 跳过 UTF-8 BOM (Excel 另存为 CSV 时会写入)
+func skipBOM(br *bufio.Reader) {
+	if b, err := br.Peek(3); err == nil && bytes.Equal(b, []byte{0xef, 0xbb, 0xbf}) {
+		br.Discard(3)
+	}
+}
+
+// looksLikeJSON 第一个非空白字符为 { 时认为是 JSON
+func looksLikeJSON(br *bufio.Reader) bool {
+	b, _ := br.Peek(512)
+	trimmed := bytes.TrimLeft(b, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// jsonFile JSON 格式的文件结构
+type jsonFile struct {
+	Version   int        `json:"version"`
+	Variables []Variable `json:"variables"`
+	Steps     []struct {
+		Time   string              `json:"time"`
+		Values map[string]*float64 `json:"values"`
+	} `json:"steps"`
+}
+
+func parseJSON(r io.Reader, loc *time.Location) (*Series, error) {
+	var f jsonFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, schemaErrorf("字段 %s 的类型应为 %s", typeErr.Field, typeErr.Type)
+		}
+		return nil, schemaErrorf("JSON 解析失败: %v", err)
+	}
+	if f.Version != 1 {
+		return nil, schemaErrorf("不支持的版本 %d，当前仅支持 version 1", f.Version)
+	}
+
+	series := &Series{Format: "json", Variables: f.Variables}
+	index := make(map[string]int, len(f.Variables))
+	for i, v := range f.Variables {
+		index[v.Name] = i
+	}
+	series.Steps = make([]Step, 0, len(f.Steps))
+	for i, s := range f.Steps {
+		t, err := ParseTime(s.Time, loc)
+		if err != nil {
+			return nil, schemaErrorf("第 %d 个时间步: %v", i+1, err)
+		}
+		values := make([]*float64, len(f.Variables))
+		for name, v := range s.Values {
+			j, ok := index[name]
+			if !ok {
+				return nil, schemaErrorf("第 %d 个时间步包含未声明的变量 %s", i+1, name)
+			}
+			values[j] = v
+		}
+		series.Steps = append(series.Steps, Step{Time: t, Values: values})
+	}
+	return series, nil
+}
+
+// missingValues CSV 中表示缺测的写法
+var missingValues = map[string]bool{"": true, "-": true, "na": true, "nan": true, "null": true}
+
+func parseCSV(br *bufio.Reader, loc *time.Location) (*Series, error) {
+	cr := csv.NewReader(br)
+	cr.Comma = sniffDelimiter(br)
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, schemaErrorf("文件为空")
+	}
+	if err != nil {
+		return nil, schemaErrorf("读取表头失败: %v", err)
+	}
+	if len(header) < 2 {
+		return nil, schemaErrorf("表头至少需要时间列和一个变量列")
+	}
+
+	series := &Series{Format: "csv"}
+	for _, h := range header[1:] {
+		name, unit := splitUnit(h)
+		series.Variables = append(series.Variables, Variable{Name: name, Unit: unit})
+	}
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, schemaErrorf("第 %d 行: %v", line, err)
+		}
+		if len(series.Steps) >= MaxSteps {
+			return nil, schemaErrorf("时间步数量超过上限 %d", MaxSteps)
+		}
+		t, err := ParseTime(record[0], loc)
+		if err != nil {
+			return nil, schemaErrorf("第 %d 行: %v", line, err)
+		}
+		values := make([]*float64, len(series.Variables))
+		for j, cell := range record[1:] {
+			cell = strings.TrimSpace(cell)
+			if missingValues[strings.ToLower(cell)] {
+				continue
+			}
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return nil, schemaErrorf("第 %d 行 %s 列的值 %q 不是数字", line, series.Variables[j].Name, cell)
+			}
+			values[j] = &v
+		}
+		series.Steps = append(series.Steps, Step{Time: t, Values: values})
+	}
+	return series, nil
+}
+
+// sniffDelimiter 根据第一行中出现次数最多的字符判断分隔符 (逗号、分号或制表符)
+func sniffDelimiter(br *bufio.Reader) rune {
+	b, _ := br.Peek(4096)
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[:i]
+	}
+	best, bestCount := ',', bytes.Count(b, []byte{','})
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(b, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// splitUnit 拆分列名中的单位，例如 "temperature(°C)"、"温度 [℃]"、"风速（m/s）"
+func splitUnit(h string) (string, string) {
+	h = strings.TrimSpace(h)
+	for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}, {"（", "）"}} {
+		if strings.HasSuffix(h, pair[1]) {
+			if i := strings.LastIndex(h, pair[0]); i > 0 {
+				return strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+len(pair[0]) : len(h)-len(pair[1])])
+			}
+		}
+	}
+	return h, ""
+}
@@ -0,0 +1,179 @@
+// Package weather 解析和校验气象时间序列文件
+//
+// 支持两种格式，时间必须递增且不能重复，数值为空表示该时刻缺测：
+//
+// JSON (.json)：
+//
+//	{
+//	  "version": 1,
+//	  "variables": [
+//	    {"name": "temperature", "unit": "°C"},
+//	    {"name": "windDirection", "unit": "deg", "interp": "angle"},
+//	    {"name": "weatherCode", "interp": "step"}
+//	  ],
+//	  "steps": [
+//	    {"time": "2024-05-01T08:00:00+08:00", "values": {"temperature": 21.5, "windDirection": 90, "weatherCode": 1}}
+//	  ]
+//	}
+//
+// CSV (.csv/.txt)：第一列为时间，其余列为变量，列名可以用括号带单位，例如 temperature(°C)
+//
+//	time,temperature(°C),wind_direction(deg)
+//	2024-05-01 08:00:00,21.5,90
+//
+// CSV 无法声明插值方式，列名中包含 direction、风向或以 dir 结尾的变量按角度插值，其余按线性插值。
+// 没有时区的时间按调用方传入的时区解释。
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 变量的插值方式
+const (
+	InterpLinear = "linear" // 线性插值
+	InterpAngle  = "angle"  // 角度 (0-360)，沿较小的夹角插值
+	InterpStep   = "step"   // 取前一时刻的值，用于天气现象代码等离散量
+)
+
+// MaxSteps 单个文件允许的最大时间步数量
+const MaxSteps = 200000
+
+// ErrSchema 文件不符合气象文件格式
+var ErrSchema = errors.New("气象文件格式错误")
+
+// Variable 一个气象变量
+type Variable struct {
+	Name   string `json:"name"`
+	Unit   string `json:"unit,omitempty"`
+	Interp string `json:"interp,omitempty"`
+}
+
+// Step 一个时间步，Values 与 Series.Variables 一一对应，nil 表示缺测
+type Step struct {
+	Time   time.Time
+	Values []*float64
+}
+
+// Series 解析后的气象时间序列
+type Series struct {
+	Format    string // json / csv
+	Variables []Variable
+	Steps     []Step
+}
+
+// schemaErrorf 构造带有 ErrSchema 的错误
+func schemaErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrSchema, fmt.Sprintf(format, args...))
+}
+
+// variableNamePattern 变量名只允许字母 (包括中文)、数字和下划线，保证可以作为查询参数
+var variableNamePattern = regexp.MustCompile(`^\pL[\pL\pN_]*$`)
+
+// validate 检查变量定义和时间步，并补全插值方式
+func (s *Series) validate() error {
+	if len(s.Variables) == 0 {
+		return schemaErrorf("至少需要一个变量")
+	}
+	seen := make(map[string]bool, len(s.Variables))
+	for i := range s.Variables {
+		v := &s.Variables[i]
+		if !variableNamePattern.MatchString(v.Name) {
+			return schemaErrorf("变量名 %q 无效，只能包含字母、数字和下划线且不能以数字开头", v.Name)
+		}
+		if strings.EqualFold(v.Name, "time") {
+			return schemaErrorf("变量名不能为 time")
+		}
+		if seen[v.Name] {
+			return schemaErrorf("变量 %s 重复", v.Name)
+		}
+		seen[v.Name] = true
+		switch v.Interp {
+		case "":
+			v.Interp = defaultInterp(v.Name)
+		case InterpLinear, InterpAngle, InterpStep:
+		default:
+			return schemaErrorf("变量 %s 的插值方式 %q 无效，可选 linear / angle / step", v.Name, v.Interp)
+		}
+	}
+
+	if len(s.Steps) == 0 {
+		return schemaErrorf("没有任何时间步")
+	}
+	if len(s.Steps) > MaxSteps {
+		return schemaErrorf("时间步数量 %d 超过上限 %d", len(s.Steps), MaxSteps)
+	}
+	for i, step := range s.Steps {
+		for j, v := range step.Values {
+			if v != nil && (math.IsNaN(*v) || math.IsInf(*v, 0)) {
+				return schemaErrorf("第 %d 个时间步的 %s 不是有效数值", i+1, s.Variables[j].Name)
+			}
+		}
+		if i > 0 && !step.Time.After(s.Steps[i-1].Time) {
+			return schemaErrorf("第 %d 个时间步 (%s) 不晚于前一个时间步，时间必须递增且不重复",
+				i+1, step.Time.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// defaultInterp 根据变量名推断插值方式
+func defaultInterp(name string) string {
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "direction") || strings.HasSuffix(lower, "dir") || strings.Contains(name, "风向") {
+		return InterpAngle
+	}
+	return InterpLinear
+}
+
+// Start 返回第一个时间步的时间
+func (s *Series) Start() time.Time {
+	return s.Steps[0].Time
+}
+
+// End 返回最后一个时间步的时间
+func (s *Series) End() time.Time {
+	return s.Steps[len(s.Steps)-1].Time
+}
+
+// Interval 返回时间步的间隔，间隔不一致时返回 0
+func (s *Series) Interval() time.Duration {
+	if len(s.Steps) < 2 {
+		return 0
+	}
+	d := s.Steps[1].Time.Sub(s.Steps[0].Time)
+	for i := 2; i < len(s.Steps); i++ {
+		if s.Steps[i].Time.Sub(s.Steps[i-1].Time) != d {
+			return 0
+		}
+	}
+	return d
+}
+
+// timeLayouts 支持的时间格式，按顺序尝试
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime 解析时间步的时间，没有时区的时间按 loc 解释
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间 %q", s)
+}
@@ -8,8 +8,9 @@ import (
 
 // Manager 负责管理唯一的 WebSocket 连接
 type Manager struct {
-	conn *websocket.Conn
-	mu   sync.RWMutex // 使用读写锁保护连接，保证并发安全
+	conn   *websocket.Conn
+	mu     sync.RWMutex // 使用读写锁保护连接，保证并发安全
+	warned bool         // 本次断开期间是否已经记录过 "未连接"，避免每条消息都写一遍日志
 }
 
 // NewManager 创建一个新的 Manager 实例
@@ -22,6 +23,7 @@ func (m *Manager) Register(conn *websocket.Conn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.conn = conn
+	m.warned = false
 	log.Println("WebSocket 客户端已连接")
 }
 
@@ -37,21 +39,36 @@ func (m *Manager) Unregister() {
 }
 
 // SendMessage 向已连接的客户端发送 JSON 消息
+// 导出接口和后台任务可能同时推送消息，而 websocket.Conn 不支持并发写，这里使用写锁串行化
 func (m *Manager) SendMessage(message interface{}) {
-	m.mu.RLock() // 使用读锁，允许多个发送操作同时进行（如果需要的话）
-	defer m.mu.RUnlock()
+	if m.send(message) {
+		log.Println("已通过 WebSocket成功推送消息至 Unity")
+	}
+}
+
+// Publish 推送高频事件 (例如任务进度)：成功时不写日志，未连接时直接丢弃
+func (m *Manager) Publish(message interface{}) {
+	m.send(message)
+}
+
+// send 发送消息并返回是否成功；未连接时每次断开只记录一次日志
+func (m *Manager) send(message interface{}) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.conn == nil {
-		log.Println("WebSocket 未连接，无法发送消息")
-		return
+		if !m.warned {
+			m.warned = true
+			log.Println("WebSocket 未连接，消息将被丢弃，直到客户端重新连接")
+		}
+		return false
 	}
 
 	// 使用 WriteJSON 可以方便地发送结构体
-	err := m.conn.WriteJSON(message)
-	if err != nil {
+	if err := m.conn.WriteJSON(message); err != nil {
 		log.Printf("通过 WebSocket 发送消息失败: %v", err)
 		// 发送失败通常意味着连接已断开，可以考虑在这里触发 Unregister
-	} else {
-		log.Println("已通过 WebSocket成功推送消息至 Unity")
+		return false
 	}
+	return true
 }